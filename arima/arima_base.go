@@ -0,0 +1,691 @@
+// arima ARIMA(p,d,q) モデルの推定と予測パッケージ
+package arima // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"sv_stockcheck/optimize"
+)
+
+// 引数の時系列は全て日付昇順(index 0 が最古)とする(indicator パッケージとは逆順)
+// NaN は欠測値として尤度の計算から除く
+// d 階差分(季節項がある場合はさらに周期 s の D 階差分)した系列を平均(差分しない場合のみ推定。差分する場合は 0)の周りの
+// ARMA とし、状態空間表現のカルマンフィルタで求めた厳密な尤度を最大化して推定する(statsmodels の ARIMA と同じ推定方法)
+// 季節項は AR・MA の多項式の積 (1 - φ(B))(1 - Φ(B^s))、(1 + θ(B))(1 + Θ(B^s)) とする
+// AR は定常、MA は反転可能な範囲に制約する
+
+// ---- const
+
+const maxEvaluations = 2000 // 最尤推定の目的関数の評価回数の上限(パラメータ1個あたり)
+
+// ---- struct
+
+// Order ARIMA の次数(Period が 0 の場合は季節項なし)
+type Order struct {
+	P         int `json:"p"`         // AR の次数
+	D         int `json:"d"`         // 差分の階数
+	Q         int `json:"q"`         // MA の次数
+	SeasonalP int `json:"seasonalp"` // 季節 AR の次数
+	SeasonalD int `json:"seasonald"` // 季節差分の階数
+	SeasonalQ int `json:"seasonalq"` // 季節 MA の次数
+	Period    int `json:"period"`    // 季節の周期
+}
+
+// Model 推定した ARIMA モデル
+type Model struct {
+	Order         Order
+	Mean          float64   // 差分系列の平均(差分する場合は 0)
+	AR            []float64 // AR 係数 φ1..φp (w_t = φ1 w_t-1 + ... + ε_t)
+	MA            []float64 // MA 係数 θ1..θq (... + ε_t + θ1 ε_t-1 + ...)
+	SeasonalAR    []float64 // 季節 AR 係数 Φ1..ΦP
+	SeasonalMA    []float64 // 季節 MA 係数 Θ1..ΘQ
+	Sigma2        float64   // 誤差の分散
+	LogLikelihood float64   // 対数尤度
+	NObs          int       // 尤度の計算に用いた観測数(差分後の欠測値でない値の数)
+
+	state []float64   // 最後の観測の次の時点の状態の予測値
+	tails [][]float64 // 差分する前の系列の最後の値(差分の間隔の個数。予測を元の系列に戻すために用いる)
+}
+
+// ARMA(p,q) の状態空間表現(Harvey)。状態の次元は max(p, q+1)、観測は状態の先頭の要素
+// 遷移行列 T は1列目が AR 係数で1つ右上の対角が 1、誤差の係数 R は (1, θ1, ..., θq)
+type stateSpace struct {
+	dim   int
+	ar    []float64 // 状態の次元まで 0 を補った AR 係数(T の1列目)
+	noise []float64 // R
+}
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// String (public)"ARIMA(p,d,q)"、季節項がある場合は "ARIMA(p,d,q)(P,D,Q)[s]" の形式の文字列を返す
+func (o Order) String() string {
+	str := fmt.Sprintf("ARIMA(%d,%d,%d)", o.P, o.D, o.Q)
+	if o.Period > 0 {
+		str += fmt.Sprintf("(%d,%d,%d)[%d]", o.SeasonalP, o.SeasonalD, o.SeasonalQ, o.Period)
+	}
+	return str
+}
+
+// Validate (public)次数が負でないこと、季節項がある場合は周期が 2 以上であることを検証する
+func (o Order) Validate() error {
+	if o.P < 0 || o.D < 0 || o.Q < 0 || o.SeasonalP < 0 || o.SeasonalD < 0 || o.SeasonalQ < 0 || o.Period < 0 {
+		return fmt.Errorf("invalid order %s: negative order", o)
+	}
+	if o.Period == 1 || (o.Period == 0 && o.SeasonalP+o.SeasonalD+o.SeasonalQ > 0) {
+		return fmt.Errorf("invalid order %s: seasonal period must be 2 or more", o)
+	}
+	return nil
+}
+
+// ParseOrder (public)"p,d,q" または季節項を含む "p,d,q,P,D,Q,s" の形式の文字列を Order に変換する
+func ParseOrder(str string) (Order, error) {
+	fields := strings.Split(str, ",")
+	if len(fields) != 3 && len(fields) != 7 {
+		return Order{}, fmt.Errorf("invalid ARIMA order %q (p,d,q or p,d,q,P,D,Q,s)", str)
+	}
+	values := make([]int, 7)
+	for i, field := range fields {
+		v, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || v < 0 {
+			return Order{}, fmt.Errorf("invalid ARIMA order %q (p,d,q or p,d,q,P,D,Q,s)", str)
+		}
+		values[i] = v
+	}
+	order := Order{P: values[0], D: values[1], Q: values[2], SeasonalP: values[3], SeasonalD: values[4], SeasonalQ: values[5], Period: values[6]}
+	if err := order.Validate(); err != nil {
+		return Order{}, err
+	}
+	return order, nil
+}
+
+// Fit (public)values に ARIMA(order) を最尤推定で当てはめる
+func Fit(values []float64, order Order) (*Model, error) {
+
+	if err := order.Validate(); err != nil {
+		return nil, err
+	}
+	w, tails := difference(values, order.lags())
+	observed := 0
+	for _, v := range w {
+		if !math.IsNaN(v) {
+			observed++
+		}
+	}
+	hasMean := order.hasMean()
+	nParams := order.parameters()
+	if observed <= nParams {
+		return nil, fmt.Errorf("%s needs more than %d observations, got %d", order, nParams, observed)
+	}
+
+	// 平均は標本平均からの差を標準偏差で割った値を推定する(AR・MA 係数と同じ程度の大きさにする)
+	center, scale := meanStdDev(w)
+	if scale == 0 {
+		scale = 1
+	}
+	decode := func(x []float64) (float64, []float64, []float64, []float64, []float64) {
+		mean := 0.0
+		if hasMean == true {
+			mean, x = center+x[0]*scale, x[1:]
+		}
+		ar := constrainStationary(x[:order.P])
+		x = x[order.P:]
+		ma := constrainInvertible(x[:order.Q])
+		x = x[order.Q:]
+		seasonalAR := constrainStationary(x[:order.SeasonalP])
+		seasonalMA := constrainInvertible(x[order.SeasonalP:])
+		return mean, ar, ma, seasonalAR, seasonalMA
+	}
+	objective := func(x []float64) float64 {
+		mean, ar, ma, seasonalAR, seasonalMA := decode(x)
+		logLikelihood, _, _, ok := filter(w, mean, expand(ar, seasonalAR, order.Period, -1), expand(ma, seasonalMA, order.Period, 1), nil)
+		if ok == false {
+			return math.Inf(1)
+		}
+		return -logLikelihood
+	}
+
+	// 初期値は Hannan-Rissanen 法の推定値(季節項は 0)と 0 (ホワイトノイズ) の両方から探索し、尤度の大きい方を用いる
+	starts := [][]float64{make([]float64, nParams)}
+	if ar, ma, ok := hannanRissanen(w, center, order.P, order.Q); ok == true {
+		startAR, okAR := unconstrainStationary(ar)
+		startMA, okMA := unconstrainInvertible(ma)
+		if okAR == true && okMA == true {
+			var x []float64
+			if hasMean == true {
+				x = append(x, 0)
+			}
+			x = append(append(x, startAR...), startMA...)
+			x = append(x, make([]float64, order.SeasonalP+order.SeasonalQ)...)
+			starts = append(starts, x)
+		}
+	}
+	var best []float64
+	bestValue := math.Inf(1)
+	for _, start := range starts {
+		x, value := optimize.NelderMead(objective, start, maxEvaluations*max(1, nParams))
+		if value < bestValue {
+			best, bestValue = x, value
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%s: likelihood could not be evaluated", order)
+	}
+
+	mean, ar, ma, seasonalAR, seasonalMA := decode(best)
+	logLikelihood, sigma2, state, _ := filter(w, mean, expand(ar, seasonalAR, order.Period, -1), expand(ma, seasonalMA, order.Period, 1), nil)
+	return &Model{
+		Order:         order,
+		Mean:          mean,
+		AR:            ar,
+		MA:            ma,
+		SeasonalAR:    seasonalAR,
+		SeasonalMA:    seasonalMA,
+		Sigma2:        sigma2,
+		LogLikelihood: logLikelihood,
+		NObs:          observed,
+		state:         state,
+		tails:         tails,
+	}, nil
+}
+
+// Forecast (public)推定に用いた系列の次の時点から steps 期先までの予測値を返す
+func (m *Model) Forecast(steps int) []float64 {
+
+	s := newStateSpace(m.expandedAR(), m.expandedMA())
+	state, next := append([]float64(nil), m.state...), make([]float64, s.dim)
+	forecast := make([]float64, steps)
+	for h := range forecast {
+		forecast[h] = m.Mean + state[0]
+		s.transition(state, next)
+		state, next = next, state
+	}
+	// 差分した系列の予測値を、差分する前の系列の間隔前の値に足して元の系列に戻す
+	lags := m.Order.lags()
+	for k := len(lags) - 1; k >= 0; k-- {
+		levels := append(append([]float64(nil), m.tails[k]...), make([]float64, steps)...)
+		for h := range forecast {
+			levels[lags[k]+h] = levels[h] + forecast[h]
+		}
+		forecast = levels[lags[k]:]
+	}
+	return forecast
+}
+
+// Psi (public)推定に用いた系列(差分する前)の MA(∞) 表現の係数 ψ0..ψ(n-1) を返す(ψ0 = 1)
+// h 期先の予測誤差の分散は Sigma2 (ψ0² + ... + ψ(h-1)²)
+func (m *Model) Psi(n int) []float64 {
+
+	// AR の多項式 1 - Σφ_i B^i に差分の (1 - B^lag) を掛ける
+	polynomial := []float64{1}
+	for _, a := range m.expandedAR() {
+		polynomial = append(polynomial, -a)
+	}
+	for _, lag := range m.Order.lags() {
+		product := make([]float64, len(polynomial)+lag)
+		for i, c := range polynomial {
+			product[i] += c
+			product[i+lag] -= c
+		}
+		polynomial = product
+	}
+	ma := m.expandedMA()
+	psi := make([]float64, n)
+	for j := range psi {
+		if j == 0 {
+			psi[j] = 1
+			continue
+		}
+		if j <= len(ma) {
+			psi[j] = ma[j-1]
+		}
+		for i := 1; i < len(polynomial) && i <= j; i++ {
+			psi[j] -= polynomial[i] * psi[j-i]
+		}
+	}
+	return psi
+}
+
+//---- private function ----
+
+// 差分の間隔(d 個の 1 と、D 個の季節の周期)
+func (o Order) lags() []int {
+	var lags []int
+	for k := 0; k < o.D; k++ {
+		lags = append(lags, 1)
+	}
+	for k := 0; k < o.SeasonalD; k++ {
+		lags = append(lags, o.Period)
+	}
+	return lags
+}
+
+// 平均を推定するか(差分しない場合のみ)
+func (o Order) hasMean() bool {
+	return o.D == 0 && o.SeasonalD == 0
+}
+
+// 推定するパラメータの数(誤差の分散を除く)
+func (o Order) parameters() int {
+	n := o.P + o.Q + o.SeasonalP + o.SeasonalQ
+	if o.hasMean() == true {
+		n++
+	}
+	return n
+}
+
+// 季節項を展開した AR 係数
+func (m *Model) expandedAR() []float64 {
+	return expand(m.AR, m.SeasonalAR, m.Order.Period, -1)
+}
+
+// 季節項を展開した MA 係数
+func (m *Model) expandedMA() []float64 {
+	return expand(m.MA, m.SeasonalMA, m.Order.Period, 1)
+}
+
+// 係数と季節の係数の多項式の積を展開する
+// AR は (1 - Σa_i B^i)(1 - ΣA_j B^sj) の交差項の符号が負(sign = -1)、MA は (1 + Σm_i B^i)(1 + ΣM_j B^sj) で正(sign = 1)
+func expand(coefficients []float64, seasonal []float64, period int, sign float64) []float64 {
+	if len(seasonal) == 0 {
+		return coefficients
+	}
+	expanded := make([]float64, len(coefficients)+period*len(seasonal))
+	copy(expanded, coefficients)
+	for j, c := range seasonal {
+		expanded[period*(j+1)-1] += c
+		for i, a := range coefficients {
+			expanded[period*(j+1)+i] += sign * a * c
+		}
+	}
+	return expanded
+}
+
+// values を lags の間隔で順に差分した系列と、各差分の前の系列の最後の値(間隔の個数)を返す
+func difference(values []float64, lags []int) ([]float64, [][]float64) {
+	w := values
+	tails := make([][]float64, len(lags))
+	for k, lag := range lags {
+		tails[k] = make([]float64, lag)
+		for i := range tails[k] {
+			if j := len(w) - lag + i; j >= 0 {
+				tails[k][i] = w[j]
+			} else {
+				tails[k][i] = math.NaN()
+			}
+		}
+		next := make([]float64, max(0, len(w)-lag))
+		for i := range next {
+			next[i] = w[i+lag] - w[i]
+		}
+		w = next
+	}
+	return w, tails
+}
+
+// 欠測値を除いた平均と標準偏差(母標準偏差)を返す
+func meanStdDev(values []float64) (float64, float64) {
+	sum, n := 0.0, 0
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			sum += v
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	mean := sum / float64(n)
+	sq := 0.0
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			sq += (v - mean) * (v - mean)
+		}
+	}
+	return mean, math.Sqrt(sq / float64(n))
+}
+
+// 制約のない値を定常な AR 係数に変換する
+// 値を (-1, 1) の偏自己相関に変換し、Durbin-Levinson の漸化式で AR 係数を求める(Monahan (1984))
+func constrainStationary(x []float64) []float64 {
+	coefficients := make([]float64, len(x))
+	previous := make([]float64, len(x))
+	for k := range x {
+		r := x[k] / math.Sqrt(1+x[k]*x[k])
+		copy(previous, coefficients[:k])
+		for j := 0; j < k; j++ {
+			coefficients[j] = previous[j] - r*previous[k-1-j]
+		}
+		coefficients[k] = r
+	}
+	return coefficients
+}
+
+// constrainStationary の逆変換。定常でない係数の場合は false を返す
+func unconstrainStationary(coefficients []float64) ([]float64, bool) {
+	n := len(coefficients)
+	x := make([]float64, n)
+	current := append([]float64(nil), coefficients...)
+	for k := n - 1; k >= 0; k-- {
+		r := current[k]
+		if math.Abs(r) >= 1 {
+			return nil, false
+		}
+		x[k] = r / math.Sqrt(1-r*r)
+		previous := make([]float64, k)
+		for j := 0; j < k; j++ {
+			previous[j] = (current[j] + r*current[k-1-j]) / (1 - r*r)
+		}
+		current = previous
+	}
+	return x, true
+}
+
+// 制約のない値を反転可能な MA 係数に変換する(1 + θ(B) が反転可能 ⇔ -θ が定常な AR 係数)
+func constrainInvertible(x []float64) []float64 {
+	coefficients := constrainStationary(x)
+	for i := range coefficients {
+		coefficients[i] = -coefficients[i]
+	}
+	return coefficients
+}
+
+// constrainInvertible の逆変換。反転可能でない係数の場合は false を返す
+func unconstrainInvertible(coefficients []float64) ([]float64, bool) {
+	negative := make([]float64, len(coefficients))
+	for i, c := range coefficients {
+		negative[i] = -c
+	}
+	return unconstrainStationary(negative)
+}
+
+// ARMA(p,q) の状態空間表現(Harvey)を作る
+func newStateSpace(ar []float64, ma []float64) stateSpace {
+	dim := max(len(ar), len(ma)+1)
+	s := stateSpace{dim: dim, ar: make([]float64, dim), noise: make([]float64, dim)}
+	copy(s.ar, ar)
+	s.noise[0] = 1
+	copy(s.noise[1:], ma)
+	return s
+}
+
+// T a を out に求める
+func (s stateSpace) transition(a []float64, out []float64) {
+	for i := 0; i < s.dim; i++ {
+		out[i] = s.ar[i] * a[0]
+		if i+1 < s.dim {
+			out[i] += a[i+1]
+		}
+	}
+}
+
+// T P T' + R R' を out に求める(P、work、out は dim×dim の行優先の配列)
+func (s stateSpace) propagate(p []float64, work []float64, out []float64) {
+	d := s.dim
+	// work = T P
+	for i := 0; i < d; i++ {
+		for j := 0; j < d; j++ {
+			v := s.ar[i] * p[j]
+			if i+1 < d {
+				v += p[(i+1)*d+j]
+			}
+			work[i*d+j] = v
+		}
+	}
+	// out = work T' + R R'
+	for i := 0; i < d; i++ {
+		for j := 0; j < d; j++ {
+			v := work[i*d] * s.ar[j]
+			if j+1 < d {
+				v += work[i*d+j+1]
+			}
+			out[i*d+j] = v + s.noise[i]*s.noise[j]
+		}
+	}
+}
+
+// P = T P T' + R R' の解(定常分布の分散)を倍加法で求める
+// P = Σ T^k R R' T'^k を P ← P + A P A'、A ← A A (A の初期値は T)で 2 倍ずつの項まで求める
+func (s stateSpace) stationaryCovariance() ([]float64, bool) {
+	d := s.dim
+	power := make([]float64, d*d)
+	cov := make([]float64, d*d)
+	for i := 0; i < d; i++ {
+		power[i*d] = s.ar[i]
+		if i+1 < d {
+			power[i*d+i+1] = 1
+		}
+		for j := 0; j < d; j++ {
+			cov[i*d+j] = s.noise[i] * s.noise[j]
+		}
+	}
+	work := make([]float64, d*d)
+	next := make([]float64, d*d)
+	for iteration := 0; iteration < 100; iteration++ {
+		// next = cov + power cov power'
+		multiplyMatrix(power, cov, work, d, false)
+		multiplyMatrix(work, power, next, d, true)
+		converged := true
+		for k := range next {
+			next[k] += cov[k]
+			if math.IsNaN(next[k]) || math.IsInf(next[k], 0) {
+				return nil, false
+			}
+			if math.Abs(next[k]-cov[k]) > 1e-12*(1+math.Abs(next[k])) {
+				converged = false
+			}
+		}
+		cov, next = next, cov
+		if converged == true {
+			return cov, true
+		}
+		multiplyMatrix(power, power, work, d, false)
+		power, work = work, power
+	}
+	return nil, false
+}
+
+// カルマンフィルタで平均 mean の周りの ARMA の対数尤度(誤差の分散は最尤推定値で集約)を求める
+// 誤差の分散と、最後の観測の次の時点の状態の予測値もあわせて返す
+// innovations が nil でない場合は各時点の標準化した予測誤差(欠測値は NaN)を格納する
+// 状態の初期値の分散は定常分布の分散とし、分散が収束した後(欠測値までの間)は分散の更新を省く
+func filter(w []float64, mean float64, ar []float64, ma []float64, innovations []float64) (float64, float64, []float64, bool) {
+
+	s := newStateSpace(ar, ma)
+	d := s.dim
+	cov, ok := s.stationaryCovariance()
+	if ok == false {
+		return 0, 0, nil, false
+	}
+	state, next, gain := make([]float64, d), make([]float64, d), make([]float64, d)
+	updated, work, predicted := make([]float64, d*d), make([]float64, d*d), make([]float64, d*d)
+
+	sumSquares, sumLogF, n := 0.0, 0.0, 0
+	isSteady := false
+	for t, y := range w {
+		source := cov
+		isObserved := !math.IsNaN(y)
+		if innovations != nil {
+			innovations[t] = math.NaN()
+		}
+		if isObserved == true {
+			v := y - mean - state[0]
+			f := cov[0]
+			if !(f > 0) {
+				return 0, 0, nil, false
+			}
+			for i := range gain {
+				gain[i] = cov[i*d] / f
+				state[i] += gain[i] * v
+			}
+			if isSteady == false {
+				for i := 0; i < d; i++ {
+					for j := 0; j < d; j++ {
+						updated[i*d+j] = cov[i*d+j] - gain[i]*cov[j]
+					}
+				}
+				source = updated
+			}
+			if innovations != nil {
+				innovations[t] = v / math.Sqrt(f)
+			}
+			sumSquares += v * v / f
+			sumLogF += math.Log(f)
+			n++
+		} else {
+			isSteady = false
+		}
+		s.transition(state, next)
+		state, next = next, state
+		if isSteady == false {
+			s.propagate(source, work, predicted)
+			change := 0.0
+			for k := range predicted {
+				change = max(change, math.Abs(predicted[k]-cov[k]))
+			}
+			cov, predicted = predicted, cov
+			isSteady = isObserved == true && change < 1e-12*(1+math.Abs(cov[0]))
+		}
+	}
+	if n == 0 {
+		return 0, 0, nil, false
+	}
+	sigma2 := sumSquares / float64(n)
+	if !(sigma2 > 0) {
+		// 完全に当てはまる系列は誤差の分散を下限値として尤度を有限にする
+		sigma2 = math.SmallestNonzeroFloat64
+	}
+	logLikelihood := -0.5 * (float64(n)*(math.Log(2*math.Pi*sigma2)+1) + sumLogF)
+	if math.IsNaN(logLikelihood) || math.IsInf(logLikelihood, 0) {
+		return 0, 0, nil, false
+	}
+	return logLikelihood, sigma2, state, true
+}
+
+// 中心化した系列の長次数の AR の残差を用いて、AR・MA 係数を最小二乗法で推定する(Hannan-Rissanen 法)
+// 初期値に用いるため、欠測値は除いて連続した系列として扱う
+func hannanRissanen(w []float64, center float64, p int, q int) ([]float64, []float64, bool) {
+
+	var z []float64
+	for _, v := range w {
+		if !math.IsNaN(v) {
+			z = append(z, v-center)
+		}
+	}
+	if p == 0 && q == 0 {
+		return nil, nil, true
+	}
+
+	// MA がある場合は長次数の AR の残差を誤差の推定値とする
+	residual := make([]float64, len(z))
+	start := 0
+	if q > 0 {
+		long := max(p+q, int(10*math.Log10(float64(len(z)))))
+		long = min(long, len(z)/4)
+		if long < 1 {
+			return nil, nil, false
+		}
+		coefficients, ok := leastSquares(z, long, nil, 0, long)
+		if ok == false {
+			return nil, nil, false
+		}
+		for t := long; t < len(z); t++ {
+			residual[t] = z[t]
+			for i, c := range coefficients {
+				residual[t] -= c * z[t-1-i]
+			}
+		}
+		start = long
+	}
+	coefficients, ok := leastSquares(z, p, residual, q, start+max(p, q))
+	if ok == false {
+		return nil, nil, false
+	}
+	return coefficients[:p], coefficients[p:], true
+}
+
+// z_t を z_t-1..z_t-p と residual_t-1..residual_t-q に回帰した係数を返す(t は start 以降)
+func leastSquares(z []float64, p int, residual []float64, q int, start int) ([]float64, bool) {
+	k := p + q
+	if len(z)-start <= k {
+		return nil, false
+	}
+	normal := make([][]float64, k)
+	for i := range normal {
+		normal[i] = make([]float64, k)
+	}
+	rhs := make([]float64, k)
+	row := make([]float64, k)
+	for t := start; t < len(z); t++ {
+		for i := 0; i < p; i++ {
+			row[i] = z[t-1-i]
+		}
+		for i := 0; i < q; i++ {
+			row[p+i] = residual[t-1-i]
+		}
+		for i := range row {
+			rhs[i] += row[i] * z[t]
+			for j := range row {
+				normal[i][j] += row[i] * row[j]
+			}
+		}
+	}
+	return solve(normal, rhs)
+}
+
+// 連立一次方程式 a x = b を部分ピボット選択のガウスの消去法で解く。特異な場合は false を返す
+func solve(a [][]float64, b []float64) ([]float64, bool) {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = append(append([]float64(nil), a[i]...), b[i])
+	}
+	for col := 0; col < n; col++ {
+		pivot := col
+		for i := col + 1; i < n; i++ {
+			if math.Abs(m[i][col]) > math.Abs(m[pivot][col]) {
+				pivot = i
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-12 {
+			return nil, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for i := col + 1; i < n; i++ {
+			factor := m[i][col] / m[col][col]
+			for j := col; j <= n; j++ {
+				m[i][j] -= factor * m[col][j]
+			}
+		}
+	}
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		sum := m[i][n]
+		for j := i + 1; j < n; j++ {
+			sum -= m[i][j] * x[j]
+		}
+		x[i] = sum / m[i][i]
+	}
+	return x, true
+}
+
+// d×d の行優先の配列の積 a b (transposed の場合は a b') を out に求める
+func multiplyMatrix(a []float64, b []float64, out []float64, d int, transposed bool) {
+	for i := 0; i < d; i++ {
+		for j := 0; j < d; j++ {
+			v := 0.0
+			for k := 0; k < d; k++ {
+				if transposed == true {
+					v += a[i*d+k] * b[j*d+k]
+				} else {
+					v += a[i*d+k] * b[k*d+j]
+				}
+			}
+			out[i*d+j] = v
+		}
+	}
+}
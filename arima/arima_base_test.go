@@ -0,0 +1,283 @@
+package arima
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// 平均 mean の周りの ARMA(1,1) を n 個生成する(日付昇順)
+func simulateARMA11(n int, mean float64, ar float64, ma float64, seed uint64) []float64 {
+	random := rand.New(rand.NewPCG(seed, seed))
+	values := make([]float64, n)
+	previous, previousNoise := 0.0, 0.0
+	for i := -100; i < n; i++ { // 先頭の100個は初期値の影響を除くため捨てる
+		noise := random.NormFloat64()
+		current := ar*previous + noise + ma*previousNoise
+		if i >= 0 {
+			values[i] = mean + current
+		}
+		previous, previousNoise = current, noise
+	}
+	return values
+}
+
+// 共分散行列から直接求めた ARMA(1,1) の厳密な対数尤度(欠測値はその行・列を除く)
+func directLogLikelihood(w []float64, mean float64, ar float64, ma float64, sigma2 float64) float64 {
+	var index []int
+	for i, v := range w {
+		if !math.IsNaN(v) {
+			index = append(index, i)
+		}
+	}
+	gamma := func(lag int) float64 {
+		g0 := sigma2 * (1 + 2*ar*ma + ma*ma) / (1 - ar*ar)
+		if lag == 0 {
+			return g0
+		}
+		return sigma2 * (1 + ar*ma) * (ar + ma) / (1 - ar*ar) * math.Pow(ar, float64(lag-1))
+	}
+	n := len(index)
+	// コレスキー分解 Γ = L L'
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, n)
+		for j := 0; j <= i; j++ {
+			sum := gamma(index[i] - index[j])
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				l[i][i] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	// L z = (w - mean) を解く
+	z := make([]float64, n)
+	logDet, quadratic := 0.0, 0.0
+	for i := range z {
+		sum := w[index[i]] - mean
+		for k := 0; k < i; k++ {
+			sum -= l[i][k] * z[k]
+		}
+		z[i] = sum / l[i][i]
+		logDet += 2 * math.Log(l[i][i])
+		quadratic += z[i] * z[i]
+	}
+	return -0.5 * (float64(n)*math.Log(2*math.Pi) + logDet + quadratic)
+}
+
+func TestParseOrder(t *testing.T) {
+	order, err := ParseOrder("1, 0,2")
+	if err != nil || order != (Order{P: 1, D: 0, Q: 2}) {
+		t.Errorf("ParseOrder = %v, %v", order, err)
+	}
+	if order.String() != "ARIMA(1,0,2)" {
+		t.Errorf("String = %s", order.String())
+	}
+	order, err = ParseOrder("1,0,1,1,0,1,7")
+	if err != nil || order != (Order{P: 1, Q: 1, SeasonalP: 1, SeasonalQ: 1, Period: 7}) {
+		t.Errorf("ParseOrder seasonal = %v, %v", order, err)
+	}
+	if order.String() != "ARIMA(1,0,1)(1,0,1)[7]" {
+		t.Errorf("String = %s", order.String())
+	}
+	for _, str := range []string{"", "1,0", "1,0,1,1", "a,0,1", "-1,0,1", "1,0,1,1,0,0,0", "1,0,1,0,0,0,1"} {
+		if _, err := ParseOrder(str); err == nil {
+			t.Errorf("ParseOrder(%q) expected error", str)
+		}
+	}
+}
+
+func TestStationaryTransform(t *testing.T) {
+	x := []float64{0.7, -1.5, 0.2}
+	coefficients := constrainStationary(x)
+	back, ok := unconstrainStationary(coefficients)
+	if ok == false {
+		t.Fatalf("unconstrainStationary(%v) not stationary", coefficients)
+	}
+	for i := range x {
+		if math.Abs(back[i]-x[i]) > 1e-12 {
+			t.Errorf("round trip = %v, want %v", back, x)
+			break
+		}
+	}
+	// AR(1) の係数は偏自己相関そのもの
+	if c := constrainStationary([]float64{1})[0]; math.Abs(c-1/math.Sqrt(2)) > 1e-15 {
+		t.Errorf("AR(1) = %v", c)
+	}
+	if _, ok := unconstrainStationary([]float64{1.2}); ok == true {
+		t.Error("unconstrainStationary(1.2) expected not stationary")
+	}
+}
+
+func TestFilterLikelihood(t *testing.T) {
+	// カルマンフィルタの尤度が共分散行列から直接求めた厳密な尤度と一致すること(欠測値を含む)
+	w := []float64{1.2, math.NaN(), 0.4, 2.5, 1.9, -0.3, 0.8, 1.1, math.NaN(), 2.2}
+	mean, ar, ma := 1.0, 0.6, 0.3
+	logLikelihood, sigma2, _, ok := filter(w, mean, []float64{ar}, []float64{ma}, nil)
+	if ok == false {
+		t.Fatal("filter failed")
+	}
+	if want := directLogLikelihood(w, mean, ar, ma, sigma2); math.Abs(logLikelihood-want) > 1e-9 {
+		t.Errorf("log likelihood = %v, want %v", logLikelihood, want)
+	}
+	// 集約した誤差の分散は尤度を最大にする値
+	for _, s := range []float64{sigma2 * 0.9, sigma2 * 1.1} {
+		if directLogLikelihood(w, mean, ar, ma, s) >= logLikelihood {
+			t.Errorf("sigma2 = %v is not the maximum likelihood estimate", sigma2)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	values := simulateARMA11(3000, 2, 0.5, 0.4, 1)
+	model, err := Fit(values, Order{P: 1, D: 0, Q: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		name string
+		got  float64
+		want float64
+		tol  float64
+	}{
+		{"mean", model.Mean, 2, 0.15},
+		{"ar", model.AR[0], 0.5, 0.06},
+		{"ma", model.MA[0], 0.4, 0.06},
+		{"sigma2", model.Sigma2, 1, 0.08},
+	}
+	for _, c := range checks {
+		if math.Abs(c.got-c.want) > c.tol {
+			t.Errorf("%s = %v, want %v±%v", c.name, c.got, c.want, c.tol)
+		}
+	}
+	if model.NObs != 3000 {
+		t.Errorf("NObs = %d", model.NObs)
+	}
+	// 推定値の尤度は真のパラメータの尤度以上
+	truth, _, _, _ := filter(values, 2, []float64{0.5}, []float64{0.4}, nil)
+	if model.LogLikelihood < truth {
+		t.Errorf("log likelihood %v < true parameter %v", model.LogLikelihood, truth)
+	}
+
+	if _, err := Fit(values[:3], Order{P: 1, D: 0, Q: 1}); err == nil {
+		t.Error("Fit with 3 observations expected error")
+	}
+}
+
+func TestForecast(t *testing.T) {
+	values := simulateARMA11(500, 10, 0.7, 0, 2)
+
+	// AR(1) の予測は平均に向かって φ 倍ずつ減衰する
+	model, err := Fit(values, Order{P: 1, D: 0, Q: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forecast := model.Forecast(3)
+	deviation := values[len(values)-1] - model.Mean
+	for h, got := range forecast {
+		deviation *= model.AR[0]
+		if want := model.Mean + deviation; math.Abs(got-want) > 1e-9 {
+			t.Errorf("AR(1) forecast[%d] = %v, want %v", h, got, want)
+		}
+	}
+
+	// ARIMA(1,1,0) は差分の予測を最後の値に積み上げる
+	levels := make([]float64, len(values))
+	for i := range values {
+		levels[i] = values[i]
+		if i > 0 {
+			levels[i] += levels[i-1]
+		}
+	}
+	model, err = Fit(levels, Order{P: 1, D: 1, Q: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.Mean != 0 {
+		t.Errorf("ARIMA(1,1,0) mean = %v, want 0", model.Mean)
+	}
+	forecast = model.Forecast(2)
+	last := levels[len(levels)-1]
+	diff := levels[len(levels)-1] - levels[len(levels)-2]
+	want := []float64{last + model.AR[0]*diff, last + model.AR[0]*diff + model.AR[0]*model.AR[0]*diff}
+	for h := range want {
+		if math.Abs(forecast[h]-want[h]) > 1e-9 {
+			t.Errorf("ARIMA(1,1,0) forecast[%d] = %v, want %v", h, forecast[h], want[h])
+		}
+	}
+}
+
+func TestSeasonal(t *testing.T) {
+	// (1 - 0.5B)(1 - 0.3B^4) = 1 - 0.5B - 0.3B^4 + 0.15B^5
+	ar := expand([]float64{0.5}, []float64{0.3}, 4, -1)
+	want := []float64{0.5, 0, 0, 0.3, -0.15}
+	// (1 + 0.5B)(1 + 0.3B^4) = 1 + 0.5B + 0.3B^4 + 0.15B^5
+	ma := expand([]float64{0.5}, []float64{0.3}, 4, 1)
+	wantMA := []float64{0.5, 0, 0, 0.3, 0.15}
+	for i := range want {
+		if math.Abs(ar[i]-want[i]) > 1e-15 || math.Abs(ma[i]-wantMA[i]) > 1e-15 {
+			t.Fatalf("expand = %v, %v, want %v, %v", ar, ma, want, wantMA)
+		}
+	}
+
+	// 季節差分のみのモデル(周期4)の予測は1周期前の値の繰り返し
+	values := []float64{1, 5, 2, 8, 1.5, 5.5, 2.5, 8.5, 2, 6, 3, 9}
+	model, err := Fit(values, Order{SeasonalD: 1, Period: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forecast := model.Forecast(8)
+	for h, got := range forecast {
+		if want := values[8+h%4]; math.Abs(got-want) > 1e-12 {
+			t.Errorf("seasonal random walk forecast[%d] = %v, want %v", h, got, want)
+		}
+	}
+
+	// 季節 AR の係数を推定できること
+	random := rand.New(rand.NewPCG(3, 3))
+	series := make([]float64, 2000)
+	for i := range series {
+		series[i] = random.NormFloat64()
+		if i >= 7 {
+			series[i] += 0.6 * series[i-7]
+		}
+	}
+	model, err = Fit(series, Order{SeasonalP: 1, Period: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(model.SeasonalAR[0]-0.6) > 0.05 {
+		t.Errorf("seasonal AR = %v, want 0.6", model.SeasonalAR[0])
+	}
+}
+
+func TestPsi(t *testing.T) {
+	// ARMA(1,1) は ψj = (φ + θ) φ^(j-1)
+	model := &Model{Order: Order{P: 1, Q: 1}, AR: []float64{0.5}, MA: []float64{0.4}}
+	for j, got := range model.Psi(5) {
+		want := 1.0
+		if j > 0 {
+			want = 0.9 * math.Pow(0.5, float64(j-1))
+		}
+		if math.Abs(got-want) > 1e-12 {
+			t.Errorf("ARMA(1,1) psi[%d] = %v, want %v", j, got, want)
+		}
+	}
+	// ランダムウォーク ARIMA(0,1,0) は全て 1、季節ランダムウォーク(周期4)は周期毎に 1
+	model = &Model{Order: Order{D: 1}}
+	for j, got := range model.Psi(4) {
+		if got != 1 {
+			t.Errorf("random walk psi[%d] = %v, want 1", j, got)
+		}
+	}
+	model = &Model{Order: Order{SeasonalD: 1, Period: 4}}
+	for j, got := range model.Psi(9) {
+		if want := map[bool]float64{true: 1, false: 0}[j%4 == 0]; got != want {
+			t.Errorf("seasonal random walk psi[%d] = %v, want %v", j, got, want)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+// arima ARIMA(p,d,q) モデルの推定と予測パッケージ
+package arima // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// 次数の自動選択
+// 差分の階数は ADF 検定で単位根が棄却され、かつ KPSS 検定で定常性が棄却されなくなるまで 1 階ずつ増やして決める
+// (差分の階数が異なるモデルは尤度を計算する系列が異なり情報量規準で比較できないため、探索の前に決める)
+// AR・MA (と季節 AR・季節 MA) の次数は探索範囲の全ての組み合わせを推定し、情報量規準が最小の次数を選ぶ
+
+// ---- const
+
+// Criterion 次数の選択に用いる情報量規準
+type Criterion string
+
+const (
+	CriterionAIC Criterion = "aic" // 赤池情報量規準 -2logL + 2k
+	CriterionBIC Criterion = "bic" // ベイズ情報量規準 -2logL + k log(n)
+)
+
+// ADF 検定(定数項あり)の 5% 棄却値の MacKinnon (2010) の応答曲面の係数
+const adfCritical5 = -2.86154
+const adfCritical5N1 = -2.8903
+const adfCritical5N2 = -4.234
+
+const kpssCritical5 = 0.463 // KPSS 検定(水準の定常性)の 5% 棄却値
+
+const minAutoObservations = 20 // 自動選択に必要な観測数
+
+// ---- struct
+
+// SearchSpace 次数の探索範囲
+type SearchSpace struct {
+	MaxP         int       // AR の最大次数
+	MaxD         int       // 差分の最大階数
+	MaxQ         int       // MA の最大次数
+	Period       int       // 季節の周期(0 は季節項を探索しない)
+	MaxSeasonalP int       // 季節 AR の最大次数
+	MaxSeasonalQ int       // 季節 MA の最大次数
+	Criterion    Criterion // 選択に用いる情報量規準
+}
+
+// TestResult 定常性の検定の結果
+type TestResult struct {
+	Statistic  float64 `json:"statistic"`  // 検定統計量
+	Critical   float64 `json:"critical"`   // 5% 棄却値
+	Lags       int     `json:"lags"`       // ラグ数
+	Stationary bool    `json:"stationary"` // 5% 水準で定常と判定したか
+}
+
+// DifferencingTest 差分の階数毎の定常性の検定の結果
+type DifferencingTest struct {
+	D    int        `json:"d"`
+	ADF  TestResult `json:"adf"`  // 単位根の検定(帰無仮説は単位根あり)
+	KPSS TestResult `json:"kpss"` // 定常性の検定(帰無仮説は定常)
+}
+
+// Candidate 探索した次数の尤度と情報量規準
+type Candidate struct {
+	Order         Order   `json:"order"`
+	LogLikelihood float64 `json:"loglikelihood"`
+	AIC           float64 `json:"aic"`
+	BIC           float64 `json:"bic"`
+}
+
+// LjungBox 残差の自己相関の Ljung-Box 検定の結果
+type LjungBox struct {
+	Statistic float64 `json:"statistic"`
+	Lags      int     `json:"lags"`
+	DF        int     `json:"df"`     // 自由度(ラグ数 - AR・MA の係数の数)
+	PValue    float64 `json:"pvalue"` // 小さい(0.05 未満など)場合は残差に自己相関が残っている
+}
+
+// Diagnostics 選択したモデルの推定値と診断
+type Diagnostics struct {
+	Mean          float64   `json:"mean"`
+	AR            []float64 `json:"ar"`
+	MA            []float64 `json:"ma"`
+	SeasonalAR    []float64 `json:"seasonalar"`
+	SeasonalMA    []float64 `json:"seasonalma"`
+	Sigma2        float64   `json:"sigma2"`
+	LogLikelihood float64   `json:"loglikelihood"`
+	AIC           float64   `json:"aic"`
+	BIC           float64   `json:"bic"`
+	NObs          int       `json:"nobs"`
+	LjungBox      LjungBox  `json:"ljungbox"`
+}
+
+// Selection 次数の自動選択の結果
+type Selection struct {
+	Order       Order              `json:"order"` // 選択した次数
+	Criterion   Criterion          `json:"criterion"`
+	Tests       []DifferencingTest `json:"tests"`      // 差分の階数を決めた検定の結果
+	Candidates  []Candidate        `json:"candidates"` // 推定できた次数(情報量規準の昇順)
+	Diagnostics Diagnostics        `json:"diagnostics"`
+	Model       *Model             `json:"-"` // 選択したモデル(JSON には保存しない)
+}
+
+// ---- Global Variable
+
+// DefaultSearchSpace 既定の探索範囲(季節項なし、AIC で選択)
+var DefaultSearchSpace = SearchSpace{MaxP: 3, MaxD: 2, MaxQ: 3, MaxSeasonalP: 1, MaxSeasonalQ: 1, Criterion: CriterionAIC}
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// ParseCriterion (public)文字列を Criterion に変換する
+func ParseCriterion(str string) (Criterion, error) {
+	switch criterion := Criterion(strings.ToLower(str)); criterion {
+	case CriterionAIC, CriterionBIC:
+		return criterion, nil
+	}
+	return "", fmt.Errorf("unknown criterion %q (aic / bic)", str)
+}
+
+// String (public)探索範囲を文字列で返す(探索結果を再利用できるかの判定に用いる)
+func (s SearchSpace) String() string {
+	limit := Order{P: s.MaxP, D: s.MaxD, Q: s.MaxQ}
+	if s.Period > 0 {
+		limit.SeasonalP, limit.SeasonalQ, limit.Period = s.MaxSeasonalP, s.MaxSeasonalQ, s.Period
+	}
+	return fmt.Sprintf("max=%s criterion=%s", limit, s.Criterion)
+}
+
+// AIC (public)赤池情報量規準(パラメータの数に誤差の分散を含む)
+func (m *Model) AIC() float64 {
+	return -2*m.LogLikelihood + 2*float64(m.Order.parameters()+1)
+}
+
+// BIC (public)ベイズ情報量規準(パラメータの数に誤差の分散を含む)
+func (m *Model) BIC() float64 {
+	return -2*m.LogLikelihood + math.Log(float64(m.NObs))*float64(m.Order.parameters()+1)
+}
+
+// ADF (public)定数項ありの拡張 Dickey-Fuller 検定(帰無仮説は単位根あり)
+// Δy_t = α + β y_t-1 + Σγ_i Δy_t-i の β の t 値を検定統計量とし、ラグ数は trunc((n-1)^(1/3)) とする
+// 欠測値は除いて連続した系列として扱う。観測数が足りない場合は検定統計量を NaN とする
+func ADF(values []float64) TestResult {
+
+	y := observedValues(values)
+	lags := int(math.Cbrt(float64(len(y) - 1)))
+	result := TestResult{Statistic: math.NaN(), Critical: math.NaN(), Lags: lags}
+	if len(y) < 2 {
+		return result
+	}
+	dy := make([]float64, len(y)-1)
+	for t := range dy {
+		dy[t] = y[t+1] - y[t]
+	}
+	var rows [][]float64
+	var response []float64
+	for t := lags; t < len(dy); t++ {
+		row := []float64{1, y[t]}
+		for i := 1; i <= lags; i++ {
+			row = append(row, dy[t-i])
+		}
+		rows = append(rows, row)
+		response = append(response, dy[t])
+	}
+	k := 2 + lags
+	if len(rows) <= k+1 {
+		return result
+	}
+	coefficients, rss, normal, ok := ordinaryLeastSquares(rows, response)
+	if ok == false {
+		return result
+	}
+	// β の分散は s^2 (X'X)^-1 の対角要素
+	unit := make([]float64, k)
+	unit[1] = 1
+	inverse, ok := solve(normal, unit)
+	if ok == false {
+		return result
+	}
+	n := float64(len(rows))
+	result.Statistic = coefficients[1] / math.Sqrt(rss/(n-float64(k))*inverse[1])
+	result.Critical = adfCritical5 + adfCritical5N1/n + adfCritical5N2/(n*n)
+	result.Stationary = result.Statistic < result.Critical
+	return result
+}
+
+// KPSS (public)水準の定常性の KPSS 検定(帰無仮説は定常)
+// 長期分散は Bartlett カーネルの Newey-West 推定量で、ラグ数は trunc(4 (n/100)^(1/4)) とする
+// 欠測値は除いて連続した系列として扱う。観測数が足りない場合は検定統計量を NaN とする
+func KPSS(values []float64) TestResult {
+
+	y := observedValues(values)
+	n := len(y)
+	lags := int(4 * math.Pow(float64(n)/100, 0.25))
+	result := TestResult{Statistic: math.NaN(), Critical: kpssCritical5, Lags: lags}
+	if n <= lags+1 {
+		return result
+	}
+	mean, _ := meanStdDev(y)
+	residual := make([]float64, n)
+	partial, sumPartial, variance := 0.0, 0.0, 0.0
+	for t, v := range y {
+		residual[t] = v - mean
+		partial += residual[t]
+		sumPartial += partial * partial
+		variance += residual[t] * residual[t]
+	}
+	for j := 1; j <= lags; j++ {
+		weight := 1 - float64(j)/float64(lags+1)
+		covariance := 0.0
+		for t := j; t < n; t++ {
+			covariance += residual[t] * residual[t-j]
+		}
+		variance += 2 * weight * covariance
+	}
+	variance /= float64(n)
+	if !(variance > 0) {
+		return result
+	}
+	result.Statistic = sumPartial / (float64(n) * float64(n) * variance)
+	result.Stationary = result.Statistic < result.Critical
+	return result
+}
+
+// AutoSelect (public)探索範囲の中から情報量規準が最小の次数を選び、そのモデルを推定する
+func AutoSelect(values []float64, space SearchSpace) (*Selection, error) {
+
+	if space.MaxP < 0 || space.MaxD < 0 || space.MaxQ < 0 || space.MaxSeasonalP < 0 || space.MaxSeasonalQ < 0 || space.Period < 0 || space.Period == 1 {
+		return nil, fmt.Errorf("invalid search space %s", space)
+	}
+	if _, err := ParseCriterion(string(space.Criterion)); err != nil {
+		return nil, err
+	}
+	if observed := len(observedValues(values)); observed < minAutoObservations {
+		return nil, fmt.Errorf("auto ARIMA needs %d observations, got %d", minAutoObservations, observed)
+	}
+
+	// 差分の階数
+	selection := &Selection{Criterion: space.Criterion}
+	d := space.MaxD
+	for k := 0; k <= space.MaxD; k++ {
+		w, _ := difference(values, Order{D: k}.lags())
+		test := DifferencingTest{D: k, ADF: ADF(w), KPSS: KPSS(w)}
+		selection.Tests = append(selection.Tests, test)
+		if test.ADF.Stationary == true && test.KPSS.Stationary == true {
+			d = k
+			break
+		}
+	}
+
+	// AR・MA の次数(同じ値の場合は先に推定した次数の小さいモデルを選ぶ)
+	maxSeasonalP, maxSeasonalQ := 0, 0
+	if space.Period > 0 {
+		maxSeasonalP, maxSeasonalQ = space.MaxSeasonalP, space.MaxSeasonalQ
+	}
+	criterion := func(c Candidate) float64 {
+		if space.Criterion == CriterionBIC {
+			return c.BIC
+		}
+		return c.AIC
+	}
+	for seasonalP := 0; seasonalP <= maxSeasonalP; seasonalP++ {
+		for seasonalQ := 0; seasonalQ <= maxSeasonalQ; seasonalQ++ {
+			for p := 0; p <= space.MaxP; p++ {
+				for q := 0; q <= space.MaxQ; q++ {
+					order := Order{P: p, D: d, Q: q}
+					if seasonalP+seasonalQ > 0 {
+						order.SeasonalP, order.SeasonalQ, order.Period = seasonalP, seasonalQ, space.Period
+					}
+					model, err := Fit(values, order)
+					if err != nil {
+						continue
+					}
+					candidate := Candidate{Order: order, LogLikelihood: model.LogLikelihood, AIC: model.AIC(), BIC: model.BIC()}
+					if selection.Model == nil || criterion(candidate) < criterion(selection.Candidates[0]) {
+						selection.Model = model
+						selection.Candidates = append([]Candidate{candidate}, selection.Candidates...)
+					} else {
+						selection.Candidates = append(selection.Candidates, candidate)
+					}
+				}
+			}
+		}
+	}
+	if selection.Model == nil {
+		return nil, fmt.Errorf("no ARIMA order could be fitted in %s", space)
+	}
+	slices.SortStableFunc(selection.Candidates[1:], func(a, b Candidate) int { return cmp.Compare(criterion(a), criterion(b)) })
+
+	model := selection.Model
+	selection.Order = model.Order
+	selection.Diagnostics = Diagnostics{
+		Mean:          model.Mean,
+		AR:            model.AR,
+		MA:            model.MA,
+		SeasonalAR:    model.SeasonalAR,
+		SeasonalMA:    model.SeasonalMA,
+		Sigma2:        model.Sigma2,
+		LogLikelihood: model.LogLikelihood,
+		AIC:           model.AIC(),
+		BIC:           model.BIC(),
+		NObs:          model.NObs,
+		LjungBox:      model.ljungBox(values),
+	}
+	return selection, nil
+}
+
+//---- private function ----
+
+// 欠測値を除いた系列
+func observedValues(values []float64) []float64 {
+	var observed []float64
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			observed = append(observed, v)
+		}
+	}
+	return observed
+}
+
+// 最小二乗法で回帰係数、残差平方和、正規方程式の係数行列 X'X を求める
+func ordinaryLeastSquares(rows [][]float64, response []float64) ([]float64, float64, [][]float64, bool) {
+	k := len(rows[0])
+	normal := make([][]float64, k)
+	for i := range normal {
+		normal[i] = make([]float64, k)
+	}
+	rhs := make([]float64, k)
+	for t, row := range rows {
+		for i := range row {
+			rhs[i] += row[i] * response[t]
+			for j := range row {
+				normal[i][j] += row[i] * row[j]
+			}
+		}
+	}
+	coefficients, ok := solve(normal, rhs)
+	if ok == false {
+		return nil, 0, nil, false
+	}
+	rss := 0.0
+	for t, row := range rows {
+		e := response[t]
+		for i, v := range row {
+			e -= coefficients[i] * v
+		}
+		rss += e * e
+	}
+	return coefficients, rss, normal, true
+}
+
+// 推定に用いた系列 values の標準化した残差の Ljung-Box 検定
+// ラグ数は 10 (季節項がある場合は周期の 2 倍)とし、観測数の 1/5 を上限とする
+func (m *Model) ljungBox(values []float64) LjungBox {
+
+	w, _ := difference(values, m.Order.lags())
+	innovations := make([]float64, len(w))
+	filter(w, m.Mean, m.expandedAR(), m.expandedMA(), innovations)
+	e := observedValues(innovations)
+	n := len(e)
+	lags := 10
+	if m.Order.Period > 0 {
+		lags = 2 * m.Order.Period
+	}
+	lags = min(lags, n/5)
+	df := max(1, lags-(m.Order.P+m.Order.Q+m.Order.SeasonalP+m.Order.SeasonalQ))
+	result := LjungBox{Lags: lags, DF: df, PValue: 1}
+	mean, _ := meanStdDev(e)
+	denominator := 0.0
+	for _, v := range e {
+		denominator += (v - mean) * (v - mean)
+	}
+	if lags < 1 || denominator == 0 {
+		return result
+	}
+	for k := 1; k <= lags; k++ {
+		autocovariance := 0.0
+		for t := k; t < n; t++ {
+			autocovariance += (e[t] - mean) * (e[t-k] - mean)
+		}
+		r := autocovariance / denominator
+		result.Statistic += r * r / float64(n-k)
+	}
+	result.Statistic *= float64(n) * float64(n+2)
+	result.PValue = upperIncompleteGamma(float64(df)/2, result.Statistic/2)
+	return result
+}
+
+// 正規化した上側不完全ガンマ関数 Q(a, x) (カイ二乗分布の上側確率は Q(df/2, x/2))
+// x < a+1 は級数展開、それ以外は連分数展開で求める
+func upperIncompleteGamma(a float64, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	lgamma, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lgamma)
+	if x < a+1 {
+		term, sum := 1/a, 1/a
+		for n := 1; n < 500 && math.Abs(term) > math.Abs(sum)*1e-15; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+		}
+		return max(0, 1-prefix*sum)
+	}
+	// Lentz 法
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < 500; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return prefix * h
+}
@@ -0,0 +1,156 @@
+package arima
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// ホワイトノイズと、その累積和のランダムウォークを n 個生成する(日付昇順)
+func whiteNoiseAndRandomWalk(n int, seed uint64) ([]float64, []float64) {
+	random := rand.New(rand.NewPCG(seed, seed))
+	noise := make([]float64, n)
+	walk := make([]float64, n)
+	for i := range noise {
+		noise[i] = random.NormFloat64()
+		walk[i] = noise[i]
+		if i > 0 {
+			walk[i] += walk[i-1]
+		}
+	}
+	return noise, walk
+}
+
+func TestParseCriterion(t *testing.T) {
+	if c, err := ParseCriterion("BIC"); err != nil || c != CriterionBIC {
+		t.Errorf("ParseCriterion(BIC) = %v, %v", c, err)
+	}
+	if _, err := ParseCriterion("hqic"); err == nil {
+		t.Error("ParseCriterion(hqic) expected error")
+	}
+	space := SearchSpace{MaxP: 2, MaxD: 1, MaxQ: 2, Period: 7, MaxSeasonalP: 1, MaxSeasonalQ: 0, Criterion: CriterionBIC}
+	if want := "max=ARIMA(2,1,2)(1,0,0)[7] criterion=bic"; space.String() != want {
+		t.Errorf("String = %s, want %s", space.String(), want)
+	}
+}
+
+func TestStationarityTests(t *testing.T) {
+	noise, walk := whiteNoiseAndRandomWalk(500, 4)
+	tests := []struct {
+		name       string
+		values     []float64
+		stationary bool
+	}{
+		{"white noise", noise, true},
+		{"random walk", walk, false},
+	}
+	for _, tt := range tests {
+		adf, kpss := ADF(tt.values), KPSS(tt.values)
+		if adf.Stationary != tt.stationary {
+			t.Errorf("%s: ADF = %+v, want stationary %v", tt.name, adf, tt.stationary)
+		}
+		if kpss.Stationary != tt.stationary {
+			t.Errorf("%s: KPSS = %+v, want stationary %v", tt.name, kpss, tt.stationary)
+		}
+	}
+	// ラグ数と棄却値
+	adf := ADF(noise)
+	if adf.Lags != 7 || math.Abs(adf.Critical-(-2.8673)) > 1e-3 {
+		t.Errorf("ADF lags = %d, critical = %v", adf.Lags, adf.Critical)
+	}
+	if kpss := KPSS(noise); kpss.Lags != 5 || kpss.Critical != 0.463 {
+		t.Errorf("KPSS lags = %d, critical = %v", kpss.Lags, kpss.Critical)
+	}
+	if math.IsNaN(ADF(noise[:3]).Statistic) == false {
+		t.Error("ADF with 3 observations expected NaN")
+	}
+}
+
+func TestUpperIncompleteGamma(t *testing.T) {
+	// カイ二乗分布の上側確率
+	tests := []struct {
+		df   float64
+		x    float64
+		want float64
+	}{
+		{1, 3.841459, 0.05},
+		{10, 18.307038, 0.05},
+		{2, 1.5, math.Exp(-0.75)},
+		{5, 0.5, 0.99217},
+		{3, 40, 1.0e-8},
+	}
+	for _, tt := range tests {
+		if got := upperIncompleteGamma(tt.df/2, tt.x/2); math.Abs(got-tt.want) > 1e-5*max(1, tt.want*10) && math.Abs(got-tt.want)/tt.want > 0.1 {
+			t.Errorf("Q(%v, %v) = %v, want %v", tt.df/2, tt.x/2, got, tt.want)
+		}
+	}
+}
+
+func TestAutoSelect(t *testing.T) {
+	// AR(1) (φ = 0.7) は差分せずに ARIMA(1,0,0) を選ぶ
+	values := simulateARMA11(1000, 5, 0.7, 0, 5)
+	space := SearchSpace{MaxP: 2, MaxD: 2, MaxQ: 2, Criterion: CriterionBIC}
+	selection, err := AutoSelect(values, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selection.Order != (Order{P: 1}) {
+		t.Errorf("order = %s, want ARIMA(1,0,0)", selection.Order)
+	}
+	if len(selection.Tests) != 1 || selection.Tests[0].D != 0 {
+		t.Errorf("tests = %+v, want stationary at d = 0", selection.Tests)
+	}
+	if len(selection.Candidates) != 9 {
+		t.Errorf("candidates = %d, want 9", len(selection.Candidates))
+	}
+	for i := 1; i < len(selection.Candidates); i++ {
+		if selection.Candidates[i].BIC < selection.Candidates[i-1].BIC {
+			t.Errorf("candidates are not sorted by BIC: %+v", selection.Candidates)
+			break
+		}
+	}
+	if c := selection.Candidates[0]; c.Order != selection.Order || c.BIC != selection.Diagnostics.BIC {
+		t.Errorf("best candidate = %+v, diagnostics = %+v", c, selection.Diagnostics)
+	}
+	if d := selection.Diagnostics; math.Abs(d.AR[0]-0.7) > 0.06 || d.LjungBox.PValue < 0.01 || d.LjungBox.Lags != 10 {
+		t.Errorf("diagnostics = %+v", d)
+	}
+
+	// ランダムウォークは1階差分する
+	_, walk := whiteNoiseAndRandomWalk(500, 6)
+	selection, err = AutoSelect(walk, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selection.Order.D != 1 || len(selection.Tests) != 2 {
+		t.Errorf("random walk order = %s, tests = %+v", selection.Order, selection.Tests)
+	}
+
+	// 季節 AR (周期7) を選ぶ(KPSS 検定のラグ数では季節の自己相関を吸収できないため差分の階数は 0 に固定する)
+	random := rand.New(rand.NewPCG(7, 7))
+	seasonal := make([]float64, 700)
+	for i := range seasonal {
+		seasonal[i] = random.NormFloat64()
+		if i >= 7 {
+			seasonal[i] += 0.6 * seasonal[i-7]
+		}
+	}
+	space = SearchSpace{MaxP: 1, MaxD: 0, MaxQ: 1, Period: 7, MaxSeasonalP: 1, MaxSeasonalQ: 1, Criterion: CriterionBIC}
+	selection, err = AutoSelect(seasonal, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selection.Order != (Order{SeasonalP: 1, Period: 7}) {
+		t.Errorf("seasonal order = %s, want ARIMA(0,0,0)(1,0,0)[7]", selection.Order)
+	}
+	if selection.Diagnostics.LjungBox.Lags != 14 {
+		t.Errorf("seasonal Ljung-Box lags = %d, want 14", selection.Diagnostics.LjungBox.Lags)
+	}
+
+	if _, err := AutoSelect(values[:10], space); err == nil {
+		t.Error("AutoSelect with 10 observations expected error")
+	}
+	if _, err := AutoSelect(values, SearchSpace{MaxP: 1, Criterion: "hqic"}); err == nil {
+		t.Error("AutoSelect with unknown criterion expected error")
+	}
+}
@@ -0,0 +1,195 @@
+// backtest 予測精度の評価パッケージ
+package backtest // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"math"
+)
+
+// 予測精度の指標と、ランダムウォーク(起点の実績を予測値とする)との Diebold-Mariano 検定を求める
+// 評価する点は日付昇順とする
+
+// ---- struct
+
+// Point 評価する1点の予測
+type Point struct {
+	Origin    float64 // 予測の起点の実績(方向の判定とランダムウォークの予測値に用いる)
+	Actual    float64 // 予測対象の実績
+	Predicted float64 // 予測値
+}
+
+// Metrics 予測精度の指標
+type Metrics struct {
+	N       int     // 評価した点の数
+	MAE     float64 // 平均絶対誤差
+	RMSE    float64 // 二乗平均平方根誤差
+	MAPE    float64 // 平均絶対誤差率(%)。実績が 0 の点は除く
+	HitRate float64 // 起点からの方向の的中率。実績が起点から変化しなかった点は除く(該当する点がない場合は NaN)
+}
+
+// DieboldMariano 予測誤差の二乗の差の Diebold-Mariano 検定の結果
+type DieboldMariano struct {
+	Statistic float64 // 負の場合は比較対象より誤差が小さい
+	PValue    float64 // 両側の p 値
+}
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// Evaluate (public)予測精度の指標を求める
+func Evaluate(points []Point) Metrics {
+
+	m := Metrics{N: len(points), MAE: math.NaN(), RMSE: math.NaN(), MAPE: math.NaN(), HitRate: math.NaN()}
+	if len(points) == 0 {
+		return m
+	}
+	sumAbs, sumSquare, sumPercent, nPercent, hits, nDirection := 0.0, 0.0, 0.0, 0, 0, 0
+	for _, p := range points {
+		e := p.Actual - p.Predicted
+		sumAbs += math.Abs(e)
+		sumSquare += e * e
+		if p.Actual != 0 {
+			sumPercent += math.Abs(e / p.Actual)
+			nPercent++
+		}
+		if p.Actual != p.Origin {
+			nDirection++
+			if (p.Actual > p.Origin) == (p.Predicted > p.Origin) && p.Predicted != p.Origin {
+				hits++
+			}
+		}
+	}
+	n := float64(len(points))
+	m.MAE = sumAbs / n
+	m.RMSE = math.Sqrt(sumSquare / n)
+	if nPercent > 0 {
+		m.MAPE = 100 * sumPercent / float64(nPercent)
+	}
+	if nDirection > 0 {
+		m.HitRate = float64(hits) / float64(nDirection)
+	}
+	return m
+}
+
+// RandomWalk (public)起点の実績を予測値とする点(ランダムウォークの予測)を返す
+func RandomWalk(points []Point) []Point {
+	walk := make([]Point, len(points))
+	for i, p := range points {
+		walk[i] = Point{Origin: p.Origin, Actual: p.Actual, Predicted: p.Origin}
+	}
+	return walk
+}
+
+// DMTest (public)points と baseline (同じ実績に対する予測)の誤差の二乗の差の Diebold-Mariano 検定
+// horizon 期先の予測の誤差の自己相関を考慮して horizon-1 次までの自己共分散で分散を求め、
+// Harvey, Leybourne, Newbold (1997) の小標本の補正をして自由度 n-1 の t 分布で p 値を求める
+// 差の分散が 0 以下の場合は NaN を返す
+func DMTest(points []Point, baseline []Point, horizon int) DieboldMariano {
+
+	result := DieboldMariano{Statistic: math.NaN(), PValue: math.NaN()}
+	n := len(points)
+	if n < 2 || len(baseline) != n || horizon < 1 {
+		return result
+	}
+	d := make([]float64, n)
+	mean := 0.0
+	for t := range d {
+		e, b := points[t].Actual-points[t].Predicted, baseline[t].Actual-baseline[t].Predicted
+		d[t] = e*e - b*b
+		mean += d[t] / float64(n)
+	}
+	variance := 0.0
+	for k := 0; k < min(horizon, n); k++ {
+		gamma := 0.0
+		for t := k; t < n; t++ {
+			gamma += (d[t] - mean) * (d[t-k] - mean)
+		}
+		gamma /= float64(n)
+		if k == 0 {
+			variance += gamma
+		} else {
+			variance += 2 * gamma
+		}
+	}
+	if !(variance > 0) {
+		return result
+	}
+	h := float64(horizon)
+	correction := math.Sqrt((float64(n) + 1 - 2*h + h*(h-1)/float64(n)) / float64(n))
+	result.Statistic = correction * mean / math.Sqrt(variance/float64(n))
+	result.PValue = studentTwoSided(result.Statistic, float64(n-1))
+	return result
+}
+
+// RollingWindows (public)n 個の点を size 個ずつ step 個ずらした窓の [開始, 終了) の index を返す
+func RollingWindows(n int, size int, step int) [][2]int {
+	var windows [][2]int
+	if size < 1 || step < 1 {
+		return windows
+	}
+	for start := 0; start+size <= n; start += step {
+		windows = append(windows, [2]int{start, start + size})
+	}
+	return windows
+}
+
+//---- private function ----
+
+// 自由度 df の t 分布で |t| 以上となる両側の確率 I_x(df/2, 1/2) (x = df / (df + t²))
+func studentTwoSided(t float64, df float64) float64 {
+	if math.IsNaN(t) || df <= 0 {
+		return math.NaN()
+	}
+	return regularizedBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// 正規化した不完全ベータ関数 I_x(a, b) を連分数展開(Lentz 法)で求める
+func regularizedBeta(x float64, a float64, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	// 連分数の収束が速い側で計算する
+	if x > (a+1)/(a+b+2) {
+		return 1 - regularizedBeta(1-x, b, a)
+	}
+	lgab, _ := math.Lgamma(a + b)
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	prefix := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	f := d
+	for m := 1; m < 500; m++ {
+		fm := float64(m)
+		// 偶数項と奇数項
+		for _, numerator := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + numerator*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + numerator/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			f *= d * c
+		}
+		if math.Abs(d*c-1) < 1e-15 {
+			break
+		}
+	}
+	return prefix * f / a
+}
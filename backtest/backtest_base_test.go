@@ -0,0 +1,124 @@
+package backtest
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	points := []Point{
+		{Origin: 100, Actual: 102, Predicted: 101}, // 誤差 1、方向は的中
+		{Origin: 102, Actual: 100, Predicted: 104}, // 誤差 -4、方向は外れ
+		{Origin: 100, Actual: 100, Predicted: 99},  // 誤差 1、実績が変化しないため方向は判定しない
+		{Origin: 100, Actual: 98, Predicted: 100},  // 誤差 -2、予測が変化しないため外れ
+	}
+	m := Evaluate(points)
+	checks := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"MAE", m.MAE, 8.0 / 4},
+		{"RMSE", m.RMSE, math.Sqrt(22.0 / 4)},
+		{"MAPE", m.MAPE, 100 * (1.0/102 + 4.0/100 + 1.0/100 + 2.0/98) / 4},
+		{"HitRate", m.HitRate, 1.0 / 3},
+	}
+	for _, c := range checks {
+		if math.Abs(c.got-c.want) > 1e-12 {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if m.N != 4 {
+		t.Errorf("N = %d, want 4", m.N)
+	}
+
+	walk := Evaluate(RandomWalk(points))
+	if math.Abs(walk.MAE-(2+2+0+2)/4.0) > 1e-12 || walk.HitRate != 0 {
+		t.Errorf("random walk = %+v", walk)
+	}
+	if empty := Evaluate(nil); empty.N != 0 || math.IsNaN(empty.MAE) == false {
+		t.Errorf("empty = %+v", empty)
+	}
+}
+
+func TestStudentTwoSided(t *testing.T) {
+	// t 分布の両側 5% 点
+	tests := []struct {
+		t    float64
+		df   float64
+		want float64
+	}{
+		{1.959964, 1e7, 0.05},
+		{2.228139, 10, 0.05},
+		{12.7062, 1, 0.05},
+		{0, 5, 1},
+		{1, 1, 0.5},
+	}
+	for _, tt := range tests {
+		if got := studentTwoSided(tt.t, tt.df); math.Abs(got-tt.want) > 1e-5 {
+			t.Errorf("studentTwoSided(%v, %v) = %v, want %v", tt.t, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestDMTest(t *testing.T) {
+	random := rand.New(rand.NewPCG(1, 1))
+	var good, bad []Point
+	for i := 0; i < 300; i++ {
+		origin := 100 + random.NormFloat64()
+		actual := origin + random.NormFloat64()
+		good = append(good, Point{Origin: origin, Actual: actual, Predicted: actual + 0.3*random.NormFloat64()})
+		bad = append(bad, Point{Origin: origin, Actual: actual, Predicted: actual + 1.5*random.NormFloat64()})
+	}
+	// 誤差の小さい予測は負の統計量で有意
+	dm := DMTest(good, bad, 1)
+	if !(dm.Statistic < -5 && dm.PValue < 1e-6) {
+		t.Errorf("good vs bad = %+v", dm)
+	}
+	if reverse := DMTest(bad, good, 1); math.Abs(reverse.Statistic+dm.Statistic) > 1e-12 || math.Abs(reverse.PValue-dm.PValue) > 1e-12 {
+		t.Errorf("bad vs good = %+v, want the opposite of %+v", reverse, dm)
+	}
+
+	// 差の分散は horizon-1 次までの自己共分散を含め、小標本の補正をする
+	d := []float64{1, -1, 2, 0, 1}
+	var points, baseline []Point
+	for _, v := range d {
+		// 誤差の二乗の差が v となる点
+		points = append(points, Point{Actual: math.Sqrt(v + 4), Predicted: 0})
+		baseline = append(baseline, Point{Actual: 2, Predicted: 0})
+	}
+	mean := 0.6
+	gamma0, gamma1 := 0.0, 0.0
+	for i := range d {
+		gamma0 += (d[i] - mean) * (d[i] - mean) / 5
+		if i > 0 {
+			gamma1 += (d[i] - mean) * (d[i-1] - mean) / 5
+		}
+	}
+	want := math.Sqrt((5+1-4+2.0/5)/5) * mean / math.Sqrt((gamma0+2*gamma1)/5)
+	if got := DMTest(points, baseline, 2); math.Abs(got.Statistic-want) > 1e-12 {
+		t.Errorf("DM statistic (h = 2) = %v, want %v", got.Statistic, want)
+	}
+
+	// 同じ予測は比較できない
+	if same := DMTest(good, good, 1); math.IsNaN(same.Statistic) == false {
+		t.Errorf("same forecasts = %+v, want NaN", same)
+	}
+}
+
+func TestRollingWindows(t *testing.T) {
+	windows := RollingWindows(10, 4, 3)
+	want := [][2]int{{0, 4}, {3, 7}, {6, 10}}
+	if len(windows) != len(want) {
+		t.Fatalf("windows = %v, want %v", windows, want)
+	}
+	for i := range want {
+		if windows[i] != want[i] {
+			t.Errorf("windows = %v, want %v", windows, want)
+		}
+	}
+	if windows := RollingWindows(3, 4, 1); len(windows) != 0 {
+		t.Errorf("windows = %v, want none", windows)
+	}
+}
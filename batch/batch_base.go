@@ -0,0 +1,168 @@
+// batch 複数銘柄の一括処理パッケージ
+package batch // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"sv_stockcheck/convert"
+)
+
+// ---- struct
+
+// Result 1銘柄の処理結果
+type Result struct {
+	Code    string        // 銘柄コード
+	Err     error         // 処理エラー(成功時はnil)
+	Elapsed time.Duration // 処理時間
+}
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// Run (public)銘柄コードを workers 個のワーカーで並列に処理し、入力順に結果を返す
+// 1銘柄のエラーやpanicは他の銘柄の処理に影響しない
+func Run(codes []string, workers int, process func(code string) error) []Result {
+
+	if workers <= 0 {
+		workers = 1
+	}
+	results := make([]Result, len(codes))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(codes)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = runOne(codes[idx], process)
+			}
+		}()
+	}
+	for idx := range codes {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// ReadWatchlist (public)ウォッチリストファイルから銘柄コードを読み込む
+// 1行に1銘柄(カンマ区切りも可)。空行と # 以降はコメントとして無視する
+func ReadWatchlist(filename string) ([]string, error) {
+
+	file, errOpen := os.Open(filename)
+	if errOpen != nil {
+		return nil, errOpen
+	}
+	defer file.Close()
+
+	var codes []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		codes = append(codes, SplitCodes(line)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return uniqueCodes(codes), nil
+}
+
+// ScanResourceDir (public)resourceDir/<銘柄コード>/fileName が存在する銘柄コードを昇順で返す
+func ScanResourceDir(resourceDir string, fileName string) ([]string, error) {
+
+	matches, err := filepath.Glob(filepath.Join(resourceDir, "*", fileName))
+	if err != nil {
+		return nil, err
+	}
+	var codes []string
+	for _, m := range matches {
+		codes = append(codes, filepath.Base(filepath.Dir(m)))
+	}
+	slices.Sort(codes)
+	return codes, nil
+}
+
+// SplitCodes (public)カンマ、空白区切りの銘柄コード文字列を分割する(重複した銘柄コードは除く)
+func SplitCodes(str string) []string {
+	return uniqueCodes(convert.SplitList(str))
+}
+
+// Summary (public)処理結果の集計レポートを作成する
+func Summary(results []Result) string {
+
+	var sb strings.Builder
+	nFailed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			nFailed++
+		}
+	}
+	fmt.Fprintf(&sb, "batch summary: total=%d succeeded=%d failed=%d\n", len(results), len(results)-nFailed, nFailed)
+	for _, r := range results {
+		status := "OK"
+		if r.Err != nil {
+			status = "NG " + strings.ReplaceAll(r.Err.Error(), "\n", "; ")
+		}
+		fmt.Fprintf(&sb, "  %-6s %8s  %s\n", r.Code, r.Elapsed.Round(time.Millisecond), status)
+	}
+	return sb.String()
+}
+
+// Failed (public)失敗した銘柄の結果のみを返す
+func Failed(results []Result) []Result {
+	var failed []Result
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+//---- private function ----
+
+// 1銘柄を処理する。panicはエラーとして回収する
+func runOne(code string, process func(code string) error) (result Result) {
+
+	start := time.Now()
+	result.Code = code
+	defer func() {
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("panic: %v", r)
+		}
+		result.Elapsed = time.Since(start)
+		if result.Err != nil {
+			slog.Info("Batch NG", "code", code, "err", result.Err)
+		} else {
+			slog.Info("Batch OK", "code", code, "elapsed", result.Elapsed)
+		}
+	}()
+
+	result.Err = process(code)
+	return result
+}
+
+// 重複した銘柄コードを除く(出現順は維持)
+func uniqueCodes(codes []string) []string {
+	var ret []string
+	for _, c := range codes {
+		if !slices.Contains(ret, c) {
+			ret = append(ret, c)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,164 @@
+package batch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	codes := []string{"1001", "1002", "1003", "1004", "1005", "1006", "1007", "1008"}
+	tests := []struct {
+		name    string
+		workers int
+		limit   int32 // 同時に処理する銘柄数の上限
+	}{
+		{"sequential", 1, 1},
+		{"zero workers", 0, 1},
+		{"parallel", 3, 3},
+		{"more workers than codes", 20, int32(len(codes))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, peak atomic.Int32
+			results := Run(codes, tt.workers, func(code string) error {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				switch code {
+				case "1002":
+					return errors.New("fetch failed")
+				case "1005":
+					panic("index out of range")
+				}
+				return nil
+			})
+
+			if peak.Load() > tt.limit {
+				t.Errorf("peak concurrency = %d, want <= %d", peak.Load(), tt.limit)
+			}
+			if len(results) != len(codes) {
+				t.Fatalf("results = %d, want %d", len(results), len(codes))
+			}
+			for i, r := range results {
+				// 結果は入力順で、エラーと panic はその銘柄のみ失敗となる
+				if r.Code != codes[i] {
+					t.Errorf("results[%d].Code = %s, want %s", i, r.Code, codes[i])
+				}
+				switch r.Code {
+				case "1002":
+					if r.Err == nil || r.Err.Error() != "fetch failed" {
+						t.Errorf("%s err = %v", r.Code, r.Err)
+					}
+				case "1005":
+					if r.Err == nil || strings.Contains(r.Err.Error(), "panic: index out of range") == false {
+						t.Errorf("%s err = %v", r.Code, r.Err)
+					}
+				default:
+					if r.Err != nil {
+						t.Errorf("%s err = %v", r.Code, r.Err)
+					}
+				}
+			}
+
+			failed := Failed(results)
+			if len(failed) != 2 || failed[0].Code != "1002" || failed[1].Code != "1005" {
+				t.Errorf("Failed = %v", failed)
+			}
+			summary := Summary(results)
+			if strings.HasPrefix(summary, "batch summary: total=8 succeeded=6 failed=2\n") == false {
+				t.Errorf("Summary = %q", summary)
+			}
+		})
+	}
+
+	if results := Run(nil, 4, func(code string) error { return nil }); len(results) != 0 {
+		t.Errorf("Run(nil) = %v", results)
+	}
+}
+
+func TestReadWatchlist(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"one per line", "2586\n4005\n", []string{"2586", "4005"}},
+		{"comma and space", "2586, 4005 7078\n", []string{"2586", "4005", "7078"}},
+		{"comments and blank lines", "# watchlist\n\n2586 # 主力\n  \n4005\n", []string{"2586", "4005"}},
+		{"duplicates keep first order", "4005\n2586\n4005,2586\n", []string{"4005", "2586"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "watchlist.txt")
+			if err := os.WriteFile(filename, []byte(tt.body), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadWatchlist(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if slices.Equal(got, tt.want) == false {
+				t.Errorf("ReadWatchlist = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ReadWatchlist(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("ReadWatchlist of missing file expected error")
+	}
+}
+
+func TestScanResourceDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"7078/RawData.csv", "2586/RawData.csv", "4005/ModelData.csv", "CommonData.csv"} {
+		filename := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ScanResourceDir(dir, "RawData.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2586", "7078"}; slices.Equal(got, want) == false {
+		t.Errorf("ScanResourceDir = %q, want %q", got, want)
+	}
+	if got, _ := ScanResourceDir(filepath.Join(dir, "missing"), "RawData.csv"); len(got) != 0 {
+		t.Errorf("ScanResourceDir of missing dir = %q", got)
+	}
+}
+
+func TestSplitCodes(t *testing.T) {
+	tests := []struct {
+		str  string
+		want []string
+	}{
+		{"", nil},
+		{"2586", []string{"2586"}},
+		{"2586,4005", []string{"2586", "4005"}},
+		{" 2586 ,\t4005,,7078 ", []string{"2586", "4005", "7078"}},
+		{"2586,4005,2586", []string{"2586", "4005"}},
+	}
+	for _, tt := range tests {
+		if got := SplitCodes(tt.str); slices.Equal(got, tt.want) == false {
+			t.Errorf("SplitCodes(%q) = %q, want %q", tt.str, got, tt.want)
+		}
+	}
+}
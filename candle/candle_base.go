@@ -0,0 +1,203 @@
+// candle ローソク足パターン判定パッケージ
+package candle // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"math"
+)
+
+// 足の配列は日付降順(index 0 が最新、i+1 が前日)とする
+// 判定結果は 強気(上昇)のパターン 1、弱気(下落)のパターン -1、該当なし 0 とする
+// 前日以前の足がなく判定できない日付は 0 とする
+// パターンは足の形のみで判定し、直前のトレンド(下落後の hammer など)は考慮しない
+
+// ---- const
+
+const DojiBodyRatio = 0.1    // 実体が値幅のこの割合以下の足を十字線(doji)とする
+const ShadowBodyRatio = 2.0  // ひげが実体のこの倍数以上の足を hammer / shooting star とする
+const ShortShadowRatio = 0.1 // 反対側のひげが値幅のこの割合以下であること(hammer / shooting star)
+const LongBodyRatio = 0.5    // 実体が値幅のこの割合以上の足を大陽線・大陰線とする
+const StarBodyRatio = 0.3    // 星の実体は1本目の実体のこの割合以下とする(morning / evening star)
+
+// ---- struct
+
+// Bar 1本の足の四本値
+type Bar struct {
+	Opening float64 // 始値
+	High    float64 // 高値
+	Low     float64 // 安値
+	Closing float64 // 終値
+}
+
+// Pattern ローソク足パターン
+type Pattern struct {
+	Name   string                          // ModelDataのカラム名
+	Bars   int                             // 判定に用いる足の本数(当日を含む)
+	Detect func(bars []Bar, i int) float64 // bars[i] を最新の足として判定する
+}
+
+// ---- Global Variable
+
+// Patterns 判定するパターン(ModelDataの出力順)
+var Patterns = []Pattern{
+	{Name: "CandleDoji", Bars: 1, Detect: doji},
+	{Name: "CandleHammer", Bars: 1, Detect: hammer},
+	{Name: "CandleEngulfing", Bars: 2, Detect: engulfing},
+	{Name: "CandleHarami", Bars: 2, Detect: harami},
+	{Name: "CandleStar", Bars: 3, Detect: star},
+	{Name: "CandleThreeSoldiers", Bars: 3, Detect: threeSoldiers},
+	{Name: "CandleGap", Bars: 2, Detect: gap},
+}
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// Detect (public)全てのパターンを判定し、パターン名毎の判定結果(日付降順)を返す
+func Detect(bars []Bar) map[string][]float64 {
+	result := make(map[string][]float64, len(Patterns))
+	for _, p := range Patterns {
+		values := make([]float64, len(bars))
+		for i := range bars {
+			if i+p.Bars > len(bars) {
+				continue
+			}
+			values[i] = p.Detect(bars, i)
+		}
+		result[p.Name] = values
+	}
+	return result
+}
+
+//---- private function ----
+
+// 実体の大きさ
+func body(b Bar) float64 {
+	return math.Abs(b.Closing - b.Opening)
+}
+
+// 値幅(高値 - 安値)
+func barRange(b Bar) float64 {
+	return b.High - b.Low
+}
+
+// 上ひげ、下ひげ
+func upperShadow(b Bar) float64 {
+	return b.High - max(b.Opening, b.Closing)
+}
+func lowerShadow(b Bar) float64 {
+	return min(b.Opening, b.Closing) - b.Low
+}
+
+// 陽線、陰線
+func isBullish(b Bar) bool {
+	return b.Closing > b.Opening
+}
+func isBearish(b Bar) bool {
+	return b.Closing < b.Opening
+}
+
+// 大陽線・大陰線(実体が値幅の LongBodyRatio 以上)
+func isLongBody(b Bar) bool {
+	return barRange(b) > 0 && body(b) >= barRange(b)*LongBodyRatio
+}
+
+// 十字線: 実体が値幅の DojiBodyRatio 以下(値幅がない足も含む)
+// 方向を持たないため 1 とする
+func doji(bars []Bar, i int) float64 {
+	if body(bars[i]) <= barRange(bars[i])*DojiBodyRatio {
+		return 1
+	}
+	return 0
+}
+
+// hammer(1): 下ひげが実体の ShadowBodyRatio 倍以上で上ひげが短い
+// shooting star(-1): 上ひげが実体の ShadowBodyRatio 倍以上で下ひげが短い
+// 十字線(実体がほぼない足)は対象外とする
+func hammer(bars []Bar, i int) float64 {
+	b := bars[i]
+	if doji(bars, i) != 0 {
+		return 0
+	}
+	if lowerShadow(b) >= body(b)*ShadowBodyRatio && upperShadow(b) <= barRange(b)*ShortShadowRatio {
+		return 1
+	}
+	if upperShadow(b) >= body(b)*ShadowBodyRatio && lowerShadow(b) <= barRange(b)*ShortShadowRatio {
+		return -1
+	}
+	return 0
+}
+
+// 包み足: 当日の実体が前日の反対向きの実体を包む。陽線で包む場合 1、陰線で包む場合 -1
+func engulfing(bars []Bar, i int) float64 {
+	cur, prev := bars[i], bars[i+1]
+	if isBullish(cur) && isBearish(prev) && cur.Opening <= prev.Closing && cur.Closing >= prev.Opening && body(cur) > body(prev) {
+		return 1
+	}
+	if isBearish(cur) && isBullish(prev) && cur.Opening >= prev.Closing && cur.Closing <= prev.Opening && body(cur) > body(prev) {
+		return -1
+	}
+	return 0
+}
+
+// はらみ足: 前日の大陰線(大陽線)の実体の内側に当日の陽線(陰線)の実体が収まる。陽線の場合 1、陰線の場合 -1
+func harami(bars []Bar, i int) float64 {
+	cur, prev := bars[i], bars[i+1]
+	if !isLongBody(prev) {
+		return 0
+	}
+	if isBullish(cur) && isBearish(prev) && cur.Opening > prev.Closing && cur.Closing < prev.Opening {
+		return 1
+	}
+	if isBearish(cur) && isBullish(prev) && cur.Opening < prev.Closing && cur.Closing > prev.Opening {
+		return -1
+	}
+	return 0
+}
+
+// 明けの明星(1): 大陰線、実体が下に離れた小さな足(星)、1本目の実体の中心より上で引ける陽線
+// 宵の明星(-1): 大陽線、実体が上に離れた小さな足(星)、1本目の実体の中心より下で引ける陰線
+func star(bars []Bar, i int) float64 {
+	third, second, first := bars[i], bars[i+1], bars[i+2]
+	if !isLongBody(first) || body(second) > body(first)*StarBodyRatio {
+		return 0
+	}
+	middle := (first.Opening + first.Closing) / 2
+	if isBearish(first) && max(second.Opening, second.Closing) < first.Closing && isBullish(third) && third.Closing > middle {
+		return 1
+	}
+	if isBullish(first) && min(second.Opening, second.Closing) > first.Closing && isBearish(third) && third.Closing < middle {
+		return -1
+	}
+	return 0
+}
+
+// 赤三兵(1): 3本連続の陽線で、終値が切り上がり、始値が前日の実体の内側
+// 黒三兵(-1): 3本連続の陰線で、終値が切り下がり、始値が前日の実体の内側
+func threeSoldiers(bars []Bar, i int) float64 {
+	third, second, first := bars[i], bars[i+1], bars[i+2]
+	if isBullish(first) && isBullish(second) && isBullish(third) &&
+		second.Closing > first.Closing && third.Closing > second.Closing &&
+		second.Opening >= first.Opening && second.Opening <= first.Closing &&
+		third.Opening >= second.Opening && third.Opening <= second.Closing {
+		return 1
+	}
+	if isBearish(first) && isBearish(second) && isBearish(third) &&
+		second.Closing < first.Closing && third.Closing < second.Closing &&
+		second.Opening <= first.Opening && second.Opening >= first.Closing &&
+		third.Opening <= second.Opening && third.Opening >= second.Closing {
+		return -1
+	}
+	return 0
+}
+
+// 窓: 当日の安値が前日の高値より上(窓を開けて上昇)の場合 1、当日の高値が前日の安値より下の場合 -1
+func gap(bars []Bar, i int) float64 {
+	cur, prev := bars[i], bars[i+1]
+	if cur.Low > prev.High {
+		return 1
+	}
+	if cur.High < prev.Low {
+		return -1
+	}
+	return 0
+}
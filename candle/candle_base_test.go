@@ -0,0 +1,70 @@
+package candle
+
+import (
+	"testing"
+)
+
+// 始値、高値、安値、終値から足を作る
+func bar(opening, high, low, closing float64) Bar {
+	return Bar{Opening: opening, High: high, Low: low, Closing: closing}
+}
+
+func TestPatterns(t *testing.T) {
+	// bars は日付降順(先頭が判定対象の最新の足)
+	tests := []struct {
+		name    string
+		pattern string
+		bars    []Bar
+		want    float64
+	}{
+		{"doji", "CandleDoji", []Bar{bar(100, 105, 95, 100.5)}, 1},
+		{"doji flat", "CandleDoji", []Bar{bar(100, 100, 100, 100)}, 1},
+		{"not doji", "CandleDoji", []Bar{bar(100, 105, 95, 104)}, 0},
+		{"hammer", "CandleHammer", []Bar{bar(100, 101.5, 94, 101)}, 1},
+		{"shooting star", "CandleHammer", []Bar{bar(101, 107, 100.5, 100)}, -1},
+		{"hammer ignores doji", "CandleHammer", []Bar{bar(100, 100.1, 94, 100)}, 0},
+		{"bullish engulfing", "CandleEngulfing", []Bar{bar(97, 104, 96, 103), bar(102, 103, 97, 98)}, 1},
+		{"bearish engulfing", "CandleEngulfing", []Bar{bar(103, 104, 96, 97), bar(98, 103, 97, 102)}, -1},
+		{"engulfing needs opposite colour", "CandleEngulfing", []Bar{bar(97, 104, 96, 103), bar(98, 103, 97, 102)}, 0},
+		{"bullish harami", "CandleHarami", []Bar{bar(97, 99, 96, 98), bar(104, 105, 94, 95)}, 1},
+		{"bearish harami", "CandleHarami", []Bar{bar(102, 103, 100, 101), bar(95, 105, 94, 104)}, -1},
+		{"harami needs long body", "CandleHarami", []Bar{bar(97, 99, 96, 98), bar(100, 110, 90, 96)}, 0},
+		{"morning star", "CandleStar", []Bar{bar(92, 99, 91, 98), bar(90, 91, 88, 89.5), bar(100, 101, 92, 93)}, 1},
+		{"evening star", "CandleStar", []Bar{bar(108, 109, 101, 102), bar(110, 112, 109, 110.5), bar(100, 108, 99, 107)}, -1},
+		{"star without gap", "CandleStar", []Bar{bar(92, 99, 91, 98), bar(93, 95, 92, 93.5), bar(100, 101, 92, 93)}, 0},
+		{"three white soldiers", "CandleThreeSoldiers", []Bar{bar(103, 107, 102, 106), bar(101, 104, 100, 104), bar(99, 102, 98, 102)}, 1},
+		{"three black crows", "CandleThreeSoldiers", []Bar{bar(97, 98, 93, 94), bar(99, 100, 96, 96), bar(101, 102, 98, 98)}, -1},
+		{"soldiers open outside body", "CandleThreeSoldiers", []Bar{bar(105, 107, 102, 106), bar(101, 104, 100, 104), bar(99, 102, 98, 102)}, 0},
+		{"gap up", "CandleGap", []Bar{bar(106, 108, 105, 107), bar(100, 104, 99, 103)}, 1},
+		{"gap down", "CandleGap", []Bar{bar(96, 97, 94, 95), bar(100, 104, 99, 103)}, -1},
+		{"no gap", "CandleGap", []Bar{bar(104, 108, 103, 107), bar(100, 104, 99, 103)}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Detect(tt.bars)[tt.pattern]
+			if got[0] != tt.want {
+				t.Errorf("%s = %v, want %v", tt.pattern, got[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectShortHistory(t *testing.T) {
+	// 判定に必要な本数がない日付は 0 とし、全てのパターンで結果の長さは足の本数と同じ
+	bars := []Bar{bar(106, 108, 105, 107), bar(100, 104, 99, 103)}
+	result := Detect(bars)
+	if len(result) != len(Patterns) {
+		t.Fatalf("len(result) = %d, want %d", len(result), len(Patterns))
+	}
+	for _, p := range Patterns {
+		if len(result[p.Name]) != len(bars) {
+			t.Errorf("len(%s) = %d, want %d", p.Name, len(result[p.Name]), len(bars))
+		}
+	}
+	if got := result["CandleGap"][1]; got != 0 {
+		t.Errorf("CandleGap on oldest bar = %v, want 0", got)
+	}
+	if got := result["CandleStar"][0]; got != 0 {
+		t.Errorf("CandleStar with 2 bars = %v, want 0", got)
+	}
+}
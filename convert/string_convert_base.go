@@ -0,0 +1,19 @@
+// convert コンバートパッケージ
+package convert // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"strings"
+)
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// SplitList (public)カンマ、空白区切りの文字列を分割する。空の要素は除く
+func SplitList(str string) []string {
+	return strings.FieldsFunc(str, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+}
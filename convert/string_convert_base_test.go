@@ -0,0 +1,23 @@
+package convert
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		str  string
+		want []string
+	}{
+		{"", nil},
+		{"1,5,20", []string{"1", "5", "20"}},
+		{" arima, naive\tdrift ", []string{"arima", "naive", "drift"}},
+		{",,a,,b,", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		if got := SplitList(tt.str); slices.Equal(got, tt.want) == false {
+			t.Errorf("SplitList(%q) = %q, want %q", tt.str, got, tt.want)
+		}
+	}
+}
@@ -2,31 +2,50 @@ package main
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"html"
 	"log/slog"
+	"maps"
 	"math"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/gocolly/colly/v2"
-
+	"sv_stockcheck/arima"
+	"sv_stockcheck/backtest"
+	"sv_stockcheck/batch"
+	"sv_stockcheck/candle"
 	"sv_stockcheck/convert"
+	"sv_stockcheck/datasource"
 	"sv_stockcheck/fileio"
+	"sv_stockcheck/forecast"
+	"sv_stockcheck/indicator"
+	"sv_stockcheck/resample"
 )
 
 // ---- const
-const StockCode = "2586"
-const ResourceDir = "Resource/"
-const S3BucketName = "for-stock-fx-analysis"
+const DefaultStockCode = "2586"
+const DefaultResourceDir = "Resource/"
+const DefaultS3BucketName = "for-stock-fx-analysis"
 const RawDataFileName = "RawData.csv"
 const ModelDataFileName = "ModelData.csv"
-
-var nowObtain = Stock
+const CommonDataFileName = "CommonData.csv"
+const IndexStateFileName = "IndexState.json"
+const IndexCacheFileName = "IndexCache.csv"
+const IndexStateVersion = 2 // IndexState.json の形式の版(変更した場合は保存した状態から計算し直す)
+const BollingerBandK = 2    // ボリンジャーバンドの幅(標準偏差の倍数)
+const ArimaOrderFileName = "ArimaOrder.json"
+const EvaluationFileName = "Evaluation.csv"
+const EvaluationReportFileName = "Evaluation" // 拡張子は -report の形式(.md / .html)
 
 type ObtainType int
 
@@ -35,14 +54,41 @@ const (
 	Forex                   // 為替の取得
 )
 
-type TermEnum int
+// テクニカル指標の逐次計算のモード
+type IncrementalMode string
+
+const (
+	IncrementalOff    IncrementalMode = "off"    // 毎回全ての足を計算する
+	IncrementalOn     IncrementalMode = "on"     // 前回の状態から追加された足のみ計算する
+	IncrementalVerify IncrementalMode = "verify" // 追加された足のみ計算し、全ての足の再計算と比較する
+)
+
+// ARIMA予測の計算方法
+type ArimaEngine string
+
+const (
+	ArimaGo      ArimaEngine = "go"      // arima パッケージで計算する
+	ArimaPython  ArimaEngine = "python"  // arima_insights.py (pandas、statsmodels が必要)を実行する
+	ArimaCompare ArimaEngine = "compare" // 両方で計算して差をログに出力し、go の結果を用いる
+)
+
+// ModelData に予測のカラムを出力する予測モデル
+type ForecastModel string
 
 const (
-	Term5 TermEnum = iota
-	Term14
-	Term30
+	ForecastARIMA       ForecastModel = "arima"       // ARIMA (-arima の方法で計算する)
+	ForecastHoltWinters ForecastModel = "holtwinters" // 加法型の Holt-Winters 指数平滑法
+	ForecastGARCH       ForecastModel = "garch"       // 対数収益率の GARCH(1,1) (ボラティリティ)
+	ForecastNaive       ForecastModel = "naive"       // 前日の終値(ランダムウォーク)
+	ForecastDrift       ForecastModel = "drift"       // ドリフト付きランダムウォーク
+)
 
-	TermNum
+// evaluate のレポートの形式
+type ReportFormat string
+
+const (
+	ReportMarkdown ReportFormat = "md"   // Markdown
+	ReportHTML     ReportFormat = "html" // HTML
 )
 
 // ---- struct
@@ -71,36 +117,13 @@ type CommonInformation struct {
 
 // 銘柄情報構造体
 type StockBrandInformation struct {
-	ParseDate         time.Time        `json:"parsedate"`         //	日付
-	Opening           float64          `json:"opening"`           //	始値
-	High              float64          `json:"high"`              //	高値
-	Low               float64          `json:"low"`               //	安値
-	Closing           float64          `json:"closing"`           //	終値
-	Volume            float64          `json:"volume"`            //	出来高
-	MovingAve         [TermNum]float64 `json:"movingave"`         // 移動平均配列
-	EMA               [TermNum]float64 `json:"ema"`               // 指数移動平均
-	Volatility        [TermNum]float64 `json:"volatility"`        // ボラティリティ(標準偏差)
-	HighLowVolatility [TermNum]float64 `json:"highlowvolatility"` // ボラティリティ(高値と安値)
-	ATR               [TermNum]float64 `json:"atr"`               // ATR
-	UpperBBand        [TermNum]float64 `json:"upperbband"`        // ボリンジャーバンド(上)
-	UnderBBand        [TermNum]float64 `json:"underbband"`        // ボリンジャーバンド(下)
-	MADRate           [TermNum]float64 `json:"madrate"`           // 移動平均線乖離率
-	RSI               [TermNum]float64 `json:"rsi"`               // Relative Strength Index
-	ShortMacdVal      float64          `json:"shortmacdval"`      // MACD値(短期間)
-	ShortMacdSmaSig   float64          `json:"shortmacdsmasig"`   // SMA-MACDシグナルライン(短期間)
-	ShortMacdSmaHisto float64          `json:"shortmacdsmahisto"` // SMA-MACDヒストグラム(短期間)
-	ShortMacdEmaSig   float64          `json:"shortmacdemasig"`   // EMA-MACDシグナルライン(短期間)
-	ShortMacdEmaHisto float64          `json:"shortmacdemahisto"` // EMA-MACDヒストグラム(短期間)
-	LongMacdVal       float64          `json:"longmacdval"`       // MACD値(長期間)
-	LongMacdSmaSig    float64          `json:"longmacdsmasig"`    // SMA-MACDシグナルライン(長期間)
-	LongMacdSmaHisto  float64          `json:"longmacdsmahisto"`  // SMA-MACDヒストグラム(長期間)
-	LongMacdEmaSig    float64          `json:"longmacdemasig"`    // EMA-MACDシグナルライン(長期間)
-	LongMacdEmaHisto  float64          `json:"longmacdemahisto"`  // EMA-MACDヒストグラム(長期間)
-	VolumeChangeRate  float64          `json:"volumechangerate"`  // 出来高変化率
-	VolumeMovingAve   [TermNum]float64 `json:"volumemovingave"`   // 出来高移動平均配列
-	VolumeMADRate     [TermNum]float64 `json:"volumemadrate"`     // 出来高移動平均線乖離率
-	VolumeRatio       [TermNum]float64 `json:"volumeratio"`       // 出来高移動平均比率
-	VolumeEMA         [TermNum]float64 `json:"volumeema"`         // 出来高指数移動平均
+	ParseDate time.Time          `json:"parsedate"` //	日付
+	Opening   float64            `json:"opening"`   //	始値
+	High      float64            `json:"high"`      //	高値
+	Low       float64            `json:"low"`       //	安値
+	Closing   float64            `json:"closing"`   //	終値
+	Volume    float64            `json:"volume"`    //	出来高
+	Index     map[string]float64 `json:"index"`     // テクニカル指標(キーはModelDataのカラム名。期間毎の指標は "MovingAve5" のように名前+期間)
 }
 
 // ARIMA予測結果構造体
@@ -112,21 +135,192 @@ type ArimaPredictionResultInformation struct {
 	Low                     float64   `json:"low"`     //	安値
 	Closing                 float64   `json:"closing"` //	終値
 	Volume                  float64   `json:"volume"`  //	出来高
-	Arima_Diff_Prediction   float64   `json:"arima_diff_prediction`
+	Arima_Diff_Prediction   float64   `json:"arima_diff_prediction"`
 	Arima_Actual_Prediction float64   `json:"arima_actual_prediction"`
 	Prediction_Difference   float64   `json:"prediction_difference"`
 }
 
+// 自動選択した ARIMA の次数(銘柄毎に保存し、次回以降は再利用する)
+// 予測の起点毎に起点までの系列で選択するため、選択した系列の期間毎に保存する
+type ArimaOrderSidecar struct {
+	Settings string             `json:"settings"` // 探索範囲と情報量規準(変わった場合は選択し直す)
+	Windows  []ArimaOrderWindow `json:"windows"`  // 選択した系列の期間毎の次数(最新日付の昇順)
+}
+
+// 1つの期間の系列で自動選択した ARIMA の次数
+type ArimaOrderWindow struct {
+	SelectedAt time.Time       `json:"selectedat"` // 選択した日時
+	DataFrom   string          `json:"datafrom"`   // 選択に用いた系列の最古日付
+	DataTo     string          `json:"datato"`     // 選択に用いた系列の最新日付
+	Selection  arima.Selection `json:"selection"`  // 選択した次数、定常性の検定、候補、診断
+}
+
+// 予測モデル毎の予測結果(ModelData に出力するカラム名と、日付毎のカラムの値)
+type ForecastColumns struct {
+	Names  []string
+	Values map[string][]float64 // key は yyyy/mm/dd の日付
+}
+
+// 前の足を起点とした複数期先の予測
+type HorizonPrediction struct {
+	Origin      time.Time           // 起点(前の足)の日付
+	OriginClose float64             // 起点の終値
+	Target      time.Time           // 予測対象の日付(起点から N 営業日先)
+	Prediction  forecast.Prediction // 予測値と予測誤差の標準偏差
+}
+
+// 予測精度の評価結果(予測モデル、予測の期間、窓毎)
+type EvaluationResult struct {
+	Code       string
+	Model      string                  // 予測モデルの名前
+	Horizon    int                     // 予測の期間(営業日)
+	Window     string                  // all は全期間、それ以外はローリング窓の番号(1始まり)
+	From       time.Time               // 窓の最初の予測対象の日付
+	To         time.Time               // 窓の最後の予測対象の日付
+	Metrics    backtest.Metrics        // 予測モデルの予測精度
+	RandomWalk backtest.Metrics        // ランダムウォーク(起点の終値)の予測精度
+	DM         backtest.DieboldMariano // ランダムウォークとの Diebold-Mariano 検定
+}
+
+// レポートの表
+type reportTable struct {
+	title  string
+	header []string
+	rows   [][]string
+}
+
+// バックフィルの進捗(中断後の再開用)
+type BackfillCheckpoint struct {
+	Code      string    `json:"code"`      // 銘柄コード
+	Bar       string    `json:"bar"`       // 足種別(day / wek / mon)
+	Start     time.Time `json:"start"`     // 取得を要求した開始日
+	NextPage  int       `json:"nextpage"`  // 次に取得するページ番号
+	Oldest    time.Time `json:"oldest"`    // 取得済みの最古日付
+	Completed bool      `json:"completed"` // 開始日まで取得済み
+	UpdatedAt time.Time `json:"updatedat"` // 更新日時
+}
+
+// テクニカル指標の逐次計算の状態(銘柄毎に IndexState.json に保存する)
+// 逐次計算器は指標のカラム名(MACDは定義名、DMIは termKey("DMI", 期間)、ストキャスティクスは termKey("StochFastK", 期間))毎に保持する
+// 計算済みの足の指標の値は IndexCache.csv に保存する
+type IndexState struct {
+	Settings    string                                  `json:"settings"`    // 計算の設定(変更された場合は全て計算し直す)
+	Bars        int                                     `json:"bars"`        // 計算済みの足の本数
+	LastBar     StockBrandInformation                   `json:"lastbar"`     // 計算済みの最新の足(四本値が変わった場合は全て計算し直す)
+	SMA         map[string]*indicator.SmoothStream      `json:"sma"`         // 移動平均、ストキャスティクスの %D
+	StdDev      map[string]*indicator.StdDevStream      `json:"stddev"`      // ボラティリティ(標準偏差)
+	Stochastics map[string]*indicator.StochasticsStream `json:"stochastics"` // ストキャスティクスの %K、ウィリアムズ%R
+	CCI         map[string]*indicator.CCIStream         `json:"cci"`
+	ATR         map[string]*indicator.ATRStream         `json:"atr"`
+	RSI         map[string]*indicator.RSIStream         `json:"rsi"`
+	EMA         map[string]*indicator.SmoothStream      `json:"ema"`
+	DMI         map[string]*indicator.DMIStream         `json:"dmi"`
+	MACD        map[string]*indicator.MACDStream        `json:"macd"`
+	VWAP        map[string]*indicator.VWAPStream        `json:"vwap"`
+	MFI         map[string]*indicator.MFIStream         `json:"mfi"`
+	CMF         map[string]*indicator.CMFStream         `json:"cmf"`
+	PSAR        *indicator.ParabolicSARStream           `json:"psar"`
+	OBV         *indicator.OBVStream                    `json:"obv"`
+	ADLine      *indicator.ADLineStream                 `json:"adline"`
+	Ichimoku    *indicator.IchimokuStream               `json:"ichimoku"`
+	Candles     []candle.Bar                            `json:"candles"` // ローソク足パターンの判定に用いる直近の足(日付昇順)
+}
+
 // ---- Global Variable
 
 // ---- Package Global Variable
 
-var termDay = []int{
-	5,  // Term5
-	14, // Term14
-	30, // Term30
+// コマンドラインフラグで上書きされる実行設定
+var resourceDir = DefaultResourceDir
+var s3BucketName = DefaultS3BucketName
+var nowObtain = Stock
+var dateFrom, dateTo time.Time // ModelData出力対象期間(ゼロ値は制限なし)
+var dataSource datasource.DataSource = datasource.NewKabutan()
+
+// バックフィルの開始日、対象の足種別、チェックポイントを無視して最初から取得するか
+var backfillStart time.Time
+var backfillBars = []datasource.BarType{datasource.BarDaily, datasource.BarWeekly, datasource.BarMonthly}
+var isBackfillRestart = false
+
+// テクニカル指標の逐次計算のモード。-incremental で変更する
+var incrementalMode = IncrementalOff
+
+// ARIMA予測の計算方法と次数(終値の前日差分の系列に当てはめる)。-arima、-arima-order で変更する
+// Go の計算は statsmodels の出力と照合するまで既定にしない(testdata/arima の照合用の出力で確認する)
+var arimaEngine = ArimaPython
+var arimaOrder = arima.Order{P: 1, D: 0, Q: 1}
+
+// -arima-order auto の場合は銘柄毎に次数を自動選択する。探索範囲は -arima-max-order、-arima-seasonal、-arima-criterion で変更する
+// 保存した次数を使わずに選択し直すか(-arima-refresh)
+// 予測の起点毎に起点までの系列で次数を選択し、arimaReselect 本毎に選び直す(-arima-reselect)
+var isArimaAuto = false
+var arimaSearch = arima.DefaultSearchSpace
+var isArimaRefresh = false
+var arimaReselect = 20
+
+// ARIMA予測を取得する関数(テストでは python を実行せずに固定の予測を返す関数に差し替える)
+var arimaPredictor = arimaPrediction
+
+// ModelData に予測のカラムを出力する予測モデル(出力順)と Holt-Winters の季節の周期(系列の本数)。-forecast、-holtwinters-period で変更する
+var forecastModels = []ForecastModel{ForecastARIMA}
+var holtWintersPeriod = 7
+
+// 複数期先の予測の期間(営業日。空は出力しない)、予測区間の信頼水準、予測モデル。-horizons、-interval、-horizon-model で変更する
+var forecastHorizons []int
+var intervalLevel = 0.95
+var horizonModel = ForecastARIMA
+
+// evaluate のローリング窓の評価点の数とずらす数、レポートの形式。-window、-window-step、-report で変更する
+var evaluationWindow = 60
+var evaluationStep = 20
+var reportFormat = ReportMarkdown
+
+// build-model で日足に加えて出力する足の期間
+var resamplePeriods = []resample.Period{resample.Weekly, resample.Monthly}
+
+// テクニカル指標を計算する期間(昇順)。-terms で変更する
+var termDay = []int{5, 14, 30}
+
+// MACDの定義。-macd で変更する
+// short、long は従来の終値の単純移動平均(5日と30日、14日と30日)のMACD、ema12_26_9 は一般的な指数移動平均のMACD
+var macdDefinitions = []indicator.MACDDefinition{
+	{Name: "short", Base: indicator.SmoothingSMA, Fast: 5, Slow: 30, Signal: 9},
+	{Name: "long", Base: indicator.SmoothingSMA, Fast: 14, Slow: 30, Signal: 9},
+	{Name: "ema12_26_9", Base: indicator.SmoothingEMA, Fast: 12, Slow: 26, Signal: 9},
 }
-var windowMacdSignal = 9
+
+// ATR、RSIの平滑化方式。-smoothing で変更する
+var smoothings = []indicator.Smoothing{indicator.SmoothingSMA}
+
+// パラボリックSARの加速因子。-psar で変更する
+var sarAcceleration = indicator.DefaultSARAcceleration
+
+var windowStochSmoothing = 3 // ストキャスティクスの%D(%Kの移動平均)の期間
+
+// 一目均衡表の期間(転換線、基準線、先行スパンB)。先行スパン、遅行スパンのずらす期間は基準線の期間とする。-ichimoku で変更する
+var ichimokuPeriods = indicator.DefaultIchimokuPeriods
+
+// 期間毎に計算するテクニカル指標の名前(ModelDataのカラム名は 名前+期間。出力順に並べる)
+// ATR、RSIは平滑化方式毎に出力する(smoothingIndexName)
+var termIndexNames = []string{"MovingAve", "EMA", "Volatility", "HighLowVolatility", "ATR", "MADRate", "RSI"}
+var bbandIndexNames = []string{"upperBBand", "underBBand"}
+var oscillatorIndexNames = []string{"StochFastK", "StochFastD", "StochSlowD", "WilliamsR", "CCI"}
+var trendIndexNames = []string{"PlusDI", "MinusDI", "ADX"}
+
+// パラボリックSARのテクニカル指標の名前(SAR、トレンド 上昇 1 / 下降 -1)
+var sarIndexNames = []string{"PSAR", "PSARTrend"}
+
+// 一目均衡表のテクニカル指標の名前(出力順に並べる)
+var ichimokuIndexNames = []string{"IchimokuTenkan", "IchimokuKijun", "IchimokuSenkouA", "IchimokuSenkouB", "IchimokuSenkouALead", "IchimokuSenkouBLead",
+	"IchimokuChikouDiff", "IchimokuCloudPosition", "IchimokuCloudThickness"}
+var volumeTermIndexNames = []string{"VMovingAve", "VolumeRatio", "VolumeEMA", "VolumeMADRate"}
+
+// 出来高を用いるテクニカル指標の名前(株式のみ出力する。OBV、A/Dラインは期間なし、VWAP、MFI、CMFは期間毎)
+var volumeFlowIndexNames = []string{"OBV", "ADLine"}
+var volumeFlowTermIndexNames = []string{"VWAP", "MFI", "CMF"}
+
+// MACDのテクニカル指標の名前(ModelDataのカラム名は MACDDefinition.Name + 名前。出力順に並べる)
+var macdIndexNames = []string{"MACD", "MACDSignalSMA", "MACDHistoSMA", "MACDSignalEMA", "MACDHistoEMA"}
 
 // ---- public function ----
 
@@ -138,7 +332,7 @@ func readCommonCsv() []CommonInformation {
 
 	var retData []CommonInformation
 
-	fileContents, err := fileio.FileIoCsvRead("Resource/CommonData.csv")
+	fileContents, err := fileio.FileIoCsvRead(filepath.Join(resourceDir, CommonDataFileName))
 	if err != nil {
 		slog.Info("FileReadError", "err", err)
 	} else {
@@ -268,236 +462,6 @@ func linearRegression(x []float64, y []float64, predictX []float64) []float64 {
 	return predictions
 }
 
-// 移動平均を計算する
-func calcMovingAverage(data []float64) float64 {
-	dataLength := len(data)
-	if dataLength == 0 {
-		return 0 // またはエラー処理
-	}
-	sumPastData := 0.0
-	for _, c := range data {
-		sumPastData += c
-	}
-	return sumPastData / float64(dataLength)
-}
-
-// calculateEMA calculates the Exponential Moving Average (EMA : 指数移動平均) for a given data series
-func calculateEMA(data []float64, window int) []float64 {
-	if len(data) < window {
-		return nil // Not enough data to calculate EMA
-	}
-
-	alpha := 2.0 / float64(window+1) // Smoothing factor
-	ema := make([]float64, len(data))
-
-	// 有効なデータがある一番古い箇所を検索
-	var startIdx int
-	for startIdx = len(data) - 1; startIdx >= 0; startIdx-- {
-		if !math.IsNaN(data[startIdx]) {
-			break
-		}
-	}
-	if startIdx < window {
-		return nil // Not enough data to calculate EMA
-	}
-
-	// Initialize the first EMA value as the average of the first 'window' data points
-	sum := 0.0
-	for i := startIdx - 1; i > startIdx-1-window; i-- {
-		sum += data[i]
-
-	}
-	ema[startIdx-window] = sum / float64(window)
-
-	// Calculate EMA for the rest of the data
-	for i := startIdx - 1 - window; i >= 0; i-- {
-		ema[i] = (data[i] * alpha) + (ema[i+1] * (1 - alpha))
-	}
-
-	// Set EMA values before the initial window to NaN (not enough data)
-	for i := startIdx - window + 1; i < len(data); i++ {
-		ema[i] = math.NaN()
-	}
-
-	return ema
-}
-
-// 標準偏差を計算する関数
-func calcStandardDeviation(data []float64, mean float64) float64 {
-	if len(data) == 0 {
-		return 0 // またはエラー処理
-	}
-	sumOfSquares := 0.0
-	for _, value := range data {
-		deviation := value - mean
-		sumOfSquares += deviation * deviation
-	}
-	return math.Sqrt(sumOfSquares / float64(len(data))) // 母集団標準偏差
-}
-
-// 移動平均線の乖離率(MovingAverageDeviationRate)を計算する関数
-func calcMADRate(closing float64, movingAve float64) float64 {
-	if movingAve == 0 {
-		return 0 // またはエラー処理
-	}
-	rate := ((closing - movingAve) / movingAve) * 100
-	return rate
-}
-
-// RSI(Relative Strength Index)を計算する関数
-// 引数pricesの日にちそれぞれに対して、期間periodのRSIを計算して、結果を配列で返す
-func calcRSI(prices []float64, period int) ([]float64, error) {
-	// Check if there is enough data
-	if len(prices) < period {
-		return nil, fmt.Errorf("not enough data to calculate RSI")
-	}
-
-	// Initialize RSI array
-	rsis := make([]float64, len(prices))
-	// Fill the first `period-1` elements with NaN
-	for i := 0; i < period-1; i++ {
-		rsis[i] = math.NaN()
-	}
-
-	// Reverse the prices to handle descending order
-	reversedPrices := make([]float64, len(prices))
-	for i := 0; i < len(prices); i++ {
-		reversedPrices[i] = prices[len(prices)-1-i]
-	}
-
-	// Calculate the each gain and loss
-	gain := make([]float64, len(prices))
-	loss := make([]float64, len(prices))
-	for i := 1; i < len(prices); i++ {
-		change := reversedPrices[i] - reversedPrices[i-1]
-		if change > 0 {
-			gain[i] = change
-			loss[i] = 0
-		} else {
-			gain[i] = 0
-			loss[i] = -change
-		}
-	}
-
-	// Calculate RSI
-	for i := range reversedPrices {
-		rsisIndex := len(prices) - 1 - i
-		if i < period {
-			rsis[rsisIndex] = math.NaN()
-		} else {
-			avgGain := 0.0
-			avgLoss := 0.0
-			for j := 0; j < period; j++ {
-				avgGain += gain[i-j]
-				avgLoss += loss[i-j]
-			}
-			avgGain /= float64(period)
-			avgLoss /= float64(period)
-
-			if avgLoss == 0 {
-				rsis[rsisIndex] = 100
-			} else {
-				rs := avgGain / avgLoss
-				rsis[rsisIndex] = 100 - (100 / (1 + rs))
-			}
-		}
-	}
-
-	return rsis, nil
-}
-
-// MACD(Moving Average Convergence Divergence)を計算する関数
-func calcMACD(shortEma []float64, longEma []float64) ([]float64, []float64, []float64, []float64, []float64, error) {
-
-	if len(shortEma) != len(longEma) {
-		return nil, nil, nil, nil, nil, fmt.Errorf("not enough data to calculate MACD")
-	}
-
-	macdValue := make([]float64, len(shortEma))
-	macdSignal := make([]float64, len(shortEma))
-	macdHisto := make([]float64, len(shortEma))
-	macdEmaSignal := make([]float64, len(shortEma))
-	macdEmaHisto := make([]float64, len(shortEma))
-	for i := 0; i < len(shortEma); i++ {
-		macdValue[i] = shortEma[i] - longEma[i]
-	}
-
-	// シグナルラインの計算を行う(SMA)
-	for i := 0; i < len(macdValue); i++ {
-
-		sum := 0.0
-		isOufOfTarget := false
-		for j := 0; j < windowMacdSignal; j++ {
-			idx := i + j
-			if idx >= len(macdValue) || math.IsNaN(macdValue[idx]) {
-				isOufOfTarget = true
-				break
-			}
-			sum += macdValue[idx]
-		}
-		if isOufOfTarget == true {
-			macdSignal[i] = math.NaN()
-			macdHisto[i] = math.NaN()
-		} else {
-			macdSignal[i] = sum / float64(windowMacdSignal)
-			macdHisto[i] = macdValue[i] - macdSignal[i]
-		}
-	}
-
-	// シグナルラインの計算を行う(EMA)
-	macdEmaSignal = calculateEMA(macdValue, windowMacdSignal)
-	for i := 0; i < len(macdValue); i++ {
-		if math.IsNaN(macdEmaSignal[i]) {
-			macdEmaHisto[i] = math.NaN()
-		} else {
-			macdEmaHisto[i] = macdValue[i] - macdEmaSignal[i]
-		}
-
-	}
-	return macdValue, macdSignal, macdHisto, macdEmaSignal, macdEmaHisto, nil
-}
-
-// 1銘柄のurlを引数として、該当した銘柄の情報を返す
-func checkOneStockBrand(url string) []StockBrandInformation {
-
-	// Instantiate default collector
-	c := colly.NewCollector()
-
-	// データの取得 - テーブル
-	var retValue []StockBrandInformation
-	// <table class="stock_kabuka_dwm">
-	c.OnHTML(".stock_kabuka_dwm > tbody", func(e *colly.HTMLElement) {
-		e.ForEach("tr", func(_ int, el *colly.HTMLElement) {
-			var single StockBrandInformation
-			var err error
-
-			getStr := "20" + el.ChildText("th:nth-child(1)")
-			single.ParseDate, err = convert.ConvertStringToTime(getStr)
-			if err != nil {
-				slog.Info("err", "err", err)
-			}
-
-			getStr = strings.ReplaceAll(el.ChildText("td:nth-child(2)"), ",", "")
-			single.Opening, _ = strconv.ParseFloat(getStr, 64)
-			getStr = strings.ReplaceAll(el.ChildText("td:nth-child(3)"), ",", "")
-			single.High, _ = strconv.ParseFloat(getStr, 64)
-			getStr = strings.ReplaceAll(el.ChildText("td:nth-child(4)"), ",", "")
-			single.Low, _ = strconv.ParseFloat(getStr, 64)
-			getStr = strings.ReplaceAll(el.ChildText("td:nth-child(5)"), ",", "")
-			single.Closing, _ = strconv.ParseFloat(getStr, 64)
-			getStr = strings.ReplaceAll(el.ChildText("td:nth-child(8)"), ",", "")
-			single.Volume, _ = strconv.ParseFloat(getStr, 64)
-
-			retValue = append(retValue, single)
-		})
-	})
-
-	// Start scraping on https://XXX
-	c.Visit(url)
-
-	return retValue
-}
-
 // csvファイル、スクレイピングした該当銘柄の情報をマージする
 func csvMergeOneStockBrand(stockData []StockBrandInformation, csvContents []StockBrandInformation) []StockBrandInformation {
 
@@ -522,7 +486,8 @@ func csvMergeOneStockBrand(stockData []StockBrandInformation, csvContents []Stoc
 }
 
 // csvファイルを読みStockBrandInformationへデータをインサートする
-func readCSVInsertData(csvName string) ([]StockBrandInformation, bool) {
+// isBackupがtrueの場合は読み込んだ内容を1バージョン前のファイルとして保存する
+func readCSVInsertData(csvName string, isBackup bool) ([]StockBrandInformation, bool) {
 
 	var retData []StockBrandInformation
 	retInitialFlag := false
@@ -560,294 +525,1285 @@ func readCSVInsertData(csvName string) ([]StockBrandInformation, bool) {
 		})
 
 		// 読み込んだCSVを1バージョン前のモノとしてcsvに出力する
-		csvNameSlice := strings.Split(csvName, ".")
-		csvNameSlice[0] = csvNameSlice[0] + "_bef.csv"
-		_ = fileio.FileIoCsvWrite(csvNameSlice[0], fileContents, false)
+		if isBackup == true {
+			befName := strings.TrimSuffix(csvName, filepath.Ext(csvName)) + "_bef.csv"
+			_ = fileio.FileIoCsvWrite(befName, fileContents, false)
+		}
 	}
 	return retData, retInitialFlag
 }
 
-// スクレイピングし、csvファイルから読みこんだデータとマージしたStockBrandInformationを作成する
-func getWebIntegrateData(code string, isInitialCreate bool, csvData []StockBrandInformation) []StockBrandInformation {
+// 取得元の日足を StockBrandInformation に変換する
+func toStockBrandInformation(prices []datasource.DailyPrice) []StockBrandInformation {
+	var stockData []StockBrandInformation
+	for _, p := range prices {
+		stockData = append(stockData, StockBrandInformation{ParseDate: p.Date, Opening: p.Opening, High: p.High, Low: p.Low, Closing: p.Closing, Volume: p.Volume})
+	}
+	return stockData
+}
 
-	// スクレイピング
-	const maxPage = 10
-	// Obtain = Stock URL https://kabutan.jp/stock/kabuka?code=147A&ashi=day&page=1
-	// Obtain = Forex URL https://kabutan.jp/stock/kabuka?code=0970&ashi=day&page=4
-	baseUrl := "https://kabutan.jp/stock/kabuka?code="
-	subUrl := "&ashi=day&page="
-	for i := 1; i <= maxPage; i++ {
-		scrapeUrl := fmt.Sprintf("%s%s%s%d", baseUrl, code, subUrl, i)
-		slog.Info("url", "url", scrapeUrl)
+// 取得元から日足を取得し、csvファイルから読みこんだデータとマージしたStockBrandInformationを作成する
+// csvファイルがある場合は最新日付以降のみを取得する
+func getIntegrateData(code string, isInitialCreate bool, csvData []StockBrandInformation) ([]StockBrandInformation, error) {
 
-		retInformation := checkOneStockBrand(scrapeUrl)
-		slog.Info("Web Component", "len", len(retInformation))
+	var from time.Time
+	if isInitialCreate == false && len(csvData) > 0 {
+		from = csvData[0].ParseDate
+	}
+	prices, err := dataSource.FetchDaily(code, from, time.Time{})
+	if err != nil {
+		return csvData, err
+	}
+	slog.Info("Source Component", "len", len(prices))
 
-		if len(retInformation) <= 0 {
-			break
+	// CSVからのデータとマージ
+	csvData = csvMergeOneStockBrand(toStockBrandInformation(prices), csvData)
+	// 降順でソート
+	sort.Slice(csvData, func(i, j int) bool {
+		return csvData[i].ParseDate.After(csvData[j].ParseDate)
+	})
+	return csvData, nil
+}
+
+// 期間毎のテクニカル指標のキー(ModelDataのカラム名)を返す
+func termKey(name string, term int) string {
+	return name + strconv.Itoa(term)
+}
+
+// テクニカル指標の名前それぞれについて、設定された期間分のキーを返す
+// ATR、RSIは設定された平滑化方式分のキーを返す
+func termKeys(names []string) []string {
+	var keys []string
+	for _, name := range names {
+		var methodNames []string
+		if name == "ATR" || name == "RSI" {
+			for _, method := range smoothings {
+				methodNames = append(methodNames, smoothingIndexName(name, method))
+			}
+		} else {
+			methodNames = []string{name}
 		}
-		if isInitialCreate == false && !retInformation[0].ParseDate.After(csvData[0].ParseDate) {
-			slog.Info("Already", "information", retInformation[0].ParseDate, "lasttime", csvData[0].ParseDate)
-			break
+		for _, methodName := range methodNames {
+			for _, term := range termDay {
+				keys = append(keys, termKey(methodName, term))
+			}
 		}
+	}
+	return keys
+}
 
-		// CSVからのデータとマージ
-		csvData = csvMergeOneStockBrand(retInformation, csvData)
-		// 降順でソート
-		sort.Slice(csvData, func(i, j int) bool {
-			return csvData[i].ParseDate.After(csvData[j].ParseDate)
-		})
+// 平滑化方式を付加したテクニカル指標の名前を返す
+// 単純移動平均は従来通り "RSI"、Wilder は "RSIWilder"、EMA は "RSIEMA" とする
+func smoothingIndexName(name string, method indicator.Smoothing) string {
+	switch method {
+	case indicator.SmoothingWilder:
+		return name + "Wilder"
+	case indicator.SmoothingEMA:
+		return name + "EMA"
+	}
+	return name
+}
+
+// 設定された期間のうち最長の期間を返す
+func longestTerm() int {
+	return termDay[len(termDay)-1]
+}
+
+// 全てのテクニカル指標が NaN とならないために必要な足の本数を返す
+// 期間毎の指標はストキャスティクスのスロー%Dが最長期間に %D の期間2回分、ADXが最長期間の2倍、
+// MACDのシグナルは長期の移動平均が計算できる本数にさらに Signal 本、
+// 一目均衡表の先行スパンBは先行スパンBの期間に基準線の期間(ずらす本数)を加えた本数が必要
+func requiredBars() int {
+	bars := max(longestTerm()+windowStochSmoothing*2-2, longestTerm()*2, ichimokuPeriods.SenkouB+ichimokuPeriods.Kijun)
+	for _, def := range macdDefinitions {
+		bars = max(bars, def.Slow+def.Signal)
 	}
-	return csvData
+	return bars
 }
 
 // 取得した該当データに対する移動平均、ボラティリティ(標準偏差)などのテクニカル指標を計算する
 func calculateTechnicalIndex(stockData []StockBrandInformation) []StockBrandInformation {
 
 	dataLen := len(stockData)
-	var closingPrices, volumeValues []float64
-	for _, c := range stockData {
+	var closingPrices, highPrices, lowPrices, volumeValues, highLowDiff []float64
+	for i, c := range stockData {
 		closingPrices = append(closingPrices, c.Closing)
+		highLowDiff = append(highLowDiff, c.High-c.Low)
+		highPrices = append(highPrices, c.High)
+		lowPrices = append(lowPrices, c.Low)
 		volumeValues = append(volumeValues, c.Volume)
+		stockData[i].Index = make(map[string]float64)
 	}
-	var movingAveValue [TermNum][]float64
 
-	for mAveType := Term5; mAveType < TermNum; mAveType++ {
+	for _, term := range termDay {
+		// 移動平均、ボラティリティ(標準偏差)、乖離率、ボリンジャーバンド、出来高の移動平均・比率・乖離率、高値と安値の差の移動平均
+		movingAve := indicator.Smooth(closingPrices, term, indicator.SmoothingSMA)
+		volatility := indicator.StdDev(closingPrices, term)
+		madRate := indicator.MADRate(closingPrices, movingAve)
+		volumeMovingAve := indicator.Smooth(volumeValues, term, indicator.SmoothingSMA)
+		volumeMADRate := indicator.MADRate(volumeValues, volumeMovingAve)
+		highLowVolatility := indicator.Smooth(highLowDiff, term, indicator.SmoothingSMA)
 		for i := 0; i < dataLen; i++ {
+			index := stockData[i].Index
+			index[termKey("MovingAve", term)] = movingAve[i]
+			index[termKey("Volatility", term)] = volatility[i]
+			index[termKey("MADRate", term)] = madRate[i]
+			index[termKey("upperBBand", term)] = movingAve[i] + (BollingerBandK * volatility[i])
+			index[termKey("underBBand", term)] = movingAve[i] - (BollingerBandK * volatility[i])
+			index[termKey("VMovingAve", term)] = volumeMovingAve[i]
+			index[termKey("VolumeRatio", term)] = volumeValues[i] / volumeMovingAve[i]
+			index[termKey("VolumeMADRate", term)] = volumeMADRate[i]
+			index[termKey("HighLowVolatility", term)] = highLowVolatility[i]
+		}
 
-			const bollingerBandK = 2
-			var price, priceDiff, trueRange, volume []float64
-			for idx := i; idx < i+termDay[mAveType]; idx++ {
-				if idx < dataLen {
-					price = append(price, stockData[idx].Closing)
-					priceDiff = append(priceDiff, stockData[idx].High-stockData[idx].Low)
-					if (idx + 1) < dataLen {
-						candidate := []float64{stockData[idx].High - stockData[idx].Low,
-							math.Abs(stockData[idx].High - stockData[idx+1].Closing), math.Abs(stockData[idx].Low - stockData[idx+1].Closing)}
-						trueRange = append(trueRange, slices.Max(candidate))
-					}
-					volume = append(volume, stockData[idx].Volume)
-				}
-			}
-			if len(price) == termDay[mAveType] {
-				stockData[i].MovingAve[mAveType] = calcMovingAverage(price)
-				stockData[i].Volatility[mAveType] = calcStandardDeviation(price, stockData[i].MovingAve[mAveType])
-				stockData[i].MADRate[mAveType] = calcMADRate(stockData[i].Closing, stockData[i].MovingAve[mAveType])
-				stockData[i].UpperBBand[mAveType] = stockData[i].MovingAve[mAveType] + (bollingerBandK * stockData[i].Volatility[mAveType])
-				stockData[i].UnderBBand[mAveType] = stockData[i].MovingAve[mAveType] - (bollingerBandK * stockData[i].Volatility[mAveType])
-				stockData[i].VolumeMovingAve[mAveType] = calcMovingAverage(volume)
-				stockData[i].VolumeRatio[mAveType] = stockData[i].Volume / stockData[i].VolumeMovingAve[mAveType]
-				stockData[i].VolumeMADRate[mAveType] = calcMADRate(stockData[i].Volume, stockData[i].VolumeMovingAve[mAveType])
-			} else {
-				stockData[i].MovingAve[mAveType] = math.NaN()
-				stockData[i].Volatility[mAveType] = math.NaN()
-				stockData[i].MADRate[mAveType] = math.NaN()
-				stockData[i].UpperBBand[mAveType] = math.NaN()
-				stockData[i].UnderBBand[mAveType] = math.NaN()
-				stockData[i].VolumeMovingAve[mAveType] = math.NaN()
-				stockData[i].VolumeRatio[mAveType] = math.NaN()
-				stockData[i].VolumeMADRate[mAveType] = math.NaN()
-			}
-			if len(priceDiff) == termDay[mAveType] {
-				stockData[i].HighLowVolatility[mAveType] = calcMovingAverage(priceDiff)
-			} else {
-				stockData[i].HighLowVolatility[mAveType] = math.NaN()
-			}
-			if len(trueRange) == termDay[mAveType] {
-				stockData[i].ATR[mAveType] = calcMovingAverage(trueRange)
-			} else {
-				stockData[i].ATR[mAveType] = math.NaN()
+		// ATR、RSIの計算(平滑化方式毎)
+		for _, method := range smoothings {
+			resultATR := indicator.ATR(highPrices, lowPrices, closingPrices, term, method)
+			resultRSI := indicator.RSI(closingPrices, term, method)
+			for i := 0; i < dataLen; i++ {
+				stockData[i].Index[termKey(smoothingIndexName("ATR", method), term)] = resultATR[i]
+				stockData[i].Index[termKey(smoothingIndexName("RSI", method), term)] = resultRSI[i]
 			}
-			// MACD計算用に移動平均を配列化
-			movingAveValue[mAveType] = append(movingAveValue[mAveType], stockData[i].MovingAve[mAveType])
 		}
 
-		// RSIの計算
-		resultRSI, errRSI := calcRSI(closingPrices, termDay[mAveType])
-		if errRSI != nil {
-			for i, _ := range resultRSI {
-				stockData[i].RSI[mAveType] = math.NaN()
-			}
-		} else {
-			for i, c := range resultRSI {
-				stockData[i].RSI[mAveType] = c
+		// ストキャスティクス(ファスト %K・%D、スロー %D。スロー %K はファスト %D と同じ)、ウィリアムズ%R、CCIの計算
+		fastK, williamsR := indicator.Stochastics(highPrices, lowPrices, closingPrices, term)
+		fastD := indicator.Smooth(fastK, windowStochSmoothing, indicator.SmoothingSMA)
+		slowD := indicator.Smooth(fastD, windowStochSmoothing, indicator.SmoothingSMA)
+		cci := indicator.CCI(highPrices, lowPrices, closingPrices, term)
+		for i := 0; i < dataLen; i++ {
+			stockData[i].Index[termKey("StochFastK", term)] = fastK[i]
+			stockData[i].Index[termKey("StochFastD", term)] = fastD[i]
+			stockData[i].Index[termKey("StochSlowD", term)] = slowD[i]
+			stockData[i].Index[termKey("WilliamsR", term)] = williamsR[i]
+			stockData[i].Index[termKey("CCI", term)] = cci[i]
+		}
+
+		// DMI(+DI、-DI、ADX)の計算
+		plusDI, minusDI, adx := indicator.DMI(highPrices, lowPrices, closingPrices, term)
+		for i := 0; i < dataLen; i++ {
+			stockData[i].Index[termKey("PlusDI", term)] = plusDI[i]
+			stockData[i].Index[termKey("MinusDI", term)] = minusDI[i]
+			stockData[i].Index[termKey("ADX", term)] = adx[i]
+		}
+
+		//	指数移動平均(終値、出来高)
+		tempEma := indicator.EMA(closingPrices, term)
+		tempVolumeEma := indicator.EMA(volumeValues, term)
+		for i := 0; i < dataLen; i++ {
+			stockData[i].Index[termKey("EMA", term)] = tempEma[i]
+			stockData[i].Index[termKey("VolumeEMA", term)] = tempVolumeEma[i]
+		}
+	}
+
+	// MACDの計算(定義毎に終値の短期・長期の移動平均から計算する)
+	for _, def := range macdDefinitions {
+		fastAve := indicator.Smooth(closingPrices, def.Fast, def.Base)
+		slowAve := indicator.Smooth(closingPrices, def.Slow, def.Base)
+		macd, _ := indicator.MACD(fastAve, slowAve, def.Signal)
+		for i := 0; i < dataLen; i++ {
+			stockData[i].Index[def.Name+"MACD"] = macd.Value[i]
+			stockData[i].Index[def.Name+"MACDSignalSMA"] = macd.SignalSMA[i]
+			stockData[i].Index[def.Name+"MACDHistoSMA"] = macd.HistoSMA[i]
+			stockData[i].Index[def.Name+"MACDSignalEMA"] = macd.SignalEMA[i]
+			stockData[i].Index[def.Name+"MACDHistoEMA"] = macd.HistoEMA[i]
+		}
+	}
+
+	// パラボリックSARの計算
+	sar, sarTrend := indicator.ParabolicSAR(highPrices, lowPrices, closingPrices, sarAcceleration)
+	for i := 0; i < dataLen; i++ {
+		stockData[i].Index["PSAR"] = sar[i]
+		stockData[i].Index["PSARTrend"] = sarTrend[i]
+	}
+
+	// ローソク足パターンの判定
+	var bars []candle.Bar
+	for _, c := range stockData {
+		bars = append(bars, candle.Bar{Opening: c.Opening, High: c.High, Low: c.Low, Closing: c.Closing})
+	}
+	for name, values := range candle.Detect(bars) {
+		for i := 0; i < dataLen; i++ {
+			stockData[i].Index[name] = values[i]
+		}
+	}
+
+	// 出来高を用いる指標(OBV、A/Dライン、VWAP、MFI、CMF)の計算。為替は出来高がないため計算しない
+	if nowObtain != Forex {
+		obv := indicator.OBV(closingPrices, volumeValues)
+		adLine := indicator.ADLine(highPrices, lowPrices, closingPrices, volumeValues)
+		for i := 0; i < dataLen; i++ {
+			stockData[i].Index["OBV"] = obv[i]
+			stockData[i].Index["ADLine"] = adLine[i]
+		}
+		for _, term := range termDay {
+			vwap := indicator.VWAP(highPrices, lowPrices, closingPrices, volumeValues, term)
+			mfi := indicator.MFI(highPrices, lowPrices, closingPrices, volumeValues, term)
+			cmf := indicator.CMF(highPrices, lowPrices, closingPrices, volumeValues, term)
+			for i := 0; i < dataLen; i++ {
+				stockData[i].Index[termKey("VWAP", term)] = vwap[i]
+				stockData[i].Index[termKey("MFI", term)] = mfi[i]
+				stockData[i].Index[termKey("CMF", term)] = cmf[i]
 			}
 		}
 	}
 
-	// MACDの計算
-	// 5日移動平均と30日移動平均のMACD
-	tmpMACDVal, tmpMACDSignal, tmpMACDHisto, tmpMACDEMASignal, tmpMACDEMAHisto, _ := calcMACD(movingAveValue[Term5], movingAveValue[Term30])
+	// 一目均衡表の計算
+	// 先行スパンは ichimokuPeriods.Kijun 本先に描くため、当日の雲は ichimokuPeriods.Kijun 本前(日付降順では i+ichimokuPeriods.Kijun)に計算した値となる
+	// 当日に計算した先行スパン(ichimokuPeriods.Kijun 本先の雲)は Lead として出力する
+	// 遅行スパンは当日の終値を ichimokuPeriods.Kijun 本前に描くため、ichimokuPeriods.Kijun 本前の終値との差を出力する
+	tenkan := indicator.MidPrice(highPrices, lowPrices, ichimokuPeriods.Tenkan)
+	kijun := indicator.MidPrice(highPrices, lowPrices, ichimokuPeriods.Kijun)
+	senkouALead := make([]float64, dataLen)
 	for i := 0; i < dataLen; i++ {
-		stockData[i].ShortMacdVal = tmpMACDVal[i]
-		stockData[i].ShortMacdSmaSig = tmpMACDSignal[i]
-		stockData[i].ShortMacdSmaHisto = tmpMACDHisto[i]
-		stockData[i].ShortMacdEmaSig = tmpMACDEMASignal[i]
-		stockData[i].ShortMacdEmaHisto = tmpMACDEMAHisto[i]
-	}
-	// 14日移動平均と30日移動平均のMACD
-	tmpMACDVal, tmpMACDSignal, tmpMACDHisto, tmpMACDEMASignal, tmpMACDEMAHisto, _ = calcMACD(movingAveValue[Term14], movingAveValue[Term30])
+		senkouALead[i] = (tenkan[i] + kijun[i]) / 2
+	}
+	senkouBLead := indicator.MidPrice(highPrices, lowPrices, ichimokuPeriods.SenkouB)
+	senkouA := indicator.Shift(senkouALead, ichimokuPeriods.Kijun)
+	senkouB := indicator.Shift(senkouBLead, ichimokuPeriods.Kijun)
+	chikouBase := indicator.Shift(closingPrices, ichimokuPeriods.Kijun)
 	for i := 0; i < dataLen; i++ {
-		stockData[i].LongMacdVal = tmpMACDVal[i]
-		stockData[i].LongMacdSmaSig = tmpMACDSignal[i]
-		stockData[i].LongMacdSmaHisto = tmpMACDHisto[i]
-		stockData[i].LongMacdEmaSig = tmpMACDEMASignal[i]
-		stockData[i].LongMacdEmaHisto = tmpMACDEMAHisto[i]
-	}
-
-	//	指数移動平均(終値、出来高)
-	tempEma5 := calculateEMA(closingPrices, termDay[Term5])
-	tempEma14 := calculateEMA(closingPrices, termDay[Term14])
-	tempEma30 := calculateEMA(closingPrices, termDay[Term30])
-	tempVolumeEma5 := calculateEMA(volumeValues, termDay[Term5])
-	tempVolumeEma14 := calculateEMA(volumeValues, termDay[Term14])
-	tempVolumeEma30 := calculateEMA(volumeValues, termDay[Term30])
+		index := stockData[i].Index
+		index["IchimokuTenkan"] = tenkan[i]
+		index["IchimokuKijun"] = kijun[i]
+		index["IchimokuSenkouA"] = senkouA[i]
+		index["IchimokuSenkouB"] = senkouB[i]
+		index["IchimokuSenkouALead"] = senkouALead[i]
+		index["IchimokuSenkouBLead"] = senkouBLead[i]
+		index["IchimokuChikouDiff"] = closingPrices[i] - chikouBase[i]
+		index["IchimokuCloudPosition"] = ichimokuCloudPosition(closingPrices[i], senkouA[i], senkouB[i])
+		index["IchimokuCloudThickness"] = senkouA[i] - senkouB[i]
+	}
+
 	//	出来高変化率
 	for i := 0; i < dataLen; i++ {
-		if i+1 < dataLen && stockData[i+1].Volume > 0 {
-			stockData[i].VolumeChangeRate = (stockData[i].Volume - stockData[i+1].Volume) / stockData[i+1].Volume
+		if i+1 < dataLen {
+			stockData[i].Index["VCR"] = volumeChangeRate(stockData[i].Volume, stockData[i+1].Volume)
+		} else {
+			stockData[i].Index["VCR"] = 0
 		}
-		stockData[i].EMA[Term5] = tempEma5[i]
-		stockData[i].EMA[Term14] = tempEma14[i]
-		stockData[i].EMA[Term30] = tempEma30[i]
-		stockData[i].VolumeEMA[Term5] = tempVolumeEma5[i]
-		stockData[i].VolumeEMA[Term14] = tempVolumeEma14[i]
-		stockData[i].VolumeEMA[Term30] = tempVolumeEma30[i]
 	}
 
 	return stockData
 }
 
-// ARIMA予測モデルのpythonファイルを実行し予測結果を取得する
-func arimaPrediction(csvfile string) ([]ArimaPredictionResultInformation, error) {
-
-	// Pythonスクリプトのコマンドを構築
-	cmd := exec.Command("python", "arima_insights.py", csvfile)
-	// コマンド実行と結果取得
-	var stdOutBuff, stdErrBuff bytes.Buffer
-	cmd.Stdout = &stdOutBuff
-	cmd.Stderr = &stdErrBuff
-	err := cmd.Run()
-	if err != nil {
-		slog.Info("err cmd Run.", "err", err)
-		return nil, err
+// 終値と雲の位置(雲の上: 1、雲の中: 0、雲の下: -1)を返す。雲がない日付は NaN
+func ichimokuCloudPosition(closing float64, senkouA float64, senkouB float64) float64 {
+	switch {
+	case math.IsNaN(senkouA) || math.IsNaN(senkouB):
+		return math.NaN()
+	case closing > max(senkouA, senkouB):
+		return 1
+	case closing < min(senkouA, senkouB):
+		return -1
 	}
-	stdOutStr := stdOutBuff.String()
+	return 0
+}
 
-	// JSON -> 構造体へ
-	var arimaPredictResult []ArimaPredictionResultInformation
-	err = json.Unmarshal([]byte(stdOutStr), &arimaPredictResult)
-	if err != nil {
-		slog.Info("err Json Convert.", "err", err)
-		return nil, err
+// 前日の出来高 previous に対する出来高変化率を返す。前日の出来高がない場合は 0
+func volumeChangeRate(volume float64, previous float64) float64 {
+	if previous > 0 {
+		return (volume - previous) / previous
 	}
+	return 0
+}
 
-	// time.Time を求める
-	for i := 0; i < len(arimaPredictResult); i++ {
+// 逐次計算の状態に保存する計算の設定(状態の形式の版、取得種別、期間、MACDの定義、平滑化方式、パラボリックSARの加速因子、一目均衡表の期間)を返す
+func indexSettings() string {
+	return fmt.Sprintf("version=%d obtain=%d terms=%v macd=%v smoothing=%v psar=%v ichimoku=%v", IndexStateVersion, nowObtain, termDay, macdDefinitions, smoothings, sarAcceleration, ichimokuPeriods)
+}
 
-		// フォーマットを指定
-		layout := "2006-01-02T15:04:05.000"
-		// パース
-		arimaPredictResult[i].ParseDate, err = time.Parse(layout, arimaPredictResult[i].Date)
-		if err != nil {
-			slog.Info("Error parsing time", "err", err)
-			return nil, err
+// 現在の設定で逐次計算の状態を生成する
+func newIndexState() *IndexState {
+	state := &IndexState{
+		Settings:    indexSettings(),
+		SMA:         make(map[string]*indicator.SmoothStream),
+		StdDev:      make(map[string]*indicator.StdDevStream),
+		Stochastics: make(map[string]*indicator.StochasticsStream),
+		CCI:         make(map[string]*indicator.CCIStream),
+		ATR:         make(map[string]*indicator.ATRStream),
+		RSI:         make(map[string]*indicator.RSIStream),
+		EMA:         make(map[string]*indicator.SmoothStream),
+		DMI:         make(map[string]*indicator.DMIStream),
+		MACD:        make(map[string]*indicator.MACDStream),
+		VWAP:        make(map[string]*indicator.VWAPStream),
+		MFI:         make(map[string]*indicator.MFIStream),
+		CMF:         make(map[string]*indicator.CMFStream),
+		PSAR:        indicator.NewParabolicSARStream(sarAcceleration),
+		OBV:         &indicator.OBVStream{},
+		ADLine:      &indicator.ADLineStream{},
+		Ichimoku:    indicator.NewIchimokuStream(ichimokuPeriods),
+	}
+	for _, term := range termDay {
+		for _, name := range []string{"MovingAve", "VMovingAve", "HighLowVolatility"} {
+			state.SMA[termKey(name, term)] = indicator.NewSmoothStream(term, indicator.SmoothingSMA)
+		}
+		state.SMA[termKey("StochFastD", term)] = indicator.NewSmoothStream(windowStochSmoothing, indicator.SmoothingSMA)
+		state.SMA[termKey("StochSlowD", term)] = indicator.NewSmoothStream(windowStochSmoothing, indicator.SmoothingSMA)
+		state.StdDev[termKey("Volatility", term)] = indicator.NewStdDevStream(term)
+		state.Stochastics[termKey("StochFastK", term)] = indicator.NewStochasticsStream(term)
+		state.CCI[termKey("CCI", term)] = indicator.NewCCIStream(term)
+		state.VWAP[termKey("VWAP", term)] = indicator.NewVWAPStream(term)
+		state.MFI[termKey("MFI", term)] = indicator.NewMFIStream(term)
+		state.CMF[termKey("CMF", term)] = indicator.NewCMFStream(term)
+		for _, method := range smoothings {
+			state.ATR[termKey(smoothingIndexName("ATR", method), term)] = indicator.NewATRStream(term, method)
+			state.RSI[termKey(smoothingIndexName("RSI", method), term)] = indicator.NewRSIStream(term, method)
 		}
+		state.EMA[termKey("EMA", term)] = indicator.NewSmoothStream(term, indicator.SmoothingEMA)
+		state.EMA[termKey("VolumeEMA", term)] = indicator.NewSmoothStream(term, indicator.SmoothingEMA)
+		state.DMI[termKey("DMI", term)] = indicator.NewDMIStream(term)
 	}
-	return arimaPredictResult, nil
+	for _, def := range macdDefinitions {
+		state.MACD[def.Name] = indicator.NewMACDStream(def.Base, def.Fast, def.Slow, def.Signal)
+	}
+	return state
 }
 
-// 該当銘柄のcsvデータを作成する
-func csvCreationOneStockBrand(code string, cData []CommonInformation) {
+// 足 c (計算済みの足の翌日)を逐次計算器に与え、その足の全てのテクニカル指標の値を返す
+// 移動合計・平均と偏差の二乗和・期間内の最高値と最安値の候補などを状態に保持し、1本あたり期間によらない計算量で更新する(CCI の平均偏差を除く)
+func (s *IndexState) next(c StockBrandInformation) map[string]float64 {
 
-	// RawDataのcsvファイルを読み込んでStockBrandInformationに展開
-	// モデル用にテクニカル指標を付加したファイルをModelDataに出力
-	rawCsvFileName := fmt.Sprintf("%s%s/%s", ResourceDir, code, RawDataFileName)
-	modelCsvFileName := fmt.Sprintf("%s%s/%s", ResourceDir, code, ModelDataFileName)
-	synthesisStockData, isInitialCreation := readCSVInsertData(rawCsvFileName)
-	slog.Info("File Component", "len", len(synthesisStockData))
+	index := make(map[string]float64)
+	for key, stream := range s.ATR {
+		index[key] = stream.Next(c.High, c.Low, c.Closing)
+	}
+	for key, stream := range s.RSI {
+		index[key] = stream.Next(c.Closing)
+	}
+	for _, term := range termDay {
+		movingAve := s.SMA[termKey("MovingAve", term)].Next(c.Closing)
+		volatility := s.StdDev[termKey("Volatility", term)].Next(c.Closing)
+		volumeMovingAve := s.SMA[termKey("VMovingAve", term)].Next(c.Volume)
+		index[termKey("MovingAve", term)] = movingAve
+		index[termKey("Volatility", term)] = volatility
+		index[termKey("MADRate", term)] = indicator.MADRateValue(c.Closing, movingAve)
+		index[termKey("upperBBand", term)] = movingAve + (BollingerBandK * volatility)
+		index[termKey("underBBand", term)] = movingAve - (BollingerBandK * volatility)
+		index[termKey("VMovingAve", term)] = volumeMovingAve
+		index[termKey("VolumeRatio", term)] = c.Volume / volumeMovingAve
+		index[termKey("VolumeMADRate", term)] = indicator.MADRateValue(c.Volume, volumeMovingAve)
+		index[termKey("HighLowVolatility", term)] = s.SMA[termKey("HighLowVolatility", term)].Next(c.High - c.Low)
+
+		fastK, williamsR := s.Stochastics[termKey("StochFastK", term)].Next(c.High, c.Low, c.Closing)
+		fastD := s.SMA[termKey("StochFastD", term)].Next(fastK)
+		index[termKey("StochFastK", term)] = fastK
+		index[termKey("StochFastD", term)] = fastD
+		index[termKey("StochSlowD", term)] = s.SMA[termKey("StochSlowD", term)].Next(fastD)
+		index[termKey("WilliamsR", term)] = williamsR
+		index[termKey("CCI", term)] = s.CCI[termKey("CCI", term)].Next(c.High, c.Low, c.Closing)
 
-	// スクレイピングし、csvファイルから読みこんだデータとマージしたStockBrandInformationを作成
-	synthesisStockData = getWebIntegrateData(code, isInitialCreation, synthesisStockData)
+		if nowObtain != Forex {
+			index[termKey("VWAP", term)] = s.VWAP[termKey("VWAP", term)].Next(c.High, c.Low, c.Closing, c.Volume)
+			index[termKey("MFI", term)] = s.MFI[termKey("MFI", term)].Next(c.High, c.Low, c.Closing, c.Volume)
+			index[termKey("CMF", term)] = s.CMF[termKey("CMF", term)].Next(c.High, c.Low, c.Closing, c.Volume)
+		}
 
-	// 移動平均、ボラティリティの計算
-	synthesisStockData = calculateTechnicalIndex(synthesisStockData)
+		index[termKey("EMA", term)] = s.EMA[termKey("EMA", term)].Next(c.Closing)
+		index[termKey("VolumeEMA", term)] = s.EMA[termKey("VolumeEMA", term)].Next(c.Volume)
+		plusDI, minusDI, adx := s.DMI[termKey("DMI", term)].Next(c.High, c.Low, c.Closing)
+		index[termKey("PlusDI", term)] = plusDI
+		index[termKey("MinusDI", term)] = minusDI
+		index[termKey("ADX", term)] = adx
+	}
+	for _, def := range macdDefinitions {
+		macd := s.MACD[def.Name].Next(c.Closing)
+		index[def.Name+"MACD"] = macd.Value
+		index[def.Name+"MACDSignalSMA"] = macd.SignalSMA
+		index[def.Name+"MACDHistoSMA"] = macd.HistoSMA
+		index[def.Name+"MACDSignalEMA"] = macd.SignalEMA
+		index[def.Name+"MACDHistoEMA"] = macd.HistoEMA
+	}
+	index["PSAR"], index["PSARTrend"] = s.PSAR.Next(c.High, c.Low, c.Closing)
+	if nowObtain != Forex {
+		index["OBV"] = s.OBV.Next(c.Closing, c.Volume)
+		index["ADLine"] = s.ADLine.Next(c.High, c.Low, c.Closing, c.Volume)
+	}
 
-	// ARIMA予測モデル計算
-	var arimaPredictionResult []ArimaPredictionResultInformation
-	var errArima error
-	if isInitialCreation == false {
-		arimaPredictionResult, errArima = arimaPrediction(rawCsvFileName)
-		if errArima != nil {
-			slog.Info("ARIMA Prediction Err.", "error", errArima)
-			return
-		}
+	ichimoku := s.Ichimoku.Next(c.High, c.Low, c.Closing)
+	index["IchimokuTenkan"] = ichimoku.Tenkan
+	index["IchimokuKijun"] = ichimoku.Kijun
+	index["IchimokuSenkouA"] = ichimoku.SenkouA
+	index["IchimokuSenkouB"] = ichimoku.SenkouB
+	index["IchimokuSenkouALead"] = ichimoku.SenkouALead
+	index["IchimokuSenkouBLead"] = ichimoku.SenkouBLead
+	index["IchimokuChikouDiff"] = ichimoku.ChikouDiff
+	index["IchimokuCloudPosition"] = ichimokuCloudPosition(c.Closing, ichimoku.SenkouA, ichimoku.SenkouB)
+	index["IchimokuCloudThickness"] = ichimoku.SenkouA - ichimoku.SenkouB
+
+	// ローソク足パターンは判定に用いる最大の本数の足のみ保持して判定する
+	s.Candles = append(s.Candles, candle.Bar{Opening: c.Opening, High: c.High, Low: c.Low, Closing: c.Closing})
+	if len(s.Candles) > candlePatternBars() {
+		s.Candles = s.Candles[1:]
+	}
+	bars := slices.Clone(s.Candles)
+	slices.Reverse(bars)
+	for name, values := range candle.Detect(bars) {
+		index[name] = values[0]
 	}
-	/*
-		for _, c := range arimaPredictionResult {
-			slog.Info("Arima", "date", c.Date, "predict", c.Arima_Prediction, "Diff", c.Prediction_Difference)
-		}
-	*/
 
-	// CSVに出力するように文字列に変換。日付フォーマットを time.DateTime から　yyyy/mm/dd へ変更する。
-	var outputStr [][]string
-	var lineStr []string = []string{"date", "DayOfWeek", "opening", "high", "low", "closing"}
-	var lineSubStr []string = []string{"MovingAve5", "MovingAve14", "MovingAve30", "EMA5", "EMA14", "EMA30", "Volatility5", "Volatility14", "Volatility30",
-		"HighLowVolatility5", "HighLowVolatility14", "HighLowVolatility30",
-		"ATR5", "ATR14", "ATR30", "MADRate5", "MADRate14", "MADRate30", "RSI5", "RSI14", "RSI30",
-		"shortMACD", "shortMACDSignalSMA", "shortMACDHistoSMA", "shortMACDSignalEMA", "shortMACDHistoEMA", "longMACD", "longMACDSignalSMA", "longMACDHistoSMA", "longMACDSignalEMA", "longMACDHistoEMA",
-		"upperBBand5", "upperBBand14", "upperBBand30", "underBBand5", "underBBand14", "underBBand30",
-		"ARIMAPredict", "ARIMAPredictDiff"}
-	if nowObtain != Forex {
-		lineStr = append(lineStr, "volume", "VCR", "VMovingAve5", "VMovingAve14", "VMovingAve30", "VolumeRatio5", "VolumeRatio14", "VolumeRatio30",
-			"VolumeEMA5", "VolumeEMA14", "VolumeEMA30", "VolumeMADRate5", "VolumeMADRate14", "VolumeMADRate30",
-			"InterestRateate", "UnemployRateate", "CPI", "GDP", "Tankan")
-	} else {
-		if code == "0970" {
-			// ユーロドル
-			lineStr = append(lineStr, "InterestRateate(USA)", "InterestRateate(EURO)", "UnemployRateate(USA)", "UnemployRateate(EURO)",
-				"CPI(USA)", "CPI(EURO)", "GDP(USA)", "GDP(EURO)")
-		} else if code == "0952" {
-			// ポンド円
-			lineStr = append(lineStr, "InterestRateate(UK)", "InterestRateate(JPN)", "UnemployRateate(UK)", "UnemployRateate(JPN)",
-				"CPI(UK)", "CPI(JPN)", "GDP(UK)", "GDP(JPN)")
-		}
+	index["VCR"] = 0
+	if s.Bars > 0 {
+		index["VCR"] = volumeChangeRate(c.Volume, s.LastBar.Volume)
 	}
-	lineStr = append(lineStr, lineSubStr...)
-	//		var nowObtain = Forex
-	outputStr = append(outputStr, lineStr)
-	for i, c := range synthesisStockData {
 
-		// Nanが発生してしまうデータを出力しない
-		// 30日間移動平均でデータ数が30未満だとNaNが発生してしまう
-		// MACDシグナルを計算するために、さらにwindowMacdSignal-1(8)日間のデータがないとNanが発生する
-		if i >= len(synthesisStockData)-termDay[Term30]-windowMacdSignal+1 {
-			break
-		}
+	s.Bars++
+	s.LastBar = StockBrandInformation{ParseDate: c.ParseDate, Opening: c.Opening, High: c.High, Low: c.Low, Closing: c.Closing, Volume: c.Volume}
+	return index
+}
 
-		lineStr = nil
-		commonInfo := getCommonInformation(cData, c.ParseDate)
-		var arimaC ArimaPredictionResultInformation
-		for arimaI, _ := range arimaPredictionResult {
-			if c.ParseDate.Year() == arimaPredictionResult[arimaI].ParseDate.Year() &&
-				c.ParseDate.Month() == arimaPredictionResult[arimaI].ParseDate.Month() &&
-				c.ParseDate.Day() == arimaPredictionResult[arimaI].ParseDate.Day() {
-				arimaC = arimaPredictionResult[arimaI]
-				break
+// ローソク足パターンの判定に用いる最大の足の本数を返す
+func candlePatternBars() int {
+	bars := 0
+	for _, p := range candle.Patterns {
+		bars = max(bars, p.Bars)
+	}
+	return bars
+}
+
+// 保存した逐次計算の状態と、計算済みの足の指標の値(日付の文字列毎)を読み込む
+func readIndexState(code string) (*IndexState, map[string]map[string]float64, error) {
+
+	// 読み込めない状態ファイルは設定が空となり、設定の不一致として計算し直す
+	var state IndexState
+	if err := fileio.FileIoJsonRead(stockFilePath(code, IndexStateFileName), &state); err != nil {
+		return nil, nil, err
+	}
+	contents, err := fileio.FileIoCsvRead(stockFilePath(code, IndexCacheFileName))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(contents) == 0 {
+		return nil, nil, fmt.Errorf("%s: no header", IndexCacheFileName)
+	}
+	header := contents[0]
+	cache := make(map[string]map[string]float64, len(contents)-1)
+	for _, row := range contents[1:] {
+		index := make(map[string]float64, len(header)-1)
+		for j := 1; j < len(header); j++ {
+			value, err := strconv.ParseFloat(row[j], 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", IndexCacheFileName, err)
 			}
+			index[header[j]] = value
+		}
+		cache[row[0]] = index
+	}
+	return &state, cache, nil
+}
+
+// 逐次計算の状態と全ての足の指標の値(丸めずに出力する)を保存する
+func writeIndexState(code string, state *IndexState, stockData []StockBrandInformation) error {
+
+	if err := fileio.FileIoJsonWrite(stockFilePath(code, IndexStateFileName), state, false); err != nil {
+		return err
+	}
+	var keys []string
+	if len(stockData) > 0 {
+		keys = slices.Sorted(maps.Keys(stockData[0].Index))
+	}
+	outputStr := [][]string{append([]string{"date"}, keys...)}
+	for _, c := range stockData {
+		lineStr := []string{formatCsvDate(c.ParseDate)}
+		for _, key := range keys {
+			lineStr = append(lineStr, strconv.FormatFloat(c.Index[key], 'g', -1, 64))
+		}
+		outputStr = append(outputStr, lineStr)
+	}
+	return fileio.FileIoCsvWrite(stockFilePath(code, IndexCacheFileName), outputStr, false)
+}
+
+// 保存した状態から続きを計算できるかを判定し、追加された足の本数、状態、計算済みの足の指標の値を返す
+// 状態がない、設定が変わった、計算済みの最新の足の四本値が変わった、計算済みの足の本数が変わった(backfill で過去の足を追加した場合など)
+// 場合は続きから計算できないため state を nil で返す
+func resumeIndexState(code string, stockData []StockBrandInformation) (int, *IndexState, map[string]map[string]float64) {
+
+	state, cache, err := readIndexState(code)
+	if err != nil {
+		slog.Info("IndexState Not Found", "code", code, "err", err)
+		return 0, nil, nil
+	}
+	if state.Settings != indexSettings() {
+		slog.Info("IndexState Settings Changed", "code", code, "state", state.Settings)
+		return 0, nil, nil
+	}
+	added := slices.IndexFunc(stockData, func(c StockBrandInformation) bool {
+		return c.ParseDate.Equal(state.LastBar.ParseDate)
+	})
+	if added < 0 || len(stockData)-added != state.Bars {
+		slog.Info("IndexState Bars Changed", "code", code, "last", formatCsvDate(state.LastBar.ParseDate), "bars", state.Bars)
+		return 0, nil, nil
+	}
+	last := stockData[added]
+	if last.Opening != state.LastBar.Opening || last.High != state.LastBar.High || last.Low != state.LastBar.Low ||
+		last.Closing != state.LastBar.Closing || last.Volume != state.LastBar.Volume {
+		slog.Info("IndexState Last Bar Changed", "code", code, "date", formatCsvDate(last.ParseDate))
+		return 0, nil, nil
+	}
+	for _, c := range stockData[added:] {
+		if _, ok := cache[formatCsvDate(c.ParseDate)]; !ok {
+			slog.Info("IndexState Cache Missing", "code", code, "date", formatCsvDate(c.ParseDate))
+			return 0, nil, nil
+		}
+	}
+	return added, state, cache
+}
+
+// 前回保存した状態から、追加された足のみテクニカル指標を逐次計算器で1本ずつ計算する
+// 続きから計算できない場合は全ての足を計算し直して状態を作成する
+// incrementalMode が verify の場合は全ての足の再計算と比較し、許容誤差を超える場合はエラーとする
+func calculateTechnicalIndexIncremental(code string, stockData []StockBrandInformation) ([]StockBrandInformation, error) {
+
+	added, state, cache := resumeIndexState(code, stockData)
+	if state == nil {
+		slog.Info("IndexState Rebuild", "code", code, "bars", len(stockData))
+		stockData = calculateTechnicalIndex(stockData)
+		state = newIndexState()
+		for i := len(stockData) - 1; i >= 0; i-- {
+			state.next(stockData[i])
+		}
+	} else {
+		slog.Info("IndexState Resume", "code", code, "bars", state.Bars, "added", added)
+		for i := added - 1; i >= 0; i-- {
+			stockData[i].Index = state.next(stockData[i])
+		}
+		for i := added; i < len(stockData); i++ {
+			stockData[i].Index = cache[formatCsvDate(stockData[i].ParseDate)]
+		}
+	}
+
+	if incrementalMode == IncrementalVerify {
+		full := calculateTechnicalIndex(slices.Clone(stockData))
+		if err := compareTechnicalIndex(code, stockData, full); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeIndexState(code, state, stockData); err != nil {
+		return nil, err
+	}
+	return stockData, nil
+}
+
+// 逐次計算したテクニカル指標を全ての足の再計算の結果と比較する
+// 差は再計算の値の絶対値(1未満は1)に対する相対誤差とし、indexTolerance を超える値があればカラム毎の最大の差をエラーとして返す
+func compareTechnicalIndex(code string, incremental []StockBrandInformation, full []StockBrandInformation) error {
+
+	const indexTolerance = 1e-8
+	worst := make(map[string]float64) // カラム毎の最大の差
+	mismatches := 0
+	for i := range full {
+		for key, want := range full[i].Index {
+			got, ok := incremental[i].Index[key]
+			if ok && (got == want || (math.IsNaN(got) && math.IsNaN(want))) {
+				continue
+			}
+			diff := math.Inf(1) // カラムがない、片方のみ NaN・無限大の場合
+			if ok && !math.IsNaN(got-want) && !math.IsInf(got-want, 0) {
+				diff = math.Abs(got-want) / max(1, math.Abs(want))
+			}
+			if diff > indexTolerance {
+				mismatches++
+			}
+			worst[key] = max(worst[key], diff)
+		}
+	}
+
+	keys := slices.SortedFunc(maps.Keys(worst), func(a, b string) int {
+		return cmp.Compare(worst[b], worst[a])
+	})
+	if mismatches == 0 {
+		maxDiff := 0.0
+		if len(keys) > 0 {
+			maxDiff = worst[keys[0]]
+		}
+		slog.Info("IndexState Verified", "code", code, "bars", len(full), "maxdiff", maxDiff)
+		return nil
+	}
+	var details []string
+	for _, key := range keys[:min(len(keys), 5)] {
+		details = append(details, fmt.Sprintf("%s=%g", key, worst[key]))
+	}
+	return fmt.Errorf("incremental index differs from full recomputation: %d values (worst: %s)", mismatches, strings.Join(details, ", "))
+}
+
+// arimaEngine の方法で RawData の csv ファイルからARIMA予測結果を取得する
+func arimaPrediction(csvfile string) ([]ArimaPredictionResultInformation, error) {
+
+	switch arimaEngine {
+	case ArimaPython:
+		// arima_insights.py の次数は ARIMA(1,0,1) 固定
+		if isArimaAuto == true || arimaOrder != (arima.Order{P: 1, D: 0, Q: 1}) {
+			return nil, fmt.Errorf("-arima python supports only -arima-order 1,0,1 (use -arima go or compare)")
+		}
+		return arimaPredictionPython(csvfile)
+	case ArimaCompare:
+		result, err := arimaPredictionGo(csvfile)
+		if err != nil {
+			return nil, err
+		}
+		if pythonResult, errPython := arimaPredictionPython(csvfile); errPython != nil {
+			slog.Info("ARIMA Compare Skip", "file", csvfile, "err", errPython)
+		} else {
+			compareArimaPrediction(csvfile, result, pythonResult)
+		}
+		return result, nil
+	}
+	return arimaPredictionGo(csvfile)
+}
+
+// ARIMA予測を forecast.ARIMA (arima パッケージ)で計算する(arima_insights.py と同じ手順)
+// 日付昇順に並べて休日を含む暦日の終値を線形補間し、前日差分の系列に ARIMA(arimaOrder) を当てはめる
+// 6日目以降の各日付について、前日までの差分で推定したモデルの1期先の予測を前日の終値に加えて予測値とする
+// -arima-order auto の場合は起点(前日)までの差分の系列で選択した次数を用い、-arima-reselect 本毎に選び直す
+// 選択した次数は期間毎に保存し、次回以降は同じ期間の次数を再利用する(resolveArimaOrder)
+func arimaPredictionGo(csvfile string) ([]ArimaPredictionResultInformation, error) {
+
+	stockData, isNotExist := readCSVInsertData(csvfile, false)
+	if isNotExist == true || len(stockData) == 0 {
+		return nil, fmt.Errorf("raw data not found. file=%s", csvfile)
+	}
+
+	dates, closing := calendarClosing(stockData)
+	var f forecast.Forecaster = forecast.NewARIMA(arimaOrder)
+	if isArimaAuto == true {
+		sidecarFile := filepath.Join(filepath.Dir(csvfile), ArimaOrderFileName)
+		auto := forecast.NewAutoARIMA(arimaSearch, arimaReselect)
+		auto.Selector = func(diff []float64) (arima.Order, error) {
+			return resolveArimaOrder(sidecarFile, dates[:len(diff)], diff)
+		}
+		f = auto
+	}
+
+	var result []ArimaPredictionResultInformation
+	for i, p := range rollingForecast(f, dates, closing) {
+		if math.IsNaN(p.Value) {
+			continue
+		}
+		result = append(result, ArimaPredictionResultInformation{
+			Date:                    dates[i].Format("2006-01-02T15:04:05.000"),
+			ParseDate:               dates[i],
+			Closing:                 closing[i],
+			Arima_Diff_Prediction:   p.Value - closing[i-1],
+			Arima_Actual_Prediction: p.Value,
+			Prediction_Difference:   closing[i] - p.Value,
+		})
+	}
+	return result, nil
+}
+
+// 日付降順の足から休日を含む暦日の日付と終値(日付昇順)を求める。取引のない日は前後の終値を線形補間する
+func calendarClosing(stockData []StockBrandInformation) ([]time.Time, []float64) {
+
+	oldest, latest := stockData[len(stockData)-1], stockData[0]
+	days := int(latest.ParseDate.Sub(oldest.ParseDate).Hours()/24+0.5) + 1
+	dates := make([]time.Time, days)
+	closing := make([]float64, days)
+	for i := range closing {
+		dates[i] = oldest.ParseDate.AddDate(0, 0, i)
+		closing[i] = math.NaN()
+	}
+	for _, c := range stockData {
+		closing[int(c.ParseDate.Sub(oldest.ParseDate).Hours()/24+0.5)] = c.Closing
+	}
+	for i, known := 0, 0; i < days; i++ {
+		if math.IsNaN(closing[i]) == false {
+			known = i
+			continue
+		}
+		next := i + 1
+		for math.IsNaN(closing[next]) {
+			next++
+		}
+		closing[i] = closing[known] + (closing[next]-closing[known])*float64(i-known)/float64(next-known)
+	}
+	return dates, closing
+}
+
+// 日付降順の足から取引のある日の日付と終値(日付昇順)を求める
+func tradingClosing(stockData []StockBrandInformation) ([]time.Time, []float64) {
+
+	dates := make([]time.Time, len(stockData))
+	closing := make([]float64, len(stockData))
+	for i, c := range stockData {
+		dates[len(stockData)-1-i] = c.ParseDate
+		closing[len(stockData)-1-i] = c.Closing
+	}
+	return dates, closing
+}
+
+// 日付昇順の終値の系列の6番目以降の各日付について、前の日付までの系列に当てはめた予測モデルの1期先の予測を求める
+// 返り値は dates と同じ長さで、予測できない日付(当てはめに失敗した日付、5番目まで)の予測値は NaN とする
+func rollingForecast(f forecast.Forecaster, dates []time.Time, closing []float64) []forecast.Prediction {
+
+	predictions := make([]forecast.Prediction, len(dates))
+	for i := range predictions {
+		predictions[i] = forecast.Prediction{Value: math.NaN(), StdDev: math.NaN()}
+	}
+	for i := 5; i < len(dates)-1; i++ {
+		if err := f.Fit(closing[:i+1]); err != nil {
+			slog.Info("Forecast Fit Skip", "model", f.Name(), "date", formatCsvDate(dates[i+1]), "err", err)
+			continue
+		}
+		predictions[i+1] = f.Predict(1)[0]
+	}
+	return predictions
+}
+
+// -forecast の予測モデル毎に RawData の csv ファイルから予測のカラムを求める
+// arima は -arima の方法で計算した結果(arimaPredictor)を、それ以外は取引のある日の終値の系列で前の足までに当てはめて予測した結果を用いる
+// (暦日の系列は休日の終値を翌営業日の終値で補間するため、休日明けの足の予測にその足の終値が入ってしまう)
+func forecastModelColumns(csvfile string) ([]ForecastColumns, error) {
+
+	var forecasts []ForecastColumns
+	var dates []time.Time
+	var closing []float64
+	for _, model := range forecastModels {
+		if model == ForecastARIMA {
+			arimaPredictionResult, errArima := arimaPredictor(csvfile)
+			if errArima != nil {
+				slog.Info("ARIMA Prediction Err.", "error", errArima)
+				return nil, errArima
+			}
+			columns := ForecastColumns{Names: forecast.NewARIMA(arimaOrder).ColumnNames(), Values: make(map[string][]float64)}
+			for _, c := range arimaPredictionResult {
+				columns.Values[formatCsvDate(c.ParseDate)] = []float64{c.Arima_Actual_Prediction, c.Prediction_Difference}
+			}
+			forecasts = append(forecasts, columns)
+			continue
+		}
+
+		f, err := newForecaster(model)
+		if err != nil {
+			return nil, err
+		}
+		if dates == nil {
+			stockData, isNotExist := readCSVInsertData(csvfile, false)
+			if isNotExist == true || len(stockData) == 0 {
+				return nil, fmt.Errorf("raw data not found. file=%s", csvfile)
+			}
+			dates, closing = tradingClosing(stockData)
+		}
+		columns := ForecastColumns{Names: f.ColumnNames(), Values: make(map[string][]float64)}
+		for i, p := range rollingForecast(f, dates, closing) {
+			if math.IsNaN(p.Value) == false {
+				columns.Values[formatCsvDate(dates[i])] = f.ColumnValues(p, closing[i-1], closing[i])
+			}
+		}
+		slog.Info("Forecast", "model", f.Name(), "file", csvfile, "predictions", len(columns.Values))
+		forecasts = append(forecasts, columns)
+	}
+
+	if len(forecastHorizons) > 0 {
+		columns, err := horizonForecastColumns(csvfile)
+		if err != nil {
+			return nil, err
+		}
+		forecasts = append(forecasts, columns)
+	}
+	return forecasts, nil
+}
+
+// -horizon-model の予測モデルで、各足について前の足までのデータから -horizons の営業日先の予測値と予測区間を求める
+// 1営業日先は通常その足の日付の予測となる。予測区間は予測誤差を正規分布として -interval の信頼水準で求める
+func horizonForecastColumns(csvfile string) (ForecastColumns, error) {
+
+	stockData, isNotExist := readCSVInsertData(csvfile, false)
+	if isNotExist == true || len(stockData) == 0 {
+		return ForecastColumns{}, fmt.Errorf("raw data not found. file=%s", csvfile)
+	}
+	f, err := newModelForecaster(horizonModel)
+	if err != nil {
+		return ForecastColumns{}, err
+	}
+
+	columns := ForecastColumns{Values: make(map[string][]float64)}
+	for _, h := range forecastHorizons {
+		name := fmt.Sprintf("Forecast_h%d", h)
+		columns.Names = append(columns.Names, name, name+"_lo", name+"_hi")
+	}
+	z := math.Sqrt2 * math.Erfinv(intervalLevel)
+	for date, predictions := range horizonPredictions(f, stockData, forecastHorizons) {
+		var values []float64
+		for _, p := range predictions {
+			values = append(values, p.Prediction.Value, p.Prediction.Value-z*p.Prediction.StdDev, p.Prediction.Value+z*p.Prediction.StdDev)
+		}
+		columns.Values[date] = values
+	}
+	slog.Info("Forecast Horizons", "model", f.Name(), "file", csvfile, "horizons", forecastHorizons, "predictions", len(columns.Values))
+	return columns, nil
+}
+
+// 各足(日付降順の stockData)について、前の足を起点に horizons の営業日先を予測する(返り値の key は足の日付、値は horizons の順)
+// 前の足の日付までの暦日の終値の系列(休日の補間も前の足までの足のみを用いる)に当てはめるため、足の日付以降のデータは予測に用いない
+func horizonPredictions(f forecast.Forecaster, stockData []StockBrandInformation, horizons []int) map[string][]HorizonPrediction {
+
+	dates, closing := calendarClosing(stockData)
+	isTradingDay := resample.IsTradingDay
+	if nowObtain == Forex {
+		isTradingDay = resample.IsWeekday
+	}
+	maxHorizon := slices.Max(horizons)
+	result := make(map[string][]HorizonPrediction)
+	for k := len(stockData) - 2; k >= 0; k-- {
+		origin := int(stockData[k+1].ParseDate.Sub(dates[0]).Hours()/24 + 0.5)
+		if origin < 5 {
+			continue
+		}
+		if err := f.Fit(closing[:origin+1]); err != nil {
+			slog.Info("Forecast Fit Skip", "model", f.Name(), "date", formatCsvDate(stockData[k].ParseDate), "err", err)
+			continue
+		}
+		// 前の足の日付から n 営業日先の日付
+		var targets []time.Time
+		for day := dates[origin]; len(targets) < maxHorizon; {
+			day = day.AddDate(0, 0, 1)
+			if isTradingDay(day) == true {
+				targets = append(targets, day)
+			}
+		}
+		predictions := f.Predict(int(targets[maxHorizon-1].Sub(dates[origin]).Hours()/24 + 0.5))
+		var row []HorizonPrediction
+		for _, h := range horizons {
+			steps := int(targets[h-1].Sub(dates[origin]).Hours()/24 + 0.5)
+			row = append(row, HorizonPrediction{
+				Origin:      stockData[k+1].ParseDate,
+				OriginClose: stockData[k+1].Closing,
+				Target:      targets[h-1],
+				Prediction:  predictions[steps-1],
+			})
+		}
+		result[formatCsvDate(stockData[k].ParseDate)] = row
+	}
+	return result
+}
+
+// 起点毎に当てはめる予測モデルを作成する。arima は -arima-order の次数とする
+// auto の場合は起点までの系列の差分で次数を選択し、-arima-reselect 本毎に選び直す(起点より後のデータは次数の選択に用いない)
+func newModelForecaster(model ForecastModel) (forecast.Forecaster, error) {
+	if model != ForecastARIMA {
+		return newForecaster(model)
+	}
+	if isArimaAuto == true {
+		return forecast.NewAutoARIMA(arimaSearch, arimaReselect), nil
+	}
+	return forecast.NewARIMA(arimaOrder), nil
+}
+
+// ARIMA 以外の予測モデルを作成する(銘柄毎に作成する)
+func newForecaster(model ForecastModel) (forecast.Forecaster, error) {
+	switch model {
+	case ForecastHoltWinters:
+		return forecast.NewHoltWinters(holtWintersPeriod)
+	case ForecastGARCH:
+		return forecast.NewGARCH(), nil
+	case ForecastNaive:
+		return forecast.NewNaive(), nil
+	case ForecastDrift:
+		return forecast.NewDrift(), nil
+	}
+	return nil, fmt.Errorf("unknown forecast model %q", model)
+}
+
+// 保存した自動選択の次数のうち、同じ期間(dates の最古日付〜最新日付)の系列で選択した次数を読み込む
+// ない場合、探索範囲が変わった場合、-arima-refresh の場合は選択し直して保存する。最古日付の異なる期間の次数は削除する
+// dates は差分の系列 diff の各要素の日付
+func resolveArimaOrder(sidecarFile string, dates []time.Time, diff []float64) (arima.Order, error) {
+
+	// 読み込めないファイルは探索範囲が空となり、選択し直す
+	from, to := formatCsvDate(dates[0]), formatCsvDate(dates[len(dates)-1])
+	var sidecar ArimaOrderSidecar
+	if fileio.FileIoJsonRead(sidecarFile, &sidecar) != nil || sidecar.Settings != arimaSearch.String() {
+		sidecar = ArimaOrderSidecar{Settings: arimaSearch.String()}
+	}
+	sidecar.Windows = slices.DeleteFunc(sidecar.Windows, func(w ArimaOrderWindow) bool { return w.DataFrom != from })
+	index := slices.IndexFunc(sidecar.Windows, func(w ArimaOrderWindow) bool { return w.DataTo == to })
+	if isArimaRefresh == false && index >= 0 {
+		window := sidecar.Windows[index]
+		slog.Info("ARIMA Order Reuse", "file", sidecarFile, "datato", to, "order", window.Selection.Order.String(), "selectedat", window.SelectedAt)
+		return window.Selection.Order, nil
+	}
+
+	selection, err := arima.AutoSelect(diff, arimaSearch)
+	if err != nil {
+		return arima.Order{}, fmt.Errorf("auto ARIMA: %w", err)
+	}
+	window := ArimaOrderWindow{
+		SelectedAt: time.Now(),
+		DataFrom:   from,
+		DataTo:     to,
+		Selection:  *selection,
+	}
+	if index >= 0 {
+		sidecar.Windows[index] = window
+	} else {
+		sidecar.Windows = append(sidecar.Windows, window)
+		slices.SortStableFunc(sidecar.Windows, func(a, b ArimaOrderWindow) int { return strings.Compare(a.DataTo, b.DataTo) })
+	}
+	diagnostics := selection.Diagnostics
+	slog.Info("ARIMA Order Selected", "file", sidecarFile, "datato", to, "order", selection.Order.String(), "criterion", selection.Criterion,
+		"aic", diagnostics.AIC, "bic", diagnostics.BIC, "ljungbox_p", diagnostics.LjungBox.PValue, "candidates", len(selection.Candidates))
+	if err := fileio.FileIoJsonWrite(sidecarFile, sidecar, false); err != nil {
+		return arima.Order{}, err
+	}
+	return selection.Order, nil
+}
+
+// arima パッケージと arima_insights.py の予測値の差(終値に対する割合)の最大値と平均値をログに出力する
+func compareArimaPrediction(csvfile string, result []ArimaPredictionResultInformation, pythonResult []ArimaPredictionResultInformation) {
+
+	pythonPrediction := make(map[string]float64, len(pythonResult))
+	for _, r := range pythonResult {
+		pythonPrediction[formatCsvDate(r.ParseDate)] = r.Arima_Actual_Prediction
+	}
+	maxDiff, sumDiff, count, worst := 0.0, 0.0, 0, ""
+	for _, r := range result {
+		want, ok := pythonPrediction[formatCsvDate(r.ParseDate)]
+		if ok == false || r.Closing == 0 {
+			continue
+		}
+		diff := math.Abs(r.Arima_Actual_Prediction-want) / math.Abs(r.Closing)
+		sumDiff += diff
+		count++
+		if diff > maxDiff {
+			maxDiff, worst = diff, formatCsvDate(r.ParseDate)
+		}
+	}
+	if count == 0 {
+		slog.Info("ARIMA Compare", "file", csvfile, "matched", 0)
+		return
+	}
+	slog.Info("ARIMA Compare", "file", csvfile, "matched", count, "go", len(result), "python", len(pythonResult),
+		"maxDiff", maxDiff, "maxDiffDate", worst, "meanDiff", sumDiff/float64(count))
+}
+
+// ARIMA予測モデルのpythonファイルを実行し予測結果を取得する
+func arimaPredictionPython(csvfile string) ([]ArimaPredictionResultInformation, error) {
+
+	// Pythonスクリプトのコマンドを構築
+	cmd := exec.Command("python", "arima_insights.py", csvfile)
+	// コマンド実行と結果取得
+	var stdOutBuff, stdErrBuff bytes.Buffer
+	cmd.Stdout = &stdOutBuff
+	cmd.Stderr = &stdErrBuff
+	err := cmd.Run()
+	if err != nil {
+		slog.Info("err cmd Run.", "err", err)
+		return nil, err
+	}
+	return parseArimaPredictionJson(stdOutBuff.Bytes())
+}
+
+// arima_insights.py が出力した JSON を予測結果に変換する
+func parseArimaPredictionJson(stdOut []byte) ([]ArimaPredictionResultInformation, error) {
+
+	// JSON -> 構造体へ
+	var arimaPredictResult []ArimaPredictionResultInformation
+	err := json.Unmarshal(stdOut, &arimaPredictResult)
+	if err != nil {
+		slog.Info("err Json Convert.", "err", err)
+		return nil, err
+	}
+
+	// time.Time を求める
+	for i := 0; i < len(arimaPredictResult); i++ {
+
+		// フォーマットを指定
+		layout := "2006-01-02T15:04:05.000"
+		// パース
+		arimaPredictResult[i].ParseDate, err = time.Parse(layout, arimaPredictResult[i].Date)
+		if err != nil {
+			slog.Info("Error parsing time", "err", err)
+			return nil, err
+		}
+	}
+	return arimaPredictResult, nil
+}
+
+// 銘柄ファイルのパスを返す
+func stockFilePath(code string, fileName string) string {
+	return filepath.Join(resourceDir, code, fileName)
+}
+
+// 日付が対象期間(dateFrom〜dateTo)に含まれるかを判定する
+func isInDateRange(date time.Time) bool {
+	if !dateFrom.IsZero() && date.Before(dateFrom) {
+		return false
+	}
+	if !dateTo.IsZero() && date.After(dateTo) {
+		return false
+	}
+	return true
+}
+
+// time.Time を CSV 出力用の yyyy/mm/dd 文字列に変換する
+func formatCsvDate(date time.Time) string {
+	return date.Format("2006/01/02")
+}
+
+// 該当銘柄の日足を取得元から取得し、RawDataのcsvファイルにマージして出力する
+func fetchOneStockBrand(code string) error {
+
+	rawCsvFileName := stockFilePath(code, RawDataFileName)
+	synthesisStockData, isInitialCreation := readCSVInsertData(rawCsvFileName, true)
+	slog.Info("File Component", "len", len(synthesisStockData))
+
+	// 取得元から日足を取得し、csvファイルから読みこんだデータとマージしたStockBrandInformationを作成
+	synthesisStockData, err := getIntegrateData(code, isInitialCreation, synthesisStockData)
+	if err != nil {
+		return err
+	}
+	if len(synthesisStockData) == 0 {
+		return fmt.Errorf("no data obtained. code=%s", code)
+	}
+
+	if isInitialCreation == true {
+		if err := os.MkdirAll(filepath.Dir(rawCsvFileName), 0755); err != nil {
+			return err
+		}
+	}
+	return writeRawCsv(rawCsvFileName, synthesisStockData)
+}
+
+// バックフィルの出力先ファイル名を返す(日足は RawData.csv にマージする)
+func backfillFileName(bar datasource.BarType) string {
+	switch bar {
+	case datasource.BarWeekly:
+		return "RawData_weekly.csv"
+	case datasource.BarMonthly:
+		return "RawData_monthly.csv"
+	}
+	return RawDataFileName
+}
+
+// 該当銘柄の過去データを backfillStart まで遡って取得し、csvファイルにマージする
+// ページ毎にcsvファイルとチェックポイントを保存するため、中断しても次回は続きから再開する
+func backfillOneStockBrand(code string) error {
+
+	kabutan, ok := dataSource.(*datasource.Kabutan)
+	if !ok {
+		return fmt.Errorf("backfill requires -source kabutan")
+	}
+	if backfillStart.IsZero() {
+		return fmt.Errorf("backfill requires -start")
+	}
+	if err := os.MkdirAll(filepath.Join(resourceDir, code), 0755); err != nil {
+		return err
+	}
+
+	for _, bar := range backfillBars {
+		csvFileName := stockFilePath(code, backfillFileName(bar))
+		checkpointFileName := stockFilePath(code, fmt.Sprintf("Backfill_%s.json", bar))
+
+		// チェックポイントが同じ開始日のものであれば続きから再開する
+		var checkpoint BackfillCheckpoint
+		startPage := 1
+		if isBackfillRestart == false && fileio.FileIoJsonRead(checkpointFileName, &checkpoint) == nil && checkpoint.Start.Equal(backfillStart) {
+			if checkpoint.Completed == true {
+				slog.Info("Backfill Already", "code", code, "bar", bar, "oldest", checkpoint.Oldest)
+				continue
+			}
+			startPage = checkpoint.NextPage
+		}
+		checkpoint = BackfillCheckpoint{Code: code, Bar: string(bar), Start: backfillStart, NextPage: startPage, Oldest: checkpoint.Oldest}
+		slog.Info("Backfill Start", "code", code, "bar", bar, "start", backfillStart, "page", startPage)
+
+		stockData, _ := readCSVInsertData(csvFileName, false)
+		err := kabutan.Backfill(code, bar, backfillStart, startPage, func(page int, prices []datasource.DailyPrice) error {
+			stockData = csvMergeOneStockBrand(toStockBrandInformation(prices), stockData)
+			sort.Slice(stockData, func(i, j int) bool {
+				return stockData[i].ParseDate.After(stockData[j].ParseDate)
+			})
+			if err := writeRawCsv(csvFileName, stockData); err != nil {
+				return err
+			}
+			checkpoint.NextPage = page + 1
+			if len(prices) > 0 {
+				checkpoint.Oldest = prices[len(prices)-1].Date
+			}
+			checkpoint.UpdatedAt = time.Now()
+			return fileio.FileIoJsonWrite(checkpointFileName, checkpoint, false)
+		})
+		if err != nil {
+			return fmt.Errorf("backfill %s interrupted at page %d (rerun to resume): %w", bar, checkpoint.NextPage, err)
+		}
+
+		checkpoint.Completed = true
+		checkpoint.UpdatedAt = time.Now()
+		if err := fileio.FileIoJsonWrite(checkpointFileName, checkpoint, false); err != nil {
+			return err
+		}
+		slog.Info("Backfill Completed", "code", code, "bar", bar, "rows", len(stockData), "oldest", checkpoint.Oldest)
+	}
+	return nil
+}
+
+// 基本データ(日付、四本値、出来高)をcsvファイルに出力する
+func writeRawCsv(rawCsvFileName string, stockData []StockBrandInformation) error {
+
+	var outputStr [][]string
+	var rawLineStr []string = []string{"date", "opening", "high", "low", "closing", "volume"}
+	outputStr = append(outputStr, rawLineStr)
+	for _, c := range stockData {
+		lineStr := []string{formatCsvDate(c.ParseDate), strconv.FormatFloat(c.Opening, 'f', 5, 64), strconv.FormatFloat(c.High, 'f', 5, 64), strconv.FormatFloat(c.Low, 'f', 5, 64),
+			strconv.FormatFloat(c.Closing, 'f', 5, 64), strconv.FormatFloat(c.Volume, 'f', 5, 64),
+		}
+		outputStr = append(outputStr, lineStr)
+	}
+	return fileio.FileIoCsvWrite(rawCsvFileName, outputStr, false)
+}
+
+// 該当銘柄のRawDataからテクニカル指標、ARIMA予測を計算しModelDataのcsvファイルに出力する
+func buildModelOneStockBrand(code string, cData []CommonInformation) error {
+
+	// RawDataのcsvファイルを読み込んでStockBrandInformationに展開
+	// モデル用にテクニカル指標を付加したファイルをModelDataに出力
+	rawCsvFileName := stockFilePath(code, RawDataFileName)
+	modelCsvFileName := stockFilePath(code, ModelDataFileName)
+	synthesisStockData, isNotExist := readCSVInsertData(rawCsvFileName, false)
+	if isNotExist == true {
+		return fmt.Errorf("raw data not found. file=%s", rawCsvFileName)
+	}
+
+	// 移動平均、ボラティリティの計算(-incremental が on / verify の場合は前回から追加された足のみ計算する)
+	if incrementalMode == IncrementalOff {
+		synthesisStockData = calculateTechnicalIndex(synthesisStockData)
+	} else {
+		var errIndex error
+		if synthesisStockData, errIndex = calculateTechnicalIndexIncremental(code, synthesisStockData); errIndex != nil {
+			return errIndex
+		}
+	}
+
+	// 予測モデル計算(-forecast の予測モデル毎にカラムを出力する)
+	forecasts, errForecast := forecastModelColumns(rawCsvFileName)
+	if errForecast != nil {
+		return errForecast
+	}
+
+	// CSVに出力するように文字列に変換してModelDataに出力
+	outputStr := createModelData(code, synthesisStockData, cData, forecasts)
+	slog.Info("Final Component", "Data", len(synthesisStockData), "output", len(outputStr))
+	if err := fileio.FileIoCsvWrite(modelCsvFileName, outputStr, false); err != nil {
+		return err
+	}
+
+	// 週足・月足に変換して同じテクニカル指標を計算し、ModelData_weekly / ModelData_monthly に出力
+	// (ARIMA予測は日足のみのため出力しない)
+	for _, period := range resamplePeriods {
+		periodStockData := resampleStockData(synthesisStockData, period)
+		if len(periodStockData) < requiredBars() {
+			slog.Info("Resample Shortage", "code", code, "period", period, "bars", len(periodStockData))
+		}
+		periodStockData = calculateTechnicalIndex(periodStockData)
+		outputStr = createModelData(code, periodStockData, cData, nil)
+		slog.Info("Final Component", "period", period, "Data", len(periodStockData), "output", len(outputStr))
+		if err := fileio.FileIoCsvWrite(stockFilePath(code, modelFileName(period)), outputStr, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 足の期間に対応するModelDataのファイル名を返す
+func modelFileName(period resample.Period) string {
+	return fmt.Sprintf("ModelData_%s.csv", period)
+}
+
+// 日足のStockBrandInformationを period の足に変換する(テクニカル指標は未計算)
+// 株式は日本の取引所の営業日、為替は平日のデータのみを対象とする
+func resampleStockData(stockData []StockBrandInformation, period resample.Period) []StockBrandInformation {
+
+	isTradingDay := resample.IsTradingDay
+	if nowObtain == Forex {
+		isTradingDay = resample.IsWeekday
+	}
+	var daily []resample.Bar
+	for _, c := range stockData {
+		daily = append(daily, resample.Bar{Date: c.ParseDate, Opening: c.Opening, High: c.High, Low: c.Low, Closing: c.Closing, Volume: c.Volume})
+	}
+	var periodStockData []StockBrandInformation
+	for _, b := range resample.Resample(daily, period, isTradingDay) {
+		periodStockData = append(periodStockData, StockBrandInformation{ParseDate: b.Date, Opening: b.Opening, High: b.High, Low: b.Low, Closing: b.Closing, Volume: b.Volume})
+	}
+	return periodStockData
+}
+
+// ModelDataに出力するテクニカル指標のカラム名(StockBrandInformation.Indexのキー)を出力順に返す
+// 出来高の指標(株式のみ出力)と価格の指標に分けて返す
+func modelIndexColumns() ([]string, []string) {
+	volumeColumns := append([]string{"VCR"}, termKeys(volumeTermIndexNames)...)
+	volumeColumns = append(volumeColumns, volumeFlowIndexNames...)
+	volumeColumns = append(volumeColumns, termKeys(volumeFlowTermIndexNames)...)
+
+	indexColumns := termKeys(termIndexNames)
+	for _, def := range macdDefinitions {
+		for _, name := range macdIndexNames {
+			indexColumns = append(indexColumns, def.Name+name)
+		}
+	}
+	indexColumns = append(indexColumns, termKeys(bbandIndexNames)...)
+	indexColumns = append(indexColumns, termKeys(oscillatorIndexNames)...)
+	indexColumns = append(indexColumns, termKeys(trendIndexNames)...)
+	indexColumns = append(indexColumns, sarIndexNames...)
+	for _, p := range candle.Patterns {
+		indexColumns = append(indexColumns, p.Name)
+	}
+	indexColumns = append(indexColumns, ichimokuIndexNames...)
+	return volumeColumns, indexColumns
+}
+
+// テクニカル指標を計算済みのStockBrandInformationをModelDataのcsv出力用の文字列に変換する
+// 日付フォーマットを time.DateTime から　yyyy/mm/dd へ変更する。forecasts の予測モデル毎に予測のカラムを出力し、予測のない日付は 0 とする
+func createModelData(code string, synthesisStockData []StockBrandInformation, cData []CommonInformation, forecasts []ForecastColumns) [][]string {
+
+	var outputStr [][]string
+	var lineStr []string = []string{"date", "DayOfWeek", "opening", "high", "low", "closing"}
+	volumeColumns, indexColumns := modelIndexColumns()
+	var lineSubStr []string = slices.Clone(indexColumns)
+	for _, f := range forecasts {
+		lineSubStr = append(lineSubStr, f.Names...)
+	}
+	if nowObtain != Forex {
+		lineStr = append(lineStr, "volume")
+		lineStr = append(lineStr, volumeColumns...)
+		lineStr = append(lineStr, "InterestRateate", "UnemployRateate", "CPI", "GDP", "Tankan")
+	} else {
+		if code == "0970" {
+			// ユーロドル
+			lineStr = append(lineStr, "InterestRateate(USA)", "InterestRateate(EURO)", "UnemployRateate(USA)", "UnemployRateate(EURO)",
+				"CPI(USA)", "CPI(EURO)", "GDP(USA)", "GDP(EURO)")
+		} else if code == "0952" {
+			// ポンド円
+			lineStr = append(lineStr, "InterestRateate(UK)", "InterestRateate(JPN)", "UnemployRateate(UK)", "UnemployRateate(JPN)",
+				"CPI(UK)", "CPI(JPN)", "GDP(UK)", "GDP(JPN)")
+		}
+	}
+	lineStr = append(lineStr, lineSubStr...)
+	//		var nowObtain = Forex
+	outputStr = append(outputStr, lineStr)
+	for i, c := range synthesisStockData {
+
+		// Nanが発生してしまうデータを出力しない
+		// 最長期間(既定は30日)の移動平均でデータ数が期間未満だとNaNが発生してしまう
+		// MACDシグナルを計算するために、さらにシグナルの期間(9)分のデータがないとNanが発生する
+		// 一目均衡表の先行スパンBは 先行スパンBの期間(既定は52)の値を基準線の期間(26)ずらすため、さらに長い期間が必要
+		if i > len(synthesisStockData)-requiredBars() {
+			break
 		}
-		dateStr := c.ParseDate.Format(time.DateTime)
-		dateSlice := strings.Split(dateStr, " ")
-		dateSlice[0] = strings.ReplaceAll(dateSlice[0], "-", "/")
 
-		lineStr = append(lineStr, dateSlice[0], strconv.Itoa(int(c.ParseDate.Weekday())), strconv.FormatFloat(c.Opening, 'f', 5, 64), strconv.FormatFloat(c.High, 'f', 5, 64), strconv.FormatFloat(c.Low, 'f', 5, 64), strconv.FormatFloat(c.Closing, 'f', 5, 64))
+		if !isInDateRange(c.ParseDate) {
+			continue
+		}
+
+		lineStr = nil
+		commonInfo := getCommonInformation(cData, c.ParseDate)
+		lineStr = append(lineStr, formatCsvDate(c.ParseDate), strconv.Itoa(int(c.ParseDate.Weekday())), strconv.FormatFloat(c.Opening, 'f', 5, 64), strconv.FormatFloat(c.High, 'f', 5, 64), strconv.FormatFloat(c.Low, 'f', 5, 64), strconv.FormatFloat(c.Closing, 'f', 5, 64))
 		if nowObtain != Forex {
-			lineStr = append(lineStr, strconv.FormatFloat(c.Volume, 'f', 5, 64),
-				strconv.FormatFloat(c.VolumeChangeRate, 'f', 5, 64),
-				strconv.FormatFloat(c.VolumeMovingAve[Term5], 'f', 5, 64), strconv.FormatFloat(c.VolumeMovingAve[Term14], 'f', 5, 64), strconv.FormatFloat(c.VolumeMovingAve[Term30], 'f', 5, 64),
-				strconv.FormatFloat(c.VolumeRatio[Term5], 'f', 5, 64), strconv.FormatFloat(c.VolumeRatio[Term14], 'f', 5, 64), strconv.FormatFloat(c.VolumeRatio[Term30], 'f', 5, 64),
-				strconv.FormatFloat(c.VolumeEMA[Term5], 'f', 5, 64), strconv.FormatFloat(c.VolumeEMA[Term14], 'f', 5, 64), strconv.FormatFloat(c.VolumeEMA[Term30], 'f', 5, 64),
-				strconv.FormatFloat(c.VolumeMADRate[Term5], 'f', 5, 64), strconv.FormatFloat(c.VolumeMADRate[Term14], 'f', 5, 64), strconv.FormatFloat(c.VolumeMADRate[Term30], 'f', 5, 64),
+			lineStr = append(lineStr, strconv.FormatFloat(c.Volume, 'f', 5, 64))
+			for _, key := range volumeColumns {
+				lineStr = append(lineStr, strconv.FormatFloat(c.Index[key], 'f', 5, 64))
+			}
+			lineStr = append(lineStr,
 				strconv.FormatFloat(commonInfo.InterestRateJpn, 'f', 5, 64), strconv.FormatFloat(commonInfo.UnemployRateJpn, 'f', 5, 64),
 				strconv.FormatFloat(commonInfo.CpiJpn, 'f', 5, 64), strconv.FormatFloat(commonInfo.GdpJpn, 'f', 5, 64), strconv.FormatFloat(commonInfo.Tankan, 'f', 5, 64))
 		} else {
@@ -867,51 +1823,697 @@ func csvCreationOneStockBrand(code string, cData []CommonInformation) {
 				)
 			}
 		}
-		lineStr = append(lineStr, strconv.FormatFloat(c.MovingAve[Term5], 'f', 5, 64), strconv.FormatFloat(c.MovingAve[Term14], 'f', 5, 64), strconv.FormatFloat(c.MovingAve[Term30], 'f', 5, 64),
-			strconv.FormatFloat(c.EMA[Term5], 'f', 5, 64), strconv.FormatFloat(c.EMA[Term14], 'f', 5, 64), strconv.FormatFloat(c.EMA[Term30], 'f', 5, 64),
-			strconv.FormatFloat(c.Volatility[Term5], 'f', 5, 64), strconv.FormatFloat(c.Volatility[Term14], 'f', 5, 64), strconv.FormatFloat(c.Volatility[Term30], 'f', 5, 64),
-			strconv.FormatFloat(c.HighLowVolatility[Term5], 'f', 5, 64), strconv.FormatFloat(c.HighLowVolatility[Term14], 'f', 5, 64), strconv.FormatFloat(c.HighLowVolatility[Term30], 'f', 5, 64),
-			strconv.FormatFloat(c.ATR[Term5], 'f', 5, 64), strconv.FormatFloat(c.ATR[Term14], 'f', 5, 64), strconv.FormatFloat(c.ATR[Term30], 'f', 5, 64),
-			strconv.FormatFloat(c.MADRate[Term5], 'f', 5, 64), strconv.FormatFloat(c.MADRate[Term14], 'f', 5, 64), strconv.FormatFloat(c.MADRate[Term30], 'f', 5, 64),
-			strconv.FormatFloat(c.RSI[Term5], 'f', 5, 64), strconv.FormatFloat(c.RSI[Term14], 'f', 5, 64), strconv.FormatFloat(c.RSI[Term30], 'f', 5, 64),
-			strconv.FormatFloat(c.ShortMacdVal, 'f', 5, 64), strconv.FormatFloat(c.ShortMacdSmaSig, 'f', 5, 64), strconv.FormatFloat(c.ShortMacdSmaHisto, 'f', 5, 64),
-			strconv.FormatFloat(c.ShortMacdEmaSig, 'f', 5, 64), strconv.FormatFloat(c.ShortMacdEmaHisto, 'f', 5, 64),
-			strconv.FormatFloat(c.LongMacdVal, 'f', 5, 64), strconv.FormatFloat(c.LongMacdSmaSig, 'f', 5, 64), strconv.FormatFloat(c.LongMacdSmaHisto, 'f', 5, 64),
-			strconv.FormatFloat(c.LongMacdEmaSig, 'f', 5, 64), strconv.FormatFloat(c.LongMacdEmaHisto, 'f', 5, 64),
-			strconv.FormatFloat(c.UpperBBand[Term5], 'f', 5, 64), strconv.FormatFloat(c.UpperBBand[Term14], 'f', 5, 64), strconv.FormatFloat(c.UpperBBand[Term30], 'f', 5, 64),
-			strconv.FormatFloat(c.UnderBBand[Term5], 'f', 5, 64), strconv.FormatFloat(c.UnderBBand[Term14], 'f', 5, 64), strconv.FormatFloat(c.UnderBBand[Term30], 'f', 5, 64),
-			strconv.FormatFloat(arimaC.Arima_Actual_Prediction, 'f', 7, 64), strconv.FormatFloat(arimaC.Prediction_Difference, 'f', 7, 64),
-		)
+		for _, key := range indexColumns {
+			lineStr = append(lineStr, strconv.FormatFloat(c.Index[key], 'f', 5, 64))
+		}
+		for _, f := range forecasts {
+			values, ok := f.Values[formatCsvDate(c.ParseDate)]
+			if ok == false {
+				values = make([]float64, len(f.Names))
+			}
+			for _, v := range values {
+				lineStr = append(lineStr, strconv.FormatFloat(v, 'f', 7, 64))
+			}
+		}
 		outputStr = append(outputStr, lineStr)
 	}
-	_ = fileio.FileIoCsvWrite(modelCsvFileName, outputStr, false)
-	s3Key := fmt.Sprintf("%s/%s", code, ModelDataFileName)
-	_ = fileio.UploadFileToS3(S3BucketName, modelCsvFileName, s3Key)
-	slog.Info("Final Component", "Data", len(synthesisStockData), "output", len(outputStr))
+	return outputStr
+}
 
-	// 基本データをRawDataディレクトリに出力
-	var rawLineStr []string = []string{"date", "opening", "high", "low", "closing", "volume"}
-	outputStr = nil
-	outputStr = append(outputStr, rawLineStr)
-	for _, c := range synthesisStockData {
-		lineStr = nil
-		dateStr := c.ParseDate.Format(time.DateTime)
-		dateSlice := strings.Split(dateStr, " ")
-		dateSlice[0] = strings.ReplaceAll(dateSlice[0], "-", "/")
+// 該当銘柄の RawData から -forecast の予測モデルの予測精度を評価し、Evaluation.csv とレポートを出力する
+// 予測は -horizons (省略時は1営業日先)の期間毎に前の足までのデータで行い(horizonPredictions)、予測対象の日付が -from、-to の期間内の点を評価する
+// 全期間と、-window 点ずつ -window-step 点ずらしたローリング窓毎に、ランダムウォーク(起点の終値)と比較する
+func evaluateOneStockBrand(code string) error {
 
-		lineStr = append(lineStr, dateSlice[0], strconv.FormatFloat(c.Opening, 'f', 5, 64), strconv.FormatFloat(c.High, 'f', 5, 64), strconv.FormatFloat(c.Low, 'f', 5, 64),
-			strconv.FormatFloat(c.Closing, 'f', 5, 64), strconv.FormatFloat(c.Volume, 'f', 5, 64),
-		)
-		outputStr = append(outputStr, lineStr)
+	rawCsvFileName := stockFilePath(code, RawDataFileName)
+	stockData, isNotExist := readCSVInsertData(rawCsvFileName, false)
+	if isNotExist == true || len(stockData) == 0 {
+		return fmt.Errorf("raw data not found. file=%s", rawCsvFileName)
+	}
+	if len(forecastModels) == 0 {
+		return fmt.Errorf("no forecast model to evaluate (-forecast)")
+	}
+	horizons := forecastHorizons
+	if len(horizons) == 0 {
+		horizons = []int{1}
+	}
+	actual := make(map[string]float64, len(stockData))
+	for _, c := range stockData {
+		actual[formatCsvDate(c.ParseDate)] = c.Closing
+	}
+
+	var results []EvaluationResult
+	for _, model := range forecastModels {
+		f, err := newModelForecaster(model)
+		if err != nil {
+			return err
+		}
+		predictions := horizonPredictions(f, stockData, horizons)
+		for i, h := range horizons {
+			// 予測対象の日付の昇順の評価点
+			var points []backtest.Point
+			var targets []time.Time
+			for k := len(stockData) - 1; k >= 0; k-- {
+				row, ok := predictions[formatCsvDate(stockData[k].ParseDate)]
+				if ok == false {
+					continue
+				}
+				p := row[i]
+				value, ok := actual[formatCsvDate(p.Target)]
+				if ok == false || isInDateRange(p.Target) == false {
+					continue
+				}
+				points = append(points, backtest.Point{Origin: p.OriginClose, Actual: value, Predicted: p.Prediction.Value})
+				targets = append(targets, p.Target)
+			}
+			if len(points) == 0 {
+				slog.Info("Evaluation Skip", "code", code, "model", f.Name(), "horizon", h, "reason", "no prediction with actual")
+				continue
+			}
+			windows := append([][2]int{{0, len(points)}}, backtest.RollingWindows(len(points), evaluationWindow, evaluationStep)...)
+			for w, window := range windows {
+				target, walk := points[window[0]:window[1]], backtest.RandomWalk(points[window[0]:window[1]])
+				result := EvaluationResult{
+					Code:       code,
+					Model:      f.Name(),
+					Horizon:    h,
+					Window:     strconv.Itoa(w),
+					From:       targets[window[0]],
+					To:         targets[window[1]-1],
+					Metrics:    backtest.Evaluate(target),
+					RandomWalk: backtest.Evaluate(walk),
+					DM:         backtest.DMTest(target, walk, h),
+				}
+				if w == 0 {
+					result.Window = "all"
+					slog.Info("Evaluation", "code", code, "model", f.Name(), "horizon", h, "n", result.Metrics.N,
+						"mae", result.Metrics.MAE, "rw_mae", result.RandomWalk.MAE, "dm", result.DM.Statistic, "p", result.DM.PValue)
+				}
+				results = append(results, result)
+			}
+		}
+	}
+
+	if err := fileio.FileIoCsvWrite(stockFilePath(code, EvaluationFileName), evaluationCsv(results), false); err != nil {
+		return err
+	}
+	reportFileName := stockFilePath(code, EvaluationReportFileName+"."+string(reportFormat))
+	return fileio.FileIoWrite(reportFileName, []byte(evaluationReport(code, results)), false)
+}
+
+// 評価結果を csv 出力用の文字列に変換する
+func evaluationCsv(results []EvaluationResult) [][]string {
+
+	outputStr := [][]string{{"code", "model", "horizon", "window", "from", "to", "n", "mae", "rmse", "mape", "hitrate", "rw_mae", "rw_rmse", "rw_mape", "dm", "dm_pvalue"}}
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 6, 64)
+	}
+	for _, r := range results {
+		outputStr = append(outputStr, []string{
+			r.Code, r.Model, strconv.Itoa(r.Horizon), r.Window, formatCsvDate(r.From), formatCsvDate(r.To), strconv.Itoa(r.Metrics.N),
+			format(r.Metrics.MAE), format(r.Metrics.RMSE), format(r.Metrics.MAPE), format(r.Metrics.HitRate),
+			format(r.RandomWalk.MAE), format(r.RandomWalk.RMSE), format(r.RandomWalk.MAPE), format(r.DM.Statistic), format(r.DM.PValue),
+		})
+	}
+	return outputStr
+}
+
+// 評価結果のレポート(全期間の表と、予測モデル・期間毎のローリング窓の表)を -report の形式で作成する
+func evaluationReport(code string, results []EvaluationResult) string {
+
+	format := func(v float64, precision int) string {
+		if math.IsNaN(v) {
+			return "-"
+		}
+		return strconv.FormatFloat(v, 'f', precision, 64)
+	}
+	row := func(first []string, r EvaluationResult) []string {
+		return append(first, strconv.Itoa(r.Metrics.N),
+			format(r.Metrics.MAE, 4), format(r.Metrics.RMSE, 4), format(r.Metrics.MAPE, 2), format(100*r.Metrics.HitRate, 1),
+			format(r.RandomWalk.MAE, 4), format(r.RandomWalk.RMSE, 4), format(r.RandomWalk.MAPE, 2), format(r.DM.Statistic, 3), format(r.DM.PValue, 4))
+	}
+	metricHeader := []string{"n", "MAE", "RMSE", "MAPE(%)", "的中率(%)", "RW MAE", "RW RMSE", "RW MAPE(%)", "DM", "p 値"}
+
+	summary := reportTable{title: "全期間", header: append([]string{"予測モデル", "期間", "評価期間"}, metricHeader...)}
+	var tables []reportTable
+	for _, r := range results {
+		if r.Window == "all" {
+			summary.rows = append(summary.rows, row([]string{r.Model, fmt.Sprintf("%d営業日先", r.Horizon), formatCsvDate(r.From) + " - " + formatCsvDate(r.To)}, r))
+			tables = append(tables, reportTable{title: fmt.Sprintf("%s %d営業日先 (ローリング窓)", r.Model, r.Horizon), header: append([]string{"窓", "評価期間"}, metricHeader...)})
+			continue
+		}
+		table := &tables[len(tables)-1]
+		table.rows = append(table.rows, row([]string{r.Window, formatCsvDate(r.From) + " - " + formatCsvDate(r.To)}, r))
+	}
+	tables = append([]reportTable{summary}, tables...)
+
+	title := fmt.Sprintf("予測精度の評価 %s", code)
+	notes := []string{
+		"予測は前の足までのデータで行い、予測対象の日付の終値と比較する。RW は前の足の終値を予測値とするランダムウォーク",
+		"的中率は前の足の終値からの上昇・下落の方向の的中率 (終値が変わらなかった点は除く)",
+		"DM は誤差の二乗の差の Diebold-Mariano 検定 (Harvey-Leybourne-Newbold の補正)。負の場合は予測モデルの誤差が RW より小さく、p 値は両側",
+		fmt.Sprintf("ローリング窓は %d 点ずつ %d 点ずらして評価する", evaluationWindow, evaluationStep),
+	}
+	if reportFormat == ReportHTML {
+		return renderHTMLReport(title, notes, tables)
+	}
+	return renderMarkdownReport(title, notes, tables)
+}
+
+// レポートを Markdown で出力する
+func renderMarkdownReport(title string, notes []string, tables []reportTable) string {
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", title)
+	for _, note := range notes {
+		fmt.Fprintf(&sb, "- %s\n", note)
+	}
+	for _, table := range tables {
+		fmt.Fprintf(&sb, "\n## %s\n\n", table.title)
+		fmt.Fprintf(&sb, "| %s |\n", strings.Join(table.header, " | "))
+		fmt.Fprintf(&sb, "|%s\n", strings.Repeat(" --- |", len(table.header)))
+		for _, row := range table.rows {
+			fmt.Fprintf(&sb, "| %s |\n", strings.Join(row, " | "))
+		}
+	}
+	return sb.String()
+}
+
+// レポートを HTML で出力する
+func renderHTMLReport(title string, notes []string, tables []reportTable) string {
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<!DOCTYPE html>\n<html lang=\"ja\">\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", html.EscapeString(title))
+	sb.WriteString("<style>table { border-collapse: collapse; } th, td { border: 1px solid #999; padding: 2px 6px; } td { text-align: right; }</style>\n</head>\n<body>\n")
+	fmt.Fprintf(&sb, "<h1>%s</h1>\n<ul>\n", html.EscapeString(title))
+	for _, note := range notes {
+		fmt.Fprintf(&sb, "<li>%s</li>\n", html.EscapeString(note))
+	}
+	sb.WriteString("</ul>\n")
+	for _, table := range tables {
+		fmt.Fprintf(&sb, "<h2>%s</h2>\n<table>\n<tr>", html.EscapeString(table.title))
+		for _, h := range table.header {
+			fmt.Fprintf(&sb, "<th>%s</th>", html.EscapeString(h))
+		}
+		sb.WriteString("</tr>\n")
+		for _, row := range table.rows {
+			sb.WriteString("<tr>")
+			for _, v := range row {
+				fmt.Fprintf(&sb, "<td>%s</td>", html.EscapeString(v))
+			}
+			sb.WriteString("</tr>\n")
+		}
+		sb.WriteString("</table>\n")
+	}
+	sb.WriteString("</body>\n</html>\n")
+	return sb.String()
+}
+
+// 該当銘柄のModelDataのcsvファイルをS3へアップロードする
+// 週足・月足のModelDataが出力されていればあわせてアップロードする
+func uploadOneStockBrand(code string) error {
+	fileNames := []string{ModelDataFileName}
+	for _, period := range resamplePeriods {
+		if _, err := os.Stat(stockFilePath(code, modelFileName(period))); err == nil {
+			fileNames = append(fileNames, modelFileName(period))
+		}
+	}
+	for _, fileName := range fileNames {
+		s3Key := fmt.Sprintf("%s/%s", code, fileName)
+		if err := fileio.UploadFileToS3(s3BucketName, stockFilePath(code, fileName), s3Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 該当銘柄のRawData、ModelDataのcsvファイルの整合性を検証する
+func verifyOneStockBrand(code string) error {
+
+	var errs []error
+
+	// RawData: 日付降順、重複なし、四本値の整合性
+	rawCsvFileName := stockFilePath(code, RawDataFileName)
+	rawData, isNotExist := readCSVInsertData(rawCsvFileName, false)
+	if isNotExist == true {
+		return fmt.Errorf("raw data not found. file=%s", rawCsvFileName)
+	}
+	rawContents, err := fileio.FileIoCsvRead(rawCsvFileName)
+	if err != nil {
+		return err
 	}
-	_ = fileio.FileIoCsvWrite(rawCsvFileName, outputStr, false)
+	var prevDate time.Time
+	for i := 1; i < len(rawContents); i++ {
+		date, errDate := convert.ConvertStringToTime(rawContents[i][0])
+		if errDate != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid date %q at line %d", RawDataFileName, rawContents[i][0], i+1))
+			continue
+		}
+		if !prevDate.IsZero() && date.Equal(prevDate) {
+			errs = append(errs, fmt.Errorf("%s: duplicate date %s", RawDataFileName, rawContents[i][0]))
+		} else if !prevDate.IsZero() && date.After(prevDate) {
+			errs = append(errs, fmt.Errorf("%s: not in descending date order at %s", RawDataFileName, rawContents[i][0]))
+		}
+		prevDate = date
+	}
+	for _, c := range rawData {
+		if c.High < c.Low || c.Closing < c.Low || c.Closing > c.High || c.Opening < c.Low || c.Opening > c.High {
+			errs = append(errs, fmt.Errorf("%s: inconsistent OHLC at %s", RawDataFileName, formatCsvDate(c.ParseDate)))
+		}
+	}
+
+	// ModelData: 読み込めること(カラム数の不一致は読み込みエラーとなる)
+	modelCsvFileName := stockFilePath(code, ModelDataFileName)
+	modelContents, err := fileio.FileIoCsvRead(modelCsvFileName)
+	if err != nil {
+		errs = append(errs, err)
+	} else if len(modelContents) <= 1 {
+		errs = append(errs, fmt.Errorf("%s: no data rows", ModelDataFileName))
+	}
+
+	slog.Info("Verify", "code", code, "raw", len(rawData), "model", max(len(modelContents)-1, 0), "problems", len(errs))
+	return errors.Join(errs...)
+}
+
+// 該当銘柄のcsvデータを作成する(取得 → モデル作成 → アップロード)
+func csvCreationOneStockBrand(code string, cData []CommonInformation) error {
 
+	if err := fetchOneStockBrand(code); err != nil {
+		return err
+	}
+	if err := buildModelOneStockBrand(code, cData); err != nil {
+		return err
+	}
+	return uploadOneStockBrand(code)
 }
 
 // ---- main
+
+const usageText = `usage: csvdata_create_main [command] [flags]
+
+commands:
+  run          fetch, build-model, upload を順に実行する(省略時)
+  fetch        取得元(既定は株探)から日足を取得し RawData.csv を更新する
+  build-model  RawData.csv からテクニカル指標と ARIMA 予測を計算し ModelData.csv を出力する
+               あわせて週足・月足に変換したテクニカル指標を ModelData_weekly.csv / ModelData_monthly.csv に出力する
+  upload       ModelData.csv (週足・月足があればそれも) を S3 へアップロードする
+  verify       RawData.csv / ModelData.csv の整合性を検証する
+  backfill     株探の日足・週足・月足を -start まで遡って取得する(中断しても再実行で続きから再開)
+  evaluate     RawData.csv から予測モデルの予測精度をランダムウォークと比較し、Evaluation.csv とレポートを出力する
+
+複数銘柄を処理する場合は -codes / -watchlist / -all のいずれかを指定する。
+銘柄ごとに -workers 並列で処理し、最後に結果の集計を出力する。
+
+flags:
+`
+
+// 取得種別の文字列を ObtainType に変換する
+func parseObtainType(str string) (ObtainType, error) {
+	switch strings.ToLower(str) {
+	case "stock":
+		return Stock, nil
+	case "forex":
+		return Forex, nil
+	}
+	return Stock, fmt.Errorf("unknown obtain type %q (stock / forex)", str)
+}
+
+// 逐次計算のモードの文字列を IncrementalMode に変換する
+func parseIncrementalMode(str string) (IncrementalMode, error) {
+	switch mode := IncrementalMode(strings.ToLower(str)); mode {
+	case IncrementalOff, IncrementalOn, IncrementalVerify:
+		return mode, nil
+	}
+	return IncrementalOff, fmt.Errorf("unknown incremental mode %q (off / on / verify)", str)
+}
+
+// 予測モデルのカンマ区切りの文字列を ForecastModel に変換する。空文字は予測のカラムを出力しない
+func parseForecastModels(str string) ([]ForecastModel, error) {
+	var models []ForecastModel
+	for _, v := range convert.SplitList(str) {
+		model := ForecastModel(strings.ToLower(v))
+		switch model {
+		case ForecastARIMA, ForecastHoltWinters, ForecastGARCH, ForecastNaive, ForecastDrift:
+		default:
+			return nil, fmt.Errorf("unknown forecast model %q (arima / holtwinters / garch / naive / drift)", v)
+		}
+		if slices.Contains(models, model) == true {
+			return nil, fmt.Errorf("duplicate forecast model %q", v)
+		}
+		models = append(models, model)
+	}
+	return models, nil
+}
+
+// レポートの形式の文字列を ReportFormat に変換する
+func parseReportFormat(str string) (ReportFormat, error) {
+	switch format := ReportFormat(strings.ToLower(str)); format {
+	case ReportMarkdown, ReportHTML:
+		return format, nil
+	}
+	return ReportMarkdown, fmt.Errorf("unknown report format %q (md / html)", str)
+}
+
+// 予測の期間(営業日)のカンマ区切りの文字列を変換する。空文字は複数期先の予測を出力しない
+func parseHorizons(str string) ([]int, error) {
+	var horizons []int
+	for _, v := range convert.SplitList(str) {
+		h, err := strconv.Atoi(v)
+		if err != nil || h < 1 {
+			return nil, fmt.Errorf("invalid horizon %q (positive integer)", v)
+		}
+		if slices.Contains(horizons, h) == true {
+			return nil, fmt.Errorf("duplicate horizon %d", h)
+		}
+		horizons = append(horizons, h)
+	}
+	return horizons, nil
+}
+
+// ARIMA の次数の上限と情報量規準の文字列を自動選択の探索範囲に変換する
+func parseArimaSearchSpace(maxOrder string, criterion string) (arima.SearchSpace, error) {
+
+	limit, err := arima.ParseOrder(maxOrder)
+	if err != nil {
+		return arima.SearchSpace{}, fmt.Errorf("invalid -arima-max-order: %w", err)
+	}
+	if limit.SeasonalD > 0 {
+		return arima.SearchSpace{}, fmt.Errorf("invalid -arima-max-order: seasonal differencing is not searched")
+	}
+	space := arima.SearchSpace{MaxP: limit.P, MaxD: limit.D, MaxQ: limit.Q, Period: limit.Period, MaxSeasonalP: limit.SeasonalP, MaxSeasonalQ: limit.SeasonalQ}
+	if space.Criterion, err = arima.ParseCriterion(criterion); err != nil {
+		return arima.SearchSpace{}, err
+	}
+	return space, nil
+}
+
+// ARIMA予測の計算方法の文字列を ArimaEngine に変換する
+func parseArimaEngine(str string) (ArimaEngine, error) {
+	switch engine := ArimaEngine(strings.ToLower(str)); engine {
+	case ArimaGo, ArimaPython, ArimaCompare:
+		return engine, nil
+	}
+	return ArimaGo, fmt.Errorf("unknown arima engine %q (go / python / compare)", str)
+}
+
+// 足種別のカンマ区切り文字列を変換する
+func parseBarTypes(str string) ([]datasource.BarType, error) {
+	var bars []datasource.BarType
+	for _, v := range convert.SplitList(str) {
+		bar := datasource.BarType(v)
+		switch bar {
+		case datasource.BarDaily, datasource.BarWeekly, datasource.BarMonthly:
+			bars = append(bars, bar)
+		default:
+			return nil, fmt.Errorf("unknown bar type %q (day / wek / mon)", v)
+		}
+	}
+	return bars, nil
+}
+
+// テクニカル指標の期間のカンマ区切り文字列を昇順の期間に変換する
+func parseTerms(str string) ([]int, error) {
+	var terms []int
+	for _, v := range convert.SplitList(str) {
+		term, err := strconv.Atoi(v)
+		if err != nil || term < 2 {
+			return nil, fmt.Errorf("invalid term %q (integer >= 2)", v)
+		}
+		terms = append(terms, term)
+	}
+	slices.Sort(terms)
+	terms = slices.Compact(terms)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("at least 1 term is required")
+	}
+	return terms, nil
+}
+
+// 平滑化方式のカンマ区切り文字列を変換する
+func parseSmoothings(str string) ([]indicator.Smoothing, error) {
+	var methods []indicator.Smoothing
+	for _, v := range convert.SplitList(str) {
+		method, err := indicator.ParseSmoothing(v)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(methods, method) {
+			methods = append(methods, method)
+		}
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("at least 1 smoothing is required")
+	}
+	return methods, nil
+}
+
+// 足の期間のカンマ区切り文字列を変換する。空文字は週足・月足を出力しない
+func parseResamplePeriods(str string) ([]resample.Period, error) {
+	var periods []resample.Period
+	for _, v := range convert.SplitList(str) {
+		period, err := resample.ParsePeriod(v)
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, period)
+	}
+	return periods, nil
+}
+
+// 対象期間の日付文字列(yyyy/mm/dd)を変換する。空文字はゼロ値(制限なし)とする
+func parseDateFlag(str string) (time.Time, error) {
+	if str == "" {
+		return time.Time{}, nil
+	}
+	return convert.ConvertStringToTime(str)
+}
+
+// サブコマンドとフラグを解釈して実行する
+// 先頭引数がフラグ、または引数なしの場合は run とみなす
+func runCommand(args []string) error {
+
+	command := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usageText)
+		fs.PrintDefaults()
+	}
+	code := fs.String("code", DefaultStockCode, "銘柄コード")
+	fs.StringVar(&resourceDir, "resource", DefaultResourceDir, "リソースディレクトリ")
+	fs.StringVar(&s3BucketName, "bucket", DefaultS3BucketName, "アップロード先の S3 バケット名")
+	obtain := fs.String("obtain", "stock", "取得種別 (stock / forex)")
+	from := fs.String("from", "", "ModelData に出力する期間の開始日 yyyy/mm/dd (省略時は制限なし)")
+	to := fs.String("to", "", "ModelData に出力する期間の終了日 yyyy/mm/dd (省略時は制限なし)")
+	codes := fs.String("codes", "", "複数銘柄コード(カンマ区切り)")
+	watchlist := fs.String("watchlist", "", "銘柄コードを列挙したウォッチリストファイル")
+	all := fs.Bool("all", false, "リソースディレクトリ内の RawData.csv がある全銘柄を対象にする")
+	workers := fs.Int("workers", 4, "複数銘柄処理時の並列数")
+	source := fs.String("source", "kabutan", "日足の取得元 (kabutan / file)")
+	sourceFile := fs.String("source-file", "", "-source file のファイルパス ({code} は銘柄コードに置換。.csv / .json)")
+	kabutanDefault := datasource.NewKabutan()
+	userAgent := fs.String("user-agent", kabutanDefault.UserAgent, "株探へのリクエストに付与する User-Agent")
+	delay := fs.Duration("delay", kabutanDefault.Delay, "株探への同一ホストのリクエスト間隔")
+	randomDelay := fs.Duration("random-delay", kabutanDefault.RandomDelay, "リクエスト間隔に加えるランダム遅延の上限")
+	retry := fs.Int("retry", kabutanDefault.MaxRetry, "429 / 5xx / 通信エラー時の最大再試行回数(指数バックオフ)")
+	start := fs.String("start", "", "backfill で遡る開始日 yyyy/mm/dd")
+	bars := fs.String("bars", "day,wek,mon", "backfill 対象の足種別 (day / wek / mon のカンマ区切り)")
+	fs.BoolVar(&isBackfillRestart, "restart", false, "backfill のチェックポイントを無視して最初から取得する")
+	terms := fs.String("terms", "5,14,30", "テクニカル指標を計算する期間 (2 以上の整数のカンマ区切り。1つ以上)")
+	macd := fs.String("macd", "short=sma:5:30:9,long=sma:14:30:9,ema:12:26:9", "MACD の定義 ([名前=]sma|ema:短期:長期:シグナル のカンマ区切り)")
+	psar := fs.String("psar", "0.02,0.02,0.2", "パラボリック SAR の加速因子 (初期値,増分,上限)")
+	ichimoku := fs.String("ichimoku", "9,26,52", "一目均衡表の期間 (転換線,基準線,先行スパンB。先行スパン・遅行スパンは基準線の期間ずらす)")
+	smoothing := fs.String("smoothing", "sma", "ATR、RSI の平滑化方式 (sma / wilder / ema のカンマ区切り。方式毎にカラムを出力)")
+	incremental := fs.String("incremental", "off", "テクニカル指標の逐次計算 (off: 毎回全ての足を計算 / on: 前回から追加された足のみ計算 / verify: on に加えて全ての足の再計算と比較)")
+	engine := fs.String("arima", "python", "ARIMA予測の計算方法 (python: arima_insights.py を実行 / go: Go で計算 / compare: 両方で計算して差をログに出力し go の結果を用いる)")
+	order := fs.String("arima-order", "1,0,1", "終値の前日差分の系列に当てはめる ARIMA の次数 (p,d,q / p,d,q,P,D,Q,周期 / auto: 銘柄毎に情報量規準で自動選択)")
+	maxOrder := fs.String("arima-max-order", "3,2,3", "-arima-order auto で探索する次数の上限 (p,d,q / p,d,q,P,D,Q,周期。季節の差分 D は探索しない)")
+	criterion := fs.String("arima-criterion", "aic", "-arima-order auto で次数の選択に用いる情報量規準 (aic / bic)")
+	fs.BoolVar(&isArimaRefresh, "arima-refresh", false, "-arima-order auto で保存した次数 (ArimaOrder.json) を使わずに選択し直す")
+	fs.IntVar(&arimaReselect, "arima-reselect", 20, "-arima-order auto で予測の起点までの系列で次数を選び直す間隔 (暦日の系列の本数)")
+	models := fs.String("forecast", "arima", "ModelData に予測のカラムを出力する予測モデル (arima / holtwinters / garch / naive / drift のカンマ区切り。空文字で出力しない)")
+	fs.IntVar(&holtWintersPeriod, "holtwinters-period", 7, "Holt-Winters の季節の周期 (系列の本数。-forecast は営業日、-horizon-model は暦日の系列。0 は季節項なし)")
+	horizons := fs.String("horizons", "", "Forecast_h{N} / _lo / _hi のカラムに出力する予測の期間 (営業日のカンマ区切り。例 1,5,20。空文字で出力しない)")
+	fs.Float64Var(&intervalLevel, "interval", 0.95, "Forecast_h{N}_lo / _hi の予測区間の信頼水準")
+	model := fs.String("horizon-model", "arima", "Forecast_h{N} の予測モデル (arima / holtwinters / garch / naive / drift)")
+	fs.IntVar(&evaluationWindow, "window", 60, "evaluate のローリング窓の評価点の数")
+	fs.IntVar(&evaluationStep, "window-step", 20, "evaluate のローリング窓をずらす評価点の数")
+	report := fs.String("report", "md", "evaluate のレポートの形式 (md / html)")
+	periods := fs.String("resample", "weekly,monthly", "build-model で日足に加えて出力する足 (weekly / monthly のカンマ区切り。空文字で出力しない)")
+	maxRejectRatio := fs.Float64("max-reject-ratio", kabutanDefault.MaxRejectRatio, "1ページの不正行の割合がこれを超えるとレイアウト変更として取得を中止する")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var err error
+	if nowObtain, err = parseObtainType(*obtain); err != nil {
+		return err
+	}
+	if dateFrom, err = parseDateFlag(*from); err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	if dateTo, err = parseDateFlag(*to); err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+	if backfillStart, err = parseDateFlag(*start); err != nil {
+		return fmt.Errorf("invalid -start: %w", err)
+	}
+	if backfillBars, err = parseBarTypes(*bars); err != nil {
+		return err
+	}
+	if termDay, err = parseTerms(*terms); err != nil {
+		return err
+	}
+	if macdDefinitions, err = indicator.ParseMACDDefinitions(*macd); err != nil {
+		return err
+	}
+	if sarAcceleration, err = indicator.ParseSARAcceleration(*psar); err != nil {
+		return err
+	}
+	if ichimokuPeriods, err = indicator.ParseIchimokuPeriods(*ichimoku); err != nil {
+		return err
+	}
+	if smoothings, err = parseSmoothings(*smoothing); err != nil {
+		return err
+	}
+	if resamplePeriods, err = parseResamplePeriods(*periods); err != nil {
+		return err
+	}
+	if incrementalMode, err = parseIncrementalMode(*incremental); err != nil {
+		return err
+	}
+	if arimaEngine, err = parseArimaEngine(*engine); err != nil {
+		return err
+	}
+	if isArimaAuto = strings.EqualFold(*order, "auto"); isArimaAuto == false {
+		if arimaOrder, err = arima.ParseOrder(*order); err != nil {
+			return err
+		}
+	}
+	if arimaSearch, err = parseArimaSearchSpace(*maxOrder, *criterion); err != nil {
+		return err
+	}
+	if forecastModels, err = parseForecastModels(*models); err != nil {
+		return err
+	}
+	if _, err = forecast.NewHoltWinters(holtWintersPeriod); err != nil {
+		return err
+	}
+	if forecastHorizons, err = parseHorizons(*horizons); err != nil {
+		return err
+	}
+	if !(intervalLevel > 0 && intervalLevel < 1) {
+		return fmt.Errorf("invalid -interval %v (0 < level < 1)", intervalLevel)
+	}
+	horizon, err := parseForecastModels(*model)
+	if err != nil || len(horizon) != 1 {
+		return fmt.Errorf("invalid -horizon-model %q (arima / holtwinters / garch / naive / drift)", *model)
+	}
+	horizonModel = horizon[0]
+	if evaluationWindow < 2 || evaluationStep < 1 {
+		return fmt.Errorf("invalid -window %d / -window-step %d", evaluationWindow, evaluationStep)
+	}
+	if reportFormat, err = parseReportFormat(*report); err != nil {
+		return err
+	}
+	if dataSource, err = datasource.New(*source, *sourceFile); err != nil {
+		return err
+	}
+	if kabutan, ok := dataSource.(*datasource.Kabutan); ok {
+		kabutan.UserAgent = *userAgent
+		kabutan.Delay = *delay
+		kabutan.RandomDelay = *randomDelay
+		kabutan.MaxRetry = *retry
+		kabutan.MaxRejectRatio = *maxRejectRatio
+	}
+
+	var process func(code string) error
+	switch command {
+	case "run":
+		cData := readCommonCsv()
+		process = func(code string) error { return csvCreationOneStockBrand(code, cData) }
+	case "fetch":
+		process = fetchOneStockBrand
+	case "build-model":
+		cData := readCommonCsv()
+		process = func(code string) error { return buildModelOneStockBrand(code, cData) }
+	case "upload":
+		process = uploadOneStockBrand
+	case "verify":
+		process = verifyOneStockBrand
+	case "backfill":
+		process = backfillOneStockBrand
+	case "evaluate":
+		process = evaluateOneStockBrand
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	targetCodes, isBatch, err := resolveTargetCodes(*code, *codes, *watchlist, *all)
+	if err != nil {
+		return err
+	}
+	if isBatch == false {
+		return process(*code)
+	}
+
+	// 複数銘柄を並列に処理し、結果を集計する
+	results := batch.Run(targetCodes, *workers, process)
+	fmt.Print(batch.Summary(results))
+	if failed := batch.Failed(results); len(failed) > 0 {
+		return fmt.Errorf("%d of %d codes failed", len(failed), len(results))
+	}
+	return nil
+}
+
+// 処理対象の銘柄コードを決定する
+// -codes / -watchlist / -all のいずれかが指定された場合は複数銘柄処理(isBatch = true)とする
+func resolveTargetCodes(code string, codes string, watchlist string, all bool) ([]string, bool, error) {
+
+	var targetCodes []string
+	isBatch := false
+	if codes != "" {
+		isBatch = true
+		targetCodes = append(targetCodes, batch.SplitCodes(codes)...)
+	}
+	if watchlist != "" {
+		isBatch = true
+		watchCodes, err := batch.ReadWatchlist(watchlist)
+		if err != nil {
+			return nil, true, fmt.Errorf("watchlist read error: %w", err)
+		}
+		targetCodes = append(targetCodes, watchCodes...)
+	}
+	if all == true {
+		isBatch = true
+		scanCodes, err := batch.ScanResourceDir(resourceDir, RawDataFileName)
+		if err != nil {
+			return nil, true, err
+		}
+		targetCodes = append(targetCodes, scanCodes...)
+	}
+	if isBatch == false {
+		return []string{code}, false, nil
+	}
+
+	targetCodes = slices.Compact(slices.Sorted(slices.Values(targetCodes)))
+	if len(targetCodes) == 0 {
+		return nil, true, fmt.Errorf("no target codes")
+	}
+	return targetCodes, true, nil
+}
+
 func main() {
 	//lambda.Start(checkJraEntries)
-	commonData := readCommonCsv()
-	csvCreationOneStockBrand(StockCode, commonData)
-
+	if err := runCommand(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		slog.Error("command failed", "err", err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,819 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"sv_stockcheck/arima"
+	"sv_stockcheck/fileio"
+)
+
+// 指標の計算を意図して変更した場合は go test -run TestBuildModelGolden -update で golden ファイルを再生成する
+var update = flag.Bool("update", false, "testdata/golden の ModelData を再生成する")
+
+// 固定の ARIMA 予測(python を実行せず、最新の5本の足に終値の1%増しを予測値として返す)
+func stubArimaPrediction(csvfile string) ([]ArimaPredictionResultInformation, error) {
+
+	stockData, _ := readCSVInsertData(csvfile, false)
+	var results []ArimaPredictionResultInformation
+	for _, c := range stockData[:min(5, len(stockData))] {
+		results = append(results, ArimaPredictionResultInformation{
+			Date:                    c.ParseDate.Format("2006-01-02T15:04:05.000"),
+			ParseDate:               c.ParseDate,
+			Arima_Actual_Prediction: c.Closing * 1.01,
+			Prediction_Difference:   c.Closing * 0.01,
+		})
+	}
+	return results, nil
+}
+
+// ファイルをコピーする
+func copyFile(t *testing.T, src string, dst string) {
+	t.Helper()
+	body, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, body, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 最初に異なる行を返す(行番号は1始まり)
+func firstDiffLine(got []byte, want []byte) (int, string, string) {
+	gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < max(len(gotLines), len(wantLines)); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			return i + 1, g, w
+		}
+	}
+	return 0, "", ""
+}
+
+// 固定の RawData.csv と CommonData.csv (testdata/Resource) から build-model で作成した ModelData を golden ファイルとバイト単位で比較する
+// 取得元、S3 にはアクセスせず、ARIMA 予測は stubArimaPrediction に差し替える
+func TestBuildModelGolden(t *testing.T) {
+
+	original := arimaPredictor
+	arimaPredictor = stubArimaPrediction
+	t.Cleanup(func() { arimaPredictor = original })
+	originalTerms, originalMACD, originalIchimoku, originalPeriods := termDay, macdDefinitions, ichimokuPeriods, resamplePeriods
+	t.Cleanup(func() {
+		termDay, macdDefinitions, ichimokuPeriods, resamplePeriods = originalTerms, originalMACD, originalIchimoku, originalPeriods
+	})
+
+	// 既定の指標の設定では週足・月足の本数が requiredBars に満たないため、日足のみ比較する
+	// 週足・月足は期間の短い設定(requiredBars = 8)で作成して比較する
+	smallConfig := []string{"-terms", "3,4", "-macd", "sma:2:4:2", "-ichimoku", "2,3,5"}
+	tests := []struct {
+		name   string // golden のディレクトリ名
+		code   string
+		obtain string
+		flags  []string
+	}{
+		{"2586", "2586", "stock", []string{"-resample", ""}},
+		{"0970", "0970", "forex", []string{"-resample", ""}},
+		{"2586_small", "2586", "stock", smallConfig},
+		{"0970_small", "0970", "forex", smallConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			copyFile(t, filepath.Join("testdata", "Resource", CommonDataFileName), filepath.Join(dir, CommonDataFileName))
+			copyFile(t, filepath.Join("testdata", "Resource", tt.code, RawDataFileName), filepath.Join(dir, tt.code, RawDataFileName))
+
+			args := append([]string{"build-model", "-code", tt.code, "-obtain", tt.obtain, "-resource", dir}, tt.flags...)
+			if err := runCommand(args); err != nil {
+				t.Fatalf("build-model: %v", err)
+			}
+
+			goldenDir := filepath.Join("testdata", "golden", tt.name)
+			outputs, _ := filepath.Glob(filepath.Join(dir, tt.code, "ModelData*.csv"))
+			goldens, _ := filepath.Glob(filepath.Join(goldenDir, "ModelData*.csv"))
+			names := func(paths []string) []string {
+				var base []string
+				for _, p := range paths {
+					base = append(base, filepath.Base(p))
+				}
+				return base
+			}
+			if *update == true {
+				if err := os.RemoveAll(goldenDir); err != nil {
+					t.Fatal(err)
+				}
+				for _, output := range outputs {
+					copyFile(t, output, filepath.Join(goldenDir, filepath.Base(output)))
+				}
+				return
+			}
+			if slices.Equal(names(outputs), names(goldens)) == false {
+				t.Fatalf("ModelData files = %v, golden = %v", names(outputs), names(goldens))
+			}
+
+			for _, output := range outputs {
+				got, err := os.ReadFile(output)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want, err := os.ReadFile(filepath.Join(goldenDir, filepath.Base(output)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if bytes.Equal(got, want) == false {
+					line, g, w := firstDiffLine(got, want)
+					t.Errorf("%s differs from golden at line %d (run go test -update if the change is intended)\n got: %s\nwant: %s", filepath.Base(output), line, g, w)
+				}
+			}
+		})
+	}
+}
+
+// arima パッケージの ARIMA 予測が arima_insights.py と同じく暦日の6日目以降の各日付の予測値を返すこと
+func TestArimaPredictionGo(t *testing.T) {
+
+	// 直近の40本の足で計算する
+	body, err := os.ReadFile(filepath.Join("testdata", "Resource", "2586", RawDataFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(body), "\n")
+	csvfile := filepath.Join(t.TempDir(), RawDataFileName)
+	if err := os.WriteFile(csvfile, []byte(strings.Join(lines[:41], "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stockData, _ := readCSVInsertData(csvfile, false)
+	oldest, latest := stockData[len(stockData)-1].ParseDate, stockData[0].ParseDate
+	days := int(latest.Sub(oldest).Hours()/24+0.5) + 1
+
+	originalOrder, originalAuto := arimaOrder, isArimaAuto
+	t.Cleanup(func() { arimaOrder, isArimaAuto = originalOrder, originalAuto })
+	arimaOrder, isArimaAuto = arima.Order{P: 1, D: 0, Q: 1}, false
+	result, err := arimaPredictionGo(csvfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != days-6 {
+		t.Fatalf("len(result) = %d, want %d", len(result), days-6)
+	}
+	if first := result[0].ParseDate; first.Equal(oldest.AddDate(0, 0, 6)) == false {
+		t.Errorf("first prediction date = %v, want %v", first, oldest.AddDate(0, 0, 6))
+	}
+	closing := make(map[string]float64)
+	for _, c := range stockData {
+		closing[formatCsvDate(c.ParseDate)] = c.Closing
+	}
+	for i, r := range result {
+		if i > 0 && r.ParseDate.Sub(result[i-1].ParseDate).Hours() != 24 {
+			t.Errorf("result[%d] date = %v is not the day after %v", i, r.ParseDate, result[i-1].ParseDate)
+		}
+		if c, ok := closing[formatCsvDate(r.ParseDate)]; ok == true && c != r.Closing {
+			t.Errorf("%s closing = %v, want %v", formatCsvDate(r.ParseDate), r.Closing, c)
+		}
+		if math.Abs(r.Closing-r.Arima_Actual_Prediction-r.Prediction_Difference) > 1e-9 {
+			t.Errorf("%s prediction difference = %v, want %v", formatCsvDate(r.ParseDate), r.Prediction_Difference, r.Closing-r.Arima_Actual_Prediction)
+		}
+		if math.IsNaN(r.Arima_Actual_Prediction) || math.Abs(r.Arima_Diff_Prediction) > 0.2*r.Closing {
+			t.Errorf("%s prediction = %v (diff %v) is out of range", formatCsvDate(r.ParseDate), r.Arima_Actual_Prediction, r.Arima_Diff_Prediction)
+		}
+	}
+}
+
+// -arima-order auto で選択した次数を ArimaOrder.json に保存し、探索範囲が同じ場合は再利用すること
+func TestResolveArimaOrder(t *testing.T) {
+
+	originalSearch, originalRefresh := arimaSearch, isArimaRefresh
+	t.Cleanup(func() { arimaSearch, isArimaRefresh = originalSearch, originalRefresh })
+
+	// 前日差分が AR(1) (φ = 0.6) の系列
+	days := 200
+	dates := make([]time.Time, days)
+	diff := make([]float64, days)
+	diff[0] = math.NaN()
+	state := uint64(1)
+	for i := range dates {
+		dates[i] = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i)
+		if i > 0 {
+			state = state*6364136223846793005 + 1442695040888963407
+			noise := float64(state>>11)/float64(1<<53) - 0.5
+			diff[i] = noise
+			if i > 1 {
+				diff[i] += 0.6 * diff[i-1]
+			}
+		}
+	}
+
+	sidecarFile := filepath.Join(t.TempDir(), ArimaOrderFileName)
+	arimaSearch = arima.SearchSpace{MaxP: 2, MaxD: 1, MaxQ: 1, Criterion: arima.CriterionBIC}
+	isArimaRefresh = false
+	order, err := resolveArimaOrder(sidecarFile, dates, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order != (arima.Order{P: 1}) {
+		t.Errorf("selected order = %s, want ARIMA(1,0,0)", order)
+	}
+	// 期間の異なる系列(前半の 150 本)の次数は別に保存する
+	if _, err := resolveArimaOrder(sidecarFile, dates[:150], diff[:150]); err != nil {
+		t.Fatal(err)
+	}
+	var sidecar ArimaOrderSidecar
+	if err := fileio.FileIoJsonRead(sidecarFile, &sidecar); err != nil {
+		t.Fatal(err)
+	}
+	if sidecar.Settings != arimaSearch.String() || len(sidecar.Windows) != 2 {
+		t.Fatalf("sidecar = %+v", sidecar)
+	}
+	window := sidecar.Windows[1]
+	if window.DataFrom != "2024/01/01" || window.DataTo != formatCsvDate(dates[days-1]) || sidecar.Windows[0].DataTo != formatCsvDate(dates[149]) ||
+		window.Selection.Order != order || len(window.Selection.Candidates) != 6 || len(window.Selection.Tests) != 1 {
+		t.Errorf("sidecar = %+v", sidecar)
+	}
+
+	// 同じ期間の次数を再利用する(選択し直していないことを確かめるため保存した次数を書き換える)
+	sidecar.Windows[1].Selection.Order = arima.Order{P: 2, Q: 1}
+	if err := fileio.FileIoJsonWrite(sidecarFile, sidecar, false); err != nil {
+		t.Fatal(err)
+	}
+	if order, _ := resolveArimaOrder(sidecarFile, dates, diff); order != (arima.Order{P: 2, Q: 1}) {
+		t.Errorf("reused order = %s, want ARIMA(2,0,1)", order)
+	}
+	// 最古日付の異なる系列で選択すると、以前の期間の次数は削除する
+	if _, err := resolveArimaOrder(sidecarFile, dates[1:], diff[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := fileio.FileIoJsonRead(sidecarFile, &sidecar); err != nil || len(sidecar.Windows) != 1 || sidecar.Windows[0].DataFrom != "2024/01/02" {
+		t.Errorf("sidecar after the oldest date changed = %+v, %v", sidecar, err)
+	}
+
+	// -arima-refresh、探索範囲の変更では選択し直す
+	isArimaRefresh = true
+	if order, _ := resolveArimaOrder(sidecarFile, dates, diff); order != (arima.Order{P: 1}) {
+		t.Errorf("refreshed order = %s, want ARIMA(1,0,0)", order)
+	}
+	isArimaRefresh = false
+	arimaSearch = arima.SearchSpace{MaxP: 0, MaxD: 1, MaxQ: 0, Criterion: arima.CriterionBIC}
+	if order, _ := resolveArimaOrder(sidecarFile, dates, diff); order != (arima.Order{}) {
+		t.Errorf("order for the changed search space = %s, want ARIMA(0,0,0)", order)
+	}
+}
+
+// -forecast の予測モデル毎にカラムを出力し、ランダムウォークの予測値は前の足の終値(月曜日は金曜日の終値)となること
+// 予測は取引のある日の足のみで、後の足を除いても過去の足の予測は変わらない
+func TestForecastModelColumns(t *testing.T) {
+
+	originalModels, originalPredictor := forecastModels, arimaPredictor
+	t.Cleanup(func() { forecastModels, arimaPredictor = originalModels, originalPredictor })
+	arimaPredictor = stubArimaPrediction
+
+	models, err := parseForecastModels("naive, ARIMA,garch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Equal(models, []ForecastModel{ForecastNaive, ForecastARIMA, ForecastGARCH}) == false {
+		t.Errorf("parseForecastModels = %v", models)
+	}
+	for _, str := range []string{"naive,naive", "prophet"} {
+		if _, err := parseForecastModels(str); err == nil {
+			t.Errorf("parseForecastModels(%q) expected error", str)
+		}
+	}
+
+	forecastModels = models
+	csvfile := filepath.Join("testdata", "Resource", "2586", RawDataFileName)
+	forecasts, err := forecastModelColumns(csvfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range forecasts {
+		names = append(names, f.Names...)
+	}
+	if want := []string{"NaivePredict", "NaivePredictDiff", "ARIMAPredict", "ARIMAPredictDiff", "GARCHVolatility", "GARCHResidual"}; slices.Equal(names, want) == false {
+		t.Fatalf("columns = %v, want %v", names, want)
+	}
+
+	stockData, _ := readCSVInsertData(csvfile, false)
+	isTradingDate := make(map[string]bool)
+	for _, c := range stockData {
+		isTradingDate[formatCsvDate(c.ParseDate)] = true
+	}
+	for _, f := range []ForecastColumns{forecasts[0], forecasts[2]} {
+		for date := range f.Values {
+			if isTradingDate[date] == false {
+				t.Errorf("%s %s is not a trading date", f.Names[0], date)
+			}
+		}
+	}
+	mondays := 0
+	for i := 0; i < len(stockData)-6; i++ {
+		today, previous := stockData[i], stockData[i+1]
+		values, ok := forecasts[0].Values[formatCsvDate(today.ParseDate)]
+		if ok == false {
+			t.Errorf("%s naive not found", formatCsvDate(today.ParseDate))
+			continue
+		}
+		if values[0] != previous.Closing || values[1] != today.Closing-previous.Closing {
+			t.Errorf("%s naive = %v, want [%v %v]", formatCsvDate(today.ParseDate), values, previous.Closing, today.Closing-previous.Closing)
+		}
+		if today.ParseDate.Weekday() == time.Monday && previous.ParseDate.Weekday() == time.Friday {
+			mondays++
+		}
+	}
+	if mondays == 0 {
+		t.Error("no Monday predicted from Friday's close")
+	}
+	// 月曜日 2024/12/23 の naive の予測値は金曜日 2024/12/20 の終値、年末年始の休日明け 2025/01/06 は 2024/12/30 の終値
+	for date, want := range map[string]float64{"2024/12/23": 119, "2025/01/06": 116} {
+		if values := forecasts[0].Values[date]; len(values) == 0 || values[0] != want {
+			t.Errorf("%s naive = %v, want %v", date, values, want)
+		}
+	}
+
+	// 最新の足を除いた RawData で計算しても、残りの足の予測は変わらない(休日の補間に後の足を用いない)
+	body, err := os.ReadFile(csvfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(body), "\n")
+	truncated := filepath.Join(t.TempDir(), RawDataFileName)
+	if err := os.WriteFile(truncated, []byte(lines[0]+strings.Join(lines[2:], "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	truncatedForecasts, err := forecastModelColumns(truncated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, j := range []int{0, 2} {
+		for date, want := range truncatedForecasts[j].Values {
+			if got := forecasts[j].Values[date]; slices.Equal(got, want) == false {
+				t.Errorf("%s %s = %v, without the latest bar %v", forecasts[j].Names[0], date, got, want)
+			}
+		}
+		if len(truncatedForecasts[j].Values) != len(forecasts[j].Values)-1 {
+			t.Errorf("%s predictions without the latest bar = %d, want %d", forecasts[j].Names[0], len(truncatedForecasts[j].Values), len(forecasts[j].Values)-1)
+		}
+	}
+	if len(forecasts[1].Values) != 5 {
+		t.Errorf("ARIMA predictions = %d, want 5 (stub)", len(forecasts[1].Values))
+	}
+	for date, values := range forecasts[2].Values {
+		if !(values[0] > 0 && values[0] < 0.5) || math.IsNaN(values[1]) {
+			t.Errorf("%s GARCH = %v", date, values)
+		}
+	}
+}
+
+// statsmodels との照合の許容誤差(終値に対する予測値の差の割合)
+// 推定の最適化の収束判定の違いによる差を許容する
+const statsmodelsTolerance = 1e-3
+
+// Go の ARIMA予測を arima_insights.py (statsmodels) の出力と照合する
+// 照合用の出力は pandas、statsmodels が使える環境で作成してコミットする
+//
+//	python arima_insights.py testdata/Resource/2586/RawData.csv > testdata/arima/2586_statsmodels.json
+func TestArimaPredictionGoMatchesStatsmodels(t *testing.T) {
+
+	originalOrder, originalAuto := arimaOrder, isArimaAuto
+	t.Cleanup(func() { arimaOrder, isArimaAuto = originalOrder, originalAuto })
+	arimaOrder, isArimaAuto = arima.Order{P: 1, D: 0, Q: 1}, false
+
+	for _, code := range []string{"2586"} {
+		body, err := os.ReadFile(filepath.Join("testdata", "arima", code+"_statsmodels.json"))
+		if os.IsNotExist(err) {
+			t.Skipf("testdata/arima/%s_statsmodels.json not found (create it with arima_insights.py)", code)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := parseArimaPredictionJson(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := arimaPredictionGo(filepath.Join("testdata", "Resource", code, RawDataFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: predictions = %d, statsmodels = %d", code, len(got), len(want))
+		}
+		for i := range want {
+			g, w := got[i], want[i]
+			if g.ParseDate.Equal(w.ParseDate) == false {
+				t.Fatalf("%s row %d: date = %s, statsmodels = %s", code, i, formatCsvDate(g.ParseDate), formatCsvDate(w.ParseDate))
+			}
+			limit := statsmodelsTolerance * math.Abs(w.Closing)
+			if math.Abs(g.Arima_Actual_Prediction-w.Arima_Actual_Prediction) > limit || math.Abs(g.Prediction_Difference-w.Prediction_Difference) > limit {
+				t.Errorf("%s %s: prediction = %v (difference %v), statsmodels = %v (difference %v)", code, formatCsvDate(g.ParseDate),
+					g.Arima_Actual_Prediction, g.Prediction_Difference, w.Arima_Actual_Prediction, w.Prediction_Difference)
+			}
+		}
+	}
+}
+
+// -arima-order auto の ARIMAPredict は起点(前日)までの系列で次数を選ぶため、後の足を除いても過去の日付の予測は変わらない
+// 保存した期間毎の次数は次回に再利用する
+func TestArimaPredictionGoAuto(t *testing.T) {
+
+	originalAuto, originalSearch, originalReselect, originalRefresh := isArimaAuto, arimaSearch, arimaReselect, isArimaRefresh
+	t.Cleanup(func() {
+		isArimaAuto, arimaSearch, arimaReselect, isArimaRefresh = originalAuto, originalSearch, originalReselect, originalRefresh
+	})
+	isArimaAuto, arimaReselect, isArimaRefresh = true, 20, false
+	arimaSearch = arima.SearchSpace{MaxP: 1, MaxD: 1, MaxQ: 1, Criterion: arima.CriterionAIC}
+
+	body, err := os.ReadFile(filepath.Join("testdata", "Resource", "2586", RawDataFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(body), "\n")
+	// RawData は日付降順のため、古い方の 60 本と 80 本
+	writeRaw := func(bars int) string {
+		csvfile := filepath.Join(t.TempDir(), RawDataFileName)
+		if err := os.WriteFile(csvfile, []byte(lines[0]+strings.Join(lines[len(lines)-1-bars:], "")), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return csvfile
+	}
+	predict := func(csvfile string) map[string]ArimaPredictionResultInformation {
+		result, err := arimaPredictionGo(csvfile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		predictions := make(map[string]ArimaPredictionResultInformation)
+		for _, r := range result {
+			predictions[formatCsvDate(r.ParseDate)] = r
+		}
+		return predictions
+	}
+
+	full, truncated := writeRaw(80), writeRaw(60)
+	fullPredictions, truncatedPredictions := predict(full), predict(truncated)
+	if len(truncatedPredictions) < 40 {
+		t.Fatalf("predictions = %d", len(truncatedPredictions))
+	}
+	for date, want := range truncatedPredictions {
+		if got := fullPredictions[date]; got != want {
+			t.Errorf("%s: full = %+v, truncated = %+v", date, got, want)
+		}
+	}
+
+	var sidecar ArimaOrderSidecar
+	if err := fileio.FileIoJsonRead(filepath.Join(filepath.Dir(full), ArimaOrderFileName), &sidecar); err != nil {
+		t.Fatal(err)
+	}
+	if len(sidecar.Windows) < 3 {
+		t.Fatalf("windows = %d, want one per -arima-reselect days", len(sidecar.Windows))
+	}
+	for i := 1; i < len(sidecar.Windows); i++ {
+		if sidecar.Windows[i].DataTo <= sidecar.Windows[i-1].DataTo {
+			t.Errorf("windows are not ascending: %s, %s", sidecar.Windows[i-1].DataTo, sidecar.Windows[i].DataTo)
+		}
+	}
+	// 2回目は保存した次数を再利用し、同じ予測となる
+	rerun := predict(full)
+	for date, want := range fullPredictions {
+		if got := rerun[date]; got != want {
+			t.Errorf("%s: rerun = %+v, want %+v", date, got, want)
+		}
+	}
+}
+
+// 複数期先の予測は前の足までのデータのみを用いること(後の足を除いたデータで計算しても同じ値となる)
+func TestHorizonForecastColumns(t *testing.T) {
+
+	originalHorizons, originalModel, originalLevel := forecastHorizons, horizonModel, intervalLevel
+	t.Cleanup(func() { forecastHorizons, horizonModel, intervalLevel = originalHorizons, originalModel, originalLevel })
+	originalOrder, originalAuto, originalSearch := arimaOrder, isArimaAuto, arimaSearch
+	t.Cleanup(func() { arimaOrder, isArimaAuto, arimaSearch = originalOrder, originalAuto, originalSearch })
+	forecastHorizons, intervalLevel = []int{1, 5, 20}, 0.95
+	arimaSearch = arima.SearchSpace{MaxP: 1, MaxD: 1, MaxQ: 1, Criterion: arima.CriterionAIC}
+	arimaOrder = arima.Order{P: 1, D: 0, Q: 1}
+
+	body, err := os.ReadFile(filepath.Join("testdata", "Resource", "2586", RawDataFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(body), "\n")
+	writeRaw := func(bars int) string {
+		csvfile := filepath.Join(t.TempDir(), RawDataFileName)
+		if err := os.WriteFile(csvfile, []byte(lines[0]+strings.Join(lines[len(lines)-1-bars:], "")), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return csvfile
+	}
+	// RawData は日付降順のため、古い方の 40 本と 60 本
+	full, truncated := writeRaw(60), writeRaw(40)
+
+	// -arima-order auto も起点までの系列で次数を選択するため、後の足を除いても同じ値となる
+	tests := []struct {
+		name   string
+		model  ForecastModel
+		isAuto bool
+	}{
+		{"arima", ForecastARIMA, false},
+		{"arima auto", ForecastARIMA, true},
+		{"naive", ForecastNaive, false},
+	}
+	for _, tt := range tests {
+		horizonModel, isArimaAuto = tt.model, tt.isAuto
+		model := tt.name
+		fullColumns, err := horizonForecastColumns(full)
+		if err != nil {
+			t.Fatal(err)
+		}
+		truncatedColumns, err := horizonForecastColumns(truncated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"Forecast_h1", "Forecast_h1_lo", "Forecast_h1_hi", "Forecast_h5", "Forecast_h5_lo", "Forecast_h5_hi", "Forecast_h20", "Forecast_h20_lo", "Forecast_h20_hi"}
+		if slices.Equal(fullColumns.Names, want) == false {
+			t.Fatalf("columns = %v, want %v", fullColumns.Names, want)
+		}
+		if len(truncatedColumns.Values) < 20 {
+			t.Fatalf("%s: predictions = %d", model, len(truncatedColumns.Values))
+		}
+		for date, values := range truncatedColumns.Values {
+			if slices.Equal(values, fullColumns.Values[date]) == false {
+				t.Errorf("%s %s: truncated = %v, full = %v", model, date, values, fullColumns.Values[date])
+			}
+			for i := 0; i < len(values); i += 3 {
+				if !(values[i+1] < values[i] && values[i] < values[i+2]) {
+					t.Errorf("%s %s: interval %v does not contain %v", model, date, values[i+1:i+3], values[i])
+				}
+			}
+		}
+	}
+
+	// naive は全ての期間で前の足の終値を予測値とする
+	stockData, _ := readCSVInsertData(full, false)
+	naive, _ := horizonForecastColumns(full)
+	for k := 0; k < len(stockData)-1; k++ {
+		values, ok := naive.Values[formatCsvDate(stockData[k].ParseDate)]
+		if ok == false {
+			continue
+		}
+		for i := 0; i < len(values); i += 3 {
+			if values[i] != stockData[k+1].Closing {
+				t.Errorf("%s naive = %v, want %v", formatCsvDate(stockData[k].ParseDate), values[i], stockData[k+1].Closing)
+			}
+		}
+	}
+}
+
+// evaluate は予測モデル・期間・窓毎の評価結果の csv とレポートを出力し、naive はランダムウォークと同じ予測となる
+func TestEvaluateOneStockBrand(t *testing.T) {
+
+	originalModels, originalHorizons, originalFormat, originalDir := forecastModels, forecastHorizons, reportFormat, resourceDir
+	originalWindow, originalStep := evaluationWindow, evaluationStep
+	t.Cleanup(func() {
+		forecastModels, forecastHorizons, reportFormat, resourceDir = originalModels, originalHorizons, originalFormat, originalDir
+		evaluationWindow, evaluationStep = originalWindow, originalStep
+	})
+
+	dir := t.TempDir()
+	copyFile(t, filepath.Join("testdata", "Resource", "2586", RawDataFileName), filepath.Join(dir, "2586", RawDataFileName))
+	args := []string{"evaluate", "-code", "2586", "-resource", dir, "-forecast", "naive,drift", "-horizons", "1,5", "-window", "100", "-window-step", "50", "-report", "html"}
+	if err := runCommand(args); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+
+	rows, err := fileio.FileIoCsvRead(filepath.Join(dir, "2586", EvaluationFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) < 2 || rows[0][0] != "code" || rows[0][15] != "dm_pvalue" {
+		t.Fatalf("header = %v", rows[0])
+	}
+	windows := map[string]int{}
+	for _, row := range rows[1:] {
+		key := row[1] + "/" + row[2]
+		windows[key]++
+		if row[1] != "Naive" {
+			continue
+		}
+		// naive は RW と誤差が一致し、DM 検定はできない
+		if row[7] != row[11] || row[8] != row[12] || row[14] != "NaN" {
+			t.Errorf("naive row = %v", row)
+		}
+	}
+	for _, key := range []string{"Naive/1", "Naive/5", "Drift/1", "Drift/5"} {
+		// 全期間と 100 点ずつ 50 点ずらした窓(約290点から4個)
+		if windows[key] != 5 {
+			t.Errorf("%s rows = %d, want 5", key, windows[key])
+		}
+	}
+
+	report, err := os.ReadFile(filepath.Join(dir, "2586", EvaluationReportFileName+".html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<h2>全期間</h2>", "<h2>Drift 5営業日先 (ローリング窓)</h2>", "<td>Naive</td>"} {
+		if strings.Contains(string(report), want) == false {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+
+	if err := runCommand([]string{"evaluate", "-code", "2586", "-resource", dir, "-report", "pdf"}); err == nil {
+		t.Error("-report pdf expected error")
+	}
+}
+
+// evaluate は起点までの系列で予測するため(-arima-order auto の次数の選択を含む)、
+// -to より後の足を書き換えても評価結果とレポートは変わらない
+func TestEvaluateIgnoresBarsAfterLastOrigin(t *testing.T) {
+
+	originalModels, originalHorizons, originalFormat, originalDir := forecastModels, forecastHorizons, reportFormat, resourceDir
+	originalAuto, originalSearch, originalTo := isArimaAuto, arimaSearch, dateTo
+	t.Cleanup(func() {
+		forecastModels, forecastHorizons, reportFormat, resourceDir = originalModels, originalHorizons, originalFormat, originalDir
+		isArimaAuto, arimaSearch, dateTo = originalAuto, originalSearch, originalTo
+	})
+
+	body, err := os.ReadFile(filepath.Join("testdata", "Resource", "2586", RawDataFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(body), "\n")
+	// 2024/12/13 より後の足を、前日比が交互に大きく上下する足に書き換える
+	modified := slices.Clone(lines)
+	for i := 1; i < len(modified) && strings.HasPrefix(modified[i], "2024/12/13") == false; i++ {
+		price := 140 + 30*float64(i%2)
+		modified[i] = fmt.Sprintf("%s,%.5f,%.5f,%.5f,%.5f,1000000.00000\n", modified[i][:10], price, price+5, price-5, price)
+	}
+
+	evaluate := func(lines []string) (string, string) {
+		dir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dir, "2586"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "2586", RawDataFileName), []byte(strings.Join(lines, "")), 0644); err != nil {
+			t.Fatal(err)
+		}
+		args := []string{"evaluate", "-code", "2586", "-resource", dir, "-forecast", "arima,drift", "-horizons", "1,5",
+			"-arima-order", "auto", "-arima-max-order", "1,1,1", "-to", "2024/12/13"}
+		if err := runCommand(args); err != nil {
+			t.Fatalf("evaluate: %v", err)
+		}
+		csv, err := os.ReadFile(filepath.Join(dir, "2586", EvaluationFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, err := os.ReadFile(filepath.Join(dir, "2586", EvaluationReportFileName+".md"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "2586", ArimaOrderFileName)); err == nil {
+			t.Error("evaluate saved the order selected on the whole series")
+		}
+		return string(csv), string(report)
+	}
+
+	csv, report := evaluate(lines)
+	modifiedCsv, modifiedReport := evaluate(modified)
+	if strings.Contains(csv, "ARIMA,1,all") == false || strings.Contains(csv, "2024/12/13") == false {
+		t.Fatalf("Evaluation.csv = %s", csv)
+	}
+	if modifiedCsv != csv {
+		t.Errorf("Evaluation.csv changed by the bars after -to\n got: %s\nwant: %s", modifiedCsv, csv)
+	}
+	if modifiedReport != report {
+		t.Error("report changed by the bars after -to")
+	}
+}
+
+// 全ての足を逐次計算器に1本ずつ与えた指標は、全ての足の再計算と同じカラムを持ち、値が一致する
+func TestIndexStateNext(t *testing.T) {
+
+	originalObtain := nowObtain
+	t.Cleanup(func() { nowObtain = originalObtain })
+	stockData, isNotExist := readCSVInsertData(filepath.Join("testdata", "Resource", "2586", RawDataFileName), false)
+	if isNotExist == true {
+		t.Fatal("raw data not found")
+	}
+	for _, obtain := range []ObtainType{Stock, Forex} {
+		nowObtain = obtain
+		full := calculateTechnicalIndex(slices.Clone(stockData))
+		streamed := slices.Clone(full)
+		state := newIndexState()
+		for i := len(streamed) - 1; i >= 0; i-- {
+			streamed[i].Index = state.next(streamed[i])
+			if len(streamed[i].Index) != len(full[i].Index) {
+				t.Fatalf("obtain %d: %d columns, want %d", obtain, len(streamed[i].Index), len(full[i].Index))
+			}
+		}
+		if err := compareTechnicalIndex("2586", streamed, full); err != nil {
+			t.Errorf("obtain %d: %v", obtain, err)
+		}
+	}
+}
+
+// テクニカル指標の逐次計算は、保存した状態から追加された足のみ計算した結果が全ての足の再計算と一致し、
+// 状態がない、設定が変わった、計算済みの最新の足が変わった、計算済みの足の本数が変わった場合は全ての足を計算し直す
+func TestCalculateTechnicalIndexIncremental(t *testing.T) {
+
+	originalDir, originalMode, originalTerms := resourceDir, incrementalMode, termDay
+	t.Cleanup(func() { resourceDir, incrementalMode, termDay = originalDir, originalMode, originalTerms })
+	resourceDir, incrementalMode = t.TempDir(), IncrementalVerify
+	const code = "2586"
+	if err := os.MkdirAll(filepath.Join(resourceDir, code), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// RawData は日付降順のため、stockData[k:] は新しい方の k 本を除いた足
+	load := func(k int, oldest int) []StockBrandInformation {
+		stockData, isNotExist := readCSVInsertData(filepath.Join("testdata", "Resource", code, RawDataFileName), false)
+		if isNotExist == true {
+			t.Fatal("raw data not found")
+		}
+		return stockData[k : len(stockData)-oldest]
+	}
+	run := func(stockData []StockBrandInformation) []StockBrandInformation {
+		t.Helper()
+		got, err := calculateTechnicalIndexIncremental(code, stockData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	// 状態がない場合は全ての足を計算し、N 本の状態を保存する
+	if _, state, _ := resumeIndexState(code, load(5, 0)); state != nil {
+		t.Fatal("resume without IndexState")
+	}
+	run(load(5, 0))
+	added, state, _ := resumeIndexState(code, load(5, 0))
+	if state == nil || added != 0 || state.Bars != len(load(5, 0)) {
+		t.Fatalf("IndexState after rebuild: added = %d, state = %v", added, state)
+	}
+
+	// k 本追加した足は状態から続けて計算し、全ての足の再計算と一致する
+	added, state, _ = resumeIndexState(code, load(0, 0))
+	if state == nil || added != 5 {
+		t.Fatalf("resume after 5 bars added: added = %d, state = %v", added, state)
+	}
+	got := run(load(0, 0))
+	full := calculateTechnicalIndex(load(0, 0))
+	if err := compareTechnicalIndex(code, got, full); err != nil {
+		t.Fatalf("resumed index differs from full recalculation: %v", err)
+	}
+	if _, state, _ := resumeIndexState(code, load(0, 0)); state == nil || state.Bars != len(full) {
+		t.Fatalf("IndexState after resume = %v, want %d bars", state, len(full))
+	}
+
+	lastBarChanged := func() []StockBrandInformation {
+		stockData := load(0, 0)
+		stockData[5].Closing += 1
+		return stockData
+	}
+	tests := []struct {
+		name    string
+		base    []StockBrandInformation        // 状態を保存する足
+		prepare func()                         // 状態を保存した後の設定・ファイルの変更
+		input   func() []StockBrandInformation // 状態を保存した後の足
+	}{
+		{"settings changed", load(5, 0), func() { termDay = []int{5, 14} }, func() []StockBrandInformation { return load(0, 0) }},
+		{"last bar changed", load(5, 0), nil, lastBarChanged},
+		// 古い方の 10 本がない状態で保存し、backfill で過去の足が増えた場合
+		{"bars added by backfill", load(5, 10), nil, func() []StockBrandInformation { return load(0, 0) }},
+		{"state removed", load(5, 0), func() {
+			if err := os.Remove(stockFilePath(code, IndexStateFileName)); err != nil {
+				t.Fatal(err)
+			}
+		}, func() []StockBrandInformation { return load(0, 0) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			termDay = originalTerms
+			run(tt.base)
+			if tt.prepare != nil {
+				tt.prepare()
+			}
+			if _, state, _ := resumeIndexState(code, tt.input()); state != nil {
+				t.Fatalf("resumed from IndexState of %d bars, want rebuild", state.Bars)
+			}
+			// 計算し直した結果は全ての足の再計算と一致し(verify)、計算し直した状態から続けられる
+			stockData := run(tt.input())
+			if _, state, _ := resumeIndexState(code, tt.input()); state == nil || state.Bars != len(stockData) {
+				t.Errorf("IndexState after rebuild = %v, want %d bars", state, len(stockData))
+			}
+		})
+	}
+}
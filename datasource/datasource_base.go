@@ -0,0 +1,69 @@
+// datasource 株価データ取得元パッケージ
+package datasource // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ---- struct
+
+// DailyPrice 日足の四本値と出来高
+type DailyPrice struct {
+	Date    time.Time // 日付
+	Opening float64   // 始値
+	High    float64   // 高値
+	Low     float64   // 安値
+	Closing float64   // 終値
+	Volume  float64   // 出来高
+}
+
+// ---- interface
+
+// DataSource 銘柄の日足データの取得元
+type DataSource interface {
+	// FetchDaily 銘柄 code の日足を from〜to の範囲(両端を含む、ゼロ値は制限なし)で日付降順に返す
+	FetchDaily(code string, from time.Time, to time.Time) ([]DailyPrice, error)
+}
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// New (public)取得元の種別から DataSource を生成する
+// kind: "kabutan" 株探スクレイピング / "file" ローカルファイル(pathPattern の {code} を銘柄コードに置換)
+func New(kind string, pathPattern string) (DataSource, error) {
+	switch kind {
+	case "kabutan":
+		return NewKabutan(), nil
+	case "file":
+		if pathPattern == "" {
+			return nil, fmt.Errorf("file data source requires a path pattern")
+		}
+		return NewFile(pathPattern), nil
+	}
+	return nil, fmt.Errorf("unknown data source %q (kabutan / file)", kind)
+}
+
+//---- private function ----
+
+// 日付が from〜to の範囲(ゼロ値は制限なし)に含まれるかを判定する
+func isInRange(date time.Time, from time.Time, to time.Time) bool {
+	if !from.IsZero() && date.Before(from) {
+		return false
+	}
+	if !to.IsZero() && date.After(to) {
+		return false
+	}
+	return true
+}
+
+// 日付降順に並べ替える
+func sortDescending(prices []DailyPrice) {
+	sort.SliceStable(prices, func(i, j int) bool {
+		return prices[i].Date.After(prices[j].Date)
+	})
+}
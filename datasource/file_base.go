@@ -0,0 +1,131 @@
+// datasource 株価データ取得元パッケージ
+package datasource // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"sv_stockcheck/convert"
+	"sv_stockcheck/fileio"
+)
+
+// ---- struct
+
+// File ローカルの CSV / JSON ファイルを読み込む取得元(オフライン実行、フィクスチャ再生用)
+// CSV は RawData.csv と同じ形式(date,opening,high,low,closing,volume)
+// JSON は {"date":"yyyy/mm/dd","opening":0,"high":0,"low":0,"closing":0,"volume":0} の配列
+type File struct {
+	PathPattern string // ファイルパス。{code} は銘柄コードに置換する
+}
+
+// JSONファイルの1要素
+type dailyPriceJson struct {
+	Date    string  `json:"date"`
+	Opening float64 `json:"opening"`
+	High    float64 `json:"high"`
+	Low     float64 `json:"low"`
+	Closing float64 `json:"closing"`
+	Volume  float64 `json:"volume"`
+}
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// NewFile (public)ローカルファイルの取得元を生成する
+func NewFile(pathPattern string) *File {
+	return &File{PathPattern: pathPattern}
+}
+
+// FetchDaily (public)ファイルを読み込み、from〜to の範囲の日足を日付降順で返す
+func (f *File) FetchDaily(code string, from time.Time, to time.Time) ([]DailyPrice, error) {
+
+	fileName := strings.ReplaceAll(f.PathPattern, "{code}", code)
+
+	var prices []DailyPrice
+	var err error
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		prices, err = readDailyCsv(fileName)
+	case ".json":
+		prices, err = readDailyJson(fileName)
+	default:
+		err = fmt.Errorf("unsupported file type: %s", fileName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var retValue []DailyPrice
+	for _, v := range prices {
+		if isInRange(v.Date, from, to) {
+			retValue = append(retValue, v)
+		}
+	}
+	sortDescending(retValue)
+	return retValue, nil
+}
+
+//---- private function ----
+
+// CSVファイル(先頭行はタイトル行)から日足を読み込む
+func readDailyCsv(fileName string) ([]DailyPrice, error) {
+
+	fileContents, err := fileio.FileIoCsvRead(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var retValue []DailyPrice
+	for i, v := range fileContents {
+		// 先頭はタイトル行なのでSkip
+		if i == 0 {
+			continue
+		}
+		if len(v) < 6 {
+			return nil, fmt.Errorf("%s line %d: expected 6 columns, got %d", fileName, i+1, len(v))
+		}
+
+		var single DailyPrice
+		if single.Date, err = convert.ConvertStringToTime(v[0]); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", fileName, i+1, err)
+		}
+		values := []*float64{&single.Opening, &single.High, &single.Low, &single.Closing, &single.Volume}
+		for j, p := range values {
+			if *p, err = strconv.ParseFloat(v[j+1], 64); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", fileName, i+1, err)
+			}
+		}
+		retValue = append(retValue, single)
+	}
+	return retValue, nil
+}
+
+// JSONファイルから日足を読み込む
+func readDailyJson(fileName string) ([]DailyPrice, error) {
+
+	fileContents, err := fileio.FileIoRead(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var records []dailyPriceJson
+	if err = json.Unmarshal(fileContents, &records); err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	var retValue []DailyPrice
+	for i, r := range records {
+		date, errDate := convert.ConvertStringToTime(r.Date)
+		if errDate != nil {
+			return nil, fmt.Errorf("%s record %d: %w", fileName, i, errDate)
+		}
+		retValue = append(retValue, DailyPrice{Date: date, Opening: r.Opening, High: r.High, Low: r.Low, Closing: r.Closing, Volume: r.Volume})
+	}
+	return retValue, nil
+}
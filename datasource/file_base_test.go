@@ -0,0 +1,122 @@
+package datasource
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 日足を比較する
+func checkDailyPrices(t *testing.T, name string, got []DailyPrice, want []DailyPrice) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d rows, want %d: %+v", name, len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Date.Equal(want[i].Date) || got[i].Opening != want[i].Opening || got[i].High != want[i].High ||
+			got[i].Low != want[i].Low || got[i].Closing != want[i].Closing || got[i].Volume != want[i].Volume {
+			t.Errorf("%s row %d = %+v, want %+v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func TestFileFetchDailyCsv(t *testing.T) {
+	f := NewFile(filepath.Join("testdata", "file", "{code}.csv"))
+
+	// ファイルの行順に関わらず日付降順で返す
+	got, err := f.FetchDaily("2586", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("FetchDaily: %v", err)
+	}
+	checkDailyPrices(t, "csv", got, []DailyPrice{
+		{Date: date(2025, 5, 16), Opening: 167, High: 168, Low: 155, Closing: 155, Volume: 8988700},
+		{Date: date(2025, 5, 15), Opening: 160, High: 166, Low: 147, Closing: 159, Volume: 6658000},
+		{Date: date(2025, 5, 14), Opening: 155, High: 160, Low: 153, Closing: 160, Volume: 2612500},
+		{Date: date(2025, 5, 13), Opening: 160, High: 162, Low: 155, Closing: 156, Volume: 2738700},
+	})
+}
+
+func TestFileFetchDailyJson(t *testing.T) {
+	f := NewFile(filepath.Join("testdata", "file", "{code}.json"))
+
+	// volume を省略した要素は 0
+	got, err := f.FetchDaily("0970", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("FetchDaily: %v", err)
+	}
+	checkDailyPrices(t, "json", got, []DailyPrice{
+		{Date: date(2025, 3, 28), Opening: 1.0798, High: 1.0845, Low: 1.0765, Closing: 1.0827},
+		{Date: date(2025, 3, 27), Opening: 1.0738, High: 1.0821, Low: 1.0733, Closing: 1.0798},
+		{Date: date(2025, 3, 26), Opening: 1.0794, High: 1.0802, Low: 1.0735, Closing: 1.0738},
+	})
+}
+
+func TestFileFetchDailyDateRange(t *testing.T) {
+	f := NewFile(filepath.Join("testdata", "file", "{code}.csv"))
+
+	// from、to は範囲に含む。ゼロ値は制限なし
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want []time.Time
+	}{
+		{"from and to", date(2025, 5, 14), date(2025, 5, 15), []time.Time{date(2025, 5, 15), date(2025, 5, 14)}},
+		{"from only", date(2025, 5, 15), time.Time{}, []time.Time{date(2025, 5, 16), date(2025, 5, 15)}},
+		{"to only", time.Time{}, date(2025, 5, 13), []time.Time{date(2025, 5, 13)}},
+		{"empty range", date(2025, 5, 17), time.Time{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := f.FetchDaily("2586", tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("FetchDaily: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range tt.want {
+				if !got[i].Date.Equal(tt.want[i]) {
+					t.Errorf("row %d date = %v, want %v", i, got[i].Date, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFileFetchDailyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		body     string
+		wantErr  string
+	}{
+		{"malformed date", "bad.csv", "date,opening,high,low,closing,volume\n2025/ab/16,1,2,1,2,100\n", "bad.csv line 2"},
+		{"malformed number", "bad.csv", "date,opening,high,low,closing,volume\n2025/05/16,1,2,1,2,100\n2025/05/15,1,abc,1,2,100\n", "bad.csv line 3"},
+		{"short row", "bad.csv", "date,opening,high,low,closing\n2025/05/16,1,2,1,2\n", "expected 6 columns"},
+		{"ragged row", "bad.csv", "date,opening,high,low,closing,volume\n2025/05/16,1,2\n", "wrong number of fields"},
+		{"malformed json", "bad.json", `[{"date": "2025/05/16", "opening": "x"}]`, "bad.json"},
+		{"malformed json date", "bad.json", `[{"date": "16 May 2025", "opening": 1}]`, "bad.json record 0"},
+		{"unsupported type", "bad.txt", "2025/05/16,1,2,1,2,100\n", "unsupported file type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), tt.fileName)
+			if err := os.WriteFile(fileName, []byte(tt.body), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewFile(fileName).FetchDaily("2586", time.Time{}, time.Time{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("FetchDaily = %+v, %v, want error containing %q", got, err, tt.wantErr)
+			}
+		})
+	}
+
+	// ファイルがない場合はエラー
+	missing := filepath.Join(t.TempDir(), "{code}.csv")
+	if _, err := NewFile(missing).FetchDaily("9999", time.Time{}, time.Time{}); !os.IsNotExist(err) {
+		t.Errorf("missing file err = %v, want not exist", err)
+	}
+}
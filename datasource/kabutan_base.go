@@ -0,0 +1,347 @@
+// datasource 株価データ取得元パッケージ
+package datasource // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+
+	"sv_stockcheck/convert"
+)
+
+// ---- const
+
+const KabutanBaseURL = "https://kabutan.jp/stock/kabuka"
+const KabutanMaxPage = 10
+const KabutanUserAgent = "sv_stockanalysis/1.0 (+https://github.com/Yoshi-Hirai/sv_stockanalysis)"
+const KabutanBackfillMaxPage = 1000 // バックフィルで辿るページ数の上限(無限ループ防止)
+
+// BarType 時系列ページの足種別(URL の ashi パラメータ)
+type BarType string
+
+const (
+	BarDaily   BarType = "day" // 日足
+	BarWeekly  BarType = "wek" // 週足
+	BarMonthly BarType = "mon" // 月足
+)
+
+// ---- struct
+
+// Kabutan 株探(https://kabutan.jp/)の時系列ページをスクレイピングする取得元
+type Kabutan struct {
+	BaseURL        string        // 時系列ページのURL(クエリを除く)
+	MaxPage        int           // 取得する最大ページ数
+	UserAgent      string        // リクエストに付与する User-Agent
+	Delay          time.Duration // 同一ホストへのリクエスト間隔
+	RandomDelay    time.Duration // リクエスト間隔に加えるランダムな遅延の上限
+	RequestTimeout time.Duration // 1リクエストのタイムアウト
+	MaxRetry       int           // 429 / 5xx / 通信エラー時の最大再試行回数
+	BackoffBase    time.Duration // 再試行の待ち時間の基準(試行毎に倍にする)
+	MaxRejectRatio float64       // 1ページの除外行の割合がこれを超えるとレイアウト変更エラーとする
+}
+
+// StatusError ページ取得に失敗した際のエラー
+type StatusError struct {
+	URL        string        // 取得対象のURL
+	StatusCode int           // HTTPステータスコード(通信エラーの場合は0)
+	RetryAfter time.Duration // Retry-After ヘッダで指定された待ち時間
+	Err        error         // 元のエラー
+}
+
+// ホスト毎のリクエスト間隔を制御する
+type hostLimiter struct {
+	mu   sync.Mutex
+	next map[string]time.Time // ホスト毎の次回リクエスト可能時刻
+}
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+// 全ての Kabutan で共有するホスト毎のリクエスト間隔制御(複数銘柄の並列取得でも間隔を守る)
+var kabutanLimiter = &hostLimiter{next: map[string]time.Time{}}
+
+//---- public function ----
+
+// NewKabutan (public)既定の設定で株探の取得元を生成する
+func NewKabutan() *Kabutan {
+	return &Kabutan{
+		BaseURL:        KabutanBaseURL,
+		MaxPage:        KabutanMaxPage,
+		UserAgent:      KabutanUserAgent,
+		Delay:          2 * time.Second,
+		RandomDelay:    1 * time.Second,
+		RequestTimeout: 30 * time.Second,
+		MaxRetry:       3,
+		BackoffBase:    2 * time.Second,
+		MaxRejectRatio: DefaultMaxRejectRatio,
+	}
+}
+
+// Error (public)エラー文字列を返す
+func (e *StatusError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("request failed: %s: %v", e.URL, e.Err)
+	}
+	return fmt.Sprintf("request failed: %s: status %d: %v", e.URL, e.StatusCode, e.Err)
+}
+
+// Unwrap (public)元のエラーを返す
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable (public)再試行で回復する見込みのあるエラー(429 / 5xx / 通信エラー)かを判定する
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == 0 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// FetchDaily (public)日足ページを新しい順に辿り、from より古いデータに到達したところで終了する
+func (k *Kabutan) FetchDaily(code string, from time.Time, to time.Time) ([]DailyPrice, error) {
+
+	// Obtain = Stock URL https://kabutan.jp/stock/kabuka?code=147A&ashi=day&page=1
+	// Obtain = Forex URL https://kabutan.jp/stock/kabuka?code=0970&ashi=day&page=4
+	var retValue []DailyPrice
+	for i := 1; i <= k.MaxPage; i++ {
+		scrapeUrl := k.pageURL(code, BarDaily, i)
+		slog.Info("url", "url", scrapeUrl)
+
+		pageData, err := k.fetchPage(scrapeUrl)
+		if err != nil {
+			return nil, fmt.Errorf("kabutan page %d: %w", i, err)
+		}
+		slog.Info("Web Component", "len", len(pageData))
+		if len(pageData) <= 0 {
+			break
+		}
+
+		isReached := false
+		for _, v := range pageData {
+			if !from.IsZero() && v.Date.Before(from) {
+				isReached = true
+				continue
+			}
+			if isInRange(v.Date, from, to) {
+				retValue = append(retValue, v)
+			}
+		}
+		if isReached == true {
+			slog.Info("Already", "information", pageData[0].Date, "from", from)
+			break
+		}
+	}
+	sortDescending(retValue)
+	return retValue, nil
+}
+
+// Backfill (public)bar の時系列ページを startPage から古い方へ辿り、start より古いデータに到達するか
+// データがなくなるまで取得する。MaxPage の制限は受けない
+// ページ毎に start 以降のデータを onPage に渡す。呼び出し側でデータと進捗(次のページ番号)を保存すれば
+// 中断しても次回は続きのページから再開できる。全ページを辿り終えた場合に nil を返す
+func (k *Kabutan) Backfill(code string, bar BarType, start time.Time, startPage int, onPage func(page int, prices []DailyPrice) error) error {
+
+	var prevOldest time.Time
+	for page := max(startPage, 1); page <= KabutanBackfillMaxPage; page++ {
+		scrapeUrl := k.pageURL(code, bar, page)
+		slog.Info("url", "url", scrapeUrl)
+
+		pageData, err := k.fetchPage(scrapeUrl)
+		if err != nil {
+			return fmt.Errorf("kabutan %s page %d: %w", bar, page, err)
+		}
+		if len(pageData) <= 0 {
+			slog.Info("Backfill End", "code", code, "bar", bar, "page", page)
+			return nil
+		}
+		sortDescending(pageData)
+		// 範囲外のページ番号で同じページが返される場合に備え、古い方へ進まなければ終了する
+		if !prevOldest.IsZero() && !pageData[0].Date.Before(prevOldest) {
+			slog.Info("Backfill No Progress", "code", code, "bar", bar, "page", page)
+			return nil
+		}
+		prevOldest = pageData[len(pageData)-1].Date
+
+		var prices []DailyPrice
+		for _, v := range pageData {
+			if isInRange(v.Date, start, time.Time{}) {
+				prices = append(prices, v)
+			}
+		}
+		if err := onPage(page, prices); err != nil {
+			return err
+		}
+		if len(prices) < len(pageData) {
+			slog.Info("Backfill Reached", "code", code, "bar", bar, "page", page, "start", start)
+			return nil
+		}
+	}
+	return fmt.Errorf("kabutan %s: backfill exceeded %d pages", bar, KabutanBackfillMaxPage)
+}
+
+//---- private function ----
+
+// 時系列ページのURLを返す
+func (k *Kabutan) pageURL(code string, bar BarType, page int) string {
+	return fmt.Sprintf("%s?code=%s&ashi=%s&page=%d", k.BaseURL, code, bar, page)
+}
+
+// 1ページを取得する。429 / 5xx / 通信エラーは指数バックオフで再試行する
+func (k *Kabutan) fetchPage(scrapeUrl string) ([]DailyPrice, error) {
+
+	var lastErr error
+	for attempt := 0; attempt <= k.MaxRetry; attempt++ {
+		if attempt > 0 {
+			wait := k.BackoffBase << (attempt - 1)
+			var statusErr *StatusError
+			if errors.As(lastErr, &statusErr) && statusErr.RetryAfter > wait {
+				wait = statusErr.RetryAfter
+			}
+			slog.Info("Retry", "url", scrapeUrl, "attempt", attempt, "wait", wait, "err", lastErr)
+			time.Sleep(wait)
+		}
+
+		pageData, err := k.checkOneStockBrand(scrapeUrl)
+		if err == nil {
+			return pageData, nil
+		}
+		lastErr = err
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || !statusErr.Retryable() {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// 1銘柄のurlを引数として、該当した銘柄の情報を返す
+func (k *Kabutan) checkOneStockBrand(scrapeUrl string) ([]DailyPrice, error) {
+
+	// 再試行で同じURLを訪問するため URL の再訪問を許可する
+	c := colly.NewCollector(colly.UserAgent(k.UserAgent), colly.AllowURLRevisit())
+	if k.RequestTimeout > 0 {
+		c.SetRequestTimeout(k.RequestTimeout)
+	}
+	var statusErr *StatusError
+	c.OnError(func(r *colly.Response, err error) {
+		statusErr = &StatusError{URL: scrapeUrl, StatusCode: r.StatusCode, Err: err}
+		if r.Headers != nil {
+			statusErr.RetryAfter = parseRetryAfter(r.Headers.Get("Retry-After"))
+		}
+	})
+
+	// データの取得 - テーブル
+	// 読めない行、四本値が不整合な行は除外し、除外理由を数える
+	// テーブルがあり行がない場合はページ送りの終わり、テーブル自体がない場合はレイアウト変更とする
+	var retValue []DailyPrice
+	validation := PageValidation{URL: scrapeUrl}
+	// <table class="stock_kabuka_dwm">
+	c.OnHTML(".stock_kabuka_dwm > tbody", func(e *colly.HTMLElement) {
+		validation.IsTableFound = true
+		e.ForEach("tr", func(_ int, el *colly.HTMLElement) {
+			validation.Total++
+			single, reason := parseDailyRow(el)
+			if reason == "" {
+				reason = ValidateDailyPrice(single)
+			}
+			if reason != "" {
+				validation.reject(reason)
+				return
+			}
+			retValue = append(retValue, single)
+		})
+	})
+
+	// Start scraping on https://XXX
+	if u, err := url.Parse(scrapeUrl); err == nil {
+		kabutanLimiter.wait(u.Host, k.Delay, k.RandomDelay)
+	}
+	if err := c.Visit(scrapeUrl); err != nil {
+		if statusErr != nil {
+			return nil, statusErr
+		}
+		return nil, err
+	}
+
+	if validation.Rejected > 0 {
+		slog.Info("Validation", "result", validation.String())
+	}
+	if validation.IsTableFound == false || validation.RejectRatio() > k.MaxRejectRatio {
+		return nil, &LayoutError{Validation: validation, MaxRejectRatio: k.MaxRejectRatio}
+	}
+	return retValue, nil
+}
+
+// 時系列テーブルの1行(日付, 始値, 高値, 安値, 終値, 前日比, 前日比％, 売買高)を読む
+// 四本値が読めなければ除外理由を返す。売買高は為替にはない(カラムがない、または －)ため、読めなければ 0 とする
+func parseDailyRow(el *colly.HTMLElement) (DailyPrice, string) {
+
+	var single DailyPrice
+	var err error
+
+	getStr := "20" + el.ChildText("th:nth-child(1)")
+	single.Date, err = convert.ConvertStringToTime(getStr)
+	if err != nil {
+		return single, RejectDate
+	}
+
+	columns := []struct {
+		selector string
+		value    *float64
+	}{
+		{"td:nth-child(2)", &single.Opening},
+		{"td:nth-child(3)", &single.High},
+		{"td:nth-child(4)", &single.Low},
+		{"td:nth-child(5)", &single.Closing},
+	}
+	for _, col := range columns {
+		getStr = strings.ReplaceAll(el.ChildText(col.selector), ",", "")
+		*col.value, err = strconv.ParseFloat(getStr, 64)
+		if err != nil {
+			return single, RejectParse
+		}
+	}
+	getStr = strings.ReplaceAll(el.ChildText("td:nth-child(8)"), ",", "")
+	if volume, err := strconv.ParseFloat(getStr, 64); err == nil {
+		single.Volume = volume
+	}
+	return single, ""
+}
+
+// Retry-After ヘッダ(秒数)を待ち時間に変換する
+func parseRetryAfter(str string) time.Duration {
+	sec, err := strconv.Atoi(strings.TrimSpace(str))
+	if err != nil || sec < 0 {
+		return 0
+	}
+	return time.Duration(sec) * time.Second
+}
+
+// host へのリクエストが可能になるまで待つ
+// 次回のリクエスト可能時刻を予約してから待つため、並列に呼ばれても間隔が保たれる
+func (l *hostLimiter) wait(host string, delay time.Duration, randomDelay time.Duration) {
+
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next[host]
+	if start.Before(now) {
+		start = now
+	}
+	interval := delay
+	if randomDelay > 0 {
+		interval += rand.N(randomDelay)
+	}
+	l.next[host] = start.Add(interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(start))
+}
@@ -0,0 +1,504 @@
+package datasource
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 記録済みの株探時系列ページ(testdata/kabutan/<code>_page<n>.html、週足・月足は <code>_<ashi>_page<n>.html)を返すテストサーバ
+// 該当ファイルがないページはデータ行のない表(empty.html)を返す
+// statuses に値がある間は、先頭から順にそのステータスコードでエラー応答する
+type kabutanFixtureServer struct {
+	*httptest.Server
+	mu         sync.Mutex
+	requests   []string
+	userAgents []string
+	statuses   []int
+}
+
+func newKabutanFixtureServer(t *testing.T) *kabutanFixtureServer {
+	t.Helper()
+	fs := &kabutanFixtureServer{}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := r.URL.Query().Get("code")
+		page := r.URL.Query().Get("page")
+		fixture := fmt.Sprintf("%s_page%s.html", code, page)
+		if ashi := r.URL.Query().Get("ashi"); ashi != string(BarDaily) {
+			fixture = fmt.Sprintf("%s_%s_page%s.html", code, ashi, page)
+			code = code + "_" + ashi
+		}
+		fs.mu.Lock()
+		fs.requests = append(fs.requests, fmt.Sprintf("%s/%s", code, page))
+		fs.userAgents = append(fs.userAgents, r.UserAgent())
+		status := 0
+		if len(fs.statuses) > 0 {
+			status, fs.statuses = fs.statuses[0], fs.statuses[1:]
+		}
+		fs.mu.Unlock()
+		if status != 0 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+
+		body, err := os.ReadFile(filepath.Join("testdata", "kabutan", fixture))
+		if os.IsNotExist(err) {
+			body, err = os.ReadFile(filepath.Join("testdata", "kabutan", "empty.html"))
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(body)
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// テスト用にリクエスト間隔と再試行の待ち時間を短くした取得元
+func (fs *kabutanFixtureServer) kabutan() *Kabutan {
+	k := NewKabutan()
+	k.BaseURL = fs.URL + "/stock/kabuka"
+	k.Delay = 0
+	k.RandomDelay = 0
+	k.BackoffBase = time.Millisecond
+	return k
+}
+
+func (fs *kabutanFixtureServer) requestedPages() []string {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return append([]string(nil), fs.requests...)
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+func TestKabutanFetchDailyParsesPages(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+
+	got, err := fs.kabutan().FetchDaily("2586", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("FetchDaily: %v", err)
+	}
+
+	// 本日分(stock_kabuka0)の行と日付が読めない行は含まれない
+	want := []DailyPrice{
+		{Date: date(2025, 5, 16), Opening: 167, High: 168, Low: 155, Closing: 155, Volume: 8988700},
+		{Date: date(2025, 5, 15), Opening: 160, High: 166, Low: 147, Closing: 159, Volume: 6658000},
+		{Date: date(2025, 5, 14), Opening: 155, High: 160, Low: 153, Closing: 160, Volume: 2612500},
+		{Date: date(2025, 5, 13), Opening: 160, High: 162, Low: 155, Closing: 156, Volume: 2738700},
+		{Date: date(2025, 5, 12), Opening: 161, High: 162, Low: 158, Closing: 159, Volume: 2243000},
+		{Date: date(2025, 5, 8), Opening: 175, High: 178, Low: 163, Closing: 164, Volume: 12794700},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].Date.Equal(want[i].Date) || got[i].Opening != want[i].Opening || got[i].High != want[i].High ||
+			got[i].Low != want[i].Low || got[i].Closing != want[i].Closing || got[i].Volume != want[i].Volume {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestKabutanFetchDailyStopsAtPaginationEnd(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+
+	if _, err := fs.kabutan().FetchDaily("2586", time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("FetchDaily: %v", err)
+	}
+
+	// 3ページ目はデータ行がないため、そこで終了し MaxPage までは辿らない
+	pages := fs.requestedPages()
+	want := []string{"2586/1", "2586/2", "2586/3"}
+	if fmt.Sprint(pages) != fmt.Sprint(want) {
+		t.Errorf("requested pages = %v, want %v", pages, want)
+	}
+}
+
+func TestKabutanFetchDailyStopsAtFromDate(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+
+	got, err := fs.kabutan().FetchDaily("2586", date(2025, 5, 14), time.Time{})
+	if err != nil {
+		t.Fatalf("FetchDaily: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d rows, want 3: %+v", len(got), got)
+	}
+	if !got[2].Date.Equal(date(2025, 5, 14)) {
+		t.Errorf("oldest row = %v, want 2025/05/14 (from is inclusive)", got[2].Date)
+	}
+	if pages := fs.requestedPages(); len(pages) != 1 {
+		t.Errorf("requested pages = %v, want only the first page", pages)
+	}
+}
+
+func TestKabutanFetchDailyEmptyTable(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+
+	got, err := fs.kabutan().FetchDaily("0000", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("FetchDaily: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %d rows from an empty table, want 0", len(got))
+	}
+	if pages := fs.requestedPages(); len(pages) != 1 {
+		t.Errorf("requested pages = %v, want only the first page", pages)
+	}
+}
+
+func TestCheckOneStockBrandCommaFormattedNumbers(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+
+	got, err := fs.kabutan().checkOneStockBrand(fs.URL + "/stock/kabuka?code=7203&ashi=day&page=1")
+	if err != nil {
+		t.Fatalf("checkOneStockBrand: %v", err)
+	}
+	want := []DailyPrice{
+		{Date: date(2025, 5, 16), Opening: 2745.5, High: 2760, Low: 2731.5, Closing: 2748, Volume: 21345600},
+		{Date: date(2025, 5, 15), Opening: 2701, High: 2740, Low: 2698, Closing: 2735.5, Volume: 25010300},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCheckOneStockBrandSkipsMalformedDate(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+
+	got, err := fs.kabutan().checkOneStockBrand(fs.URL + "/stock/kabuka?code=2586&ashi=day&page=2")
+	if err != nil {
+		t.Fatalf("checkOneStockBrand: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d rows, want 2 (malformed date row skipped): %+v", len(got), got)
+	}
+	for _, v := range got {
+		if v.Date.IsZero() {
+			t.Errorf("row with zero date was not skipped: %+v", v)
+		}
+	}
+}
+
+func TestKabutanRetriesTransientErrors(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+	fs.statuses = []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+
+	got, err := fs.kabutan().FetchDaily("7203", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("FetchDaily: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("got %d rows, want 2", len(got))
+	}
+	// 429, 503 の後に再試行で1ページ目を取得し、2ページ目(空)で終了する
+	want := []string{"7203/1", "7203/1", "7203/1", "7203/2"}
+	if pages := fs.requestedPages(); fmt.Sprint(pages) != fmt.Sprint(want) {
+		t.Errorf("requested pages = %v, want %v", pages, want)
+	}
+}
+
+func TestKabutanSurfacesErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantCode  int
+		wantTries int
+	}{
+		{"not found is not retried", []int{http.StatusNotFound}, http.StatusNotFound, 1},
+		{"server error after retries", []int{500, 502, 503, 504}, 504, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newKabutanFixtureServer(t)
+			fs.statuses = tt.statuses
+
+			got, err := fs.kabutan().FetchDaily("2586", time.Time{}, time.Time{})
+			if err == nil {
+				t.Fatalf("FetchDaily returned %d rows without error", len(got))
+			}
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantCode {
+				t.Errorf("err = %v, want StatusError with status %d", err, tt.wantCode)
+			}
+			if pages := fs.requestedPages(); len(pages) != tt.wantTries {
+				t.Errorf("requested pages = %v, want %d tries", pages, tt.wantTries)
+			}
+		})
+	}
+}
+
+func TestKabutanSendsUserAgent(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+	k := fs.kabutan()
+	k.UserAgent = "sv_stockanalysis-test/1.0"
+
+	if _, err := k.FetchDaily("7203", time.Time{}, time.Time{}); err != nil {
+		t.Fatalf("FetchDaily: %v", err)
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	for _, ua := range fs.userAgents {
+		if ua != k.UserAgent {
+			t.Errorf("User-Agent = %q, want %q", ua, k.UserAgent)
+		}
+	}
+}
+
+func TestHostLimiterSpacesRequests(t *testing.T) {
+	l := &hostLimiter{next: map[string]time.Time{}}
+	const delay = 20 * time.Millisecond
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		l.wait("example.com", delay, 0)
+	}
+	// 1回目は即時、2回目以降は delay ずつ間隔が空く
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("3 requests took %v, want at least %v", elapsed, 2*delay)
+	}
+
+	// 別ホストは待たされない
+	start = time.Now()
+	l.wait("example.org", delay, 0)
+	if elapsed := time.Since(start); elapsed >= delay {
+		t.Errorf("request to another host waited %v", elapsed)
+	}
+}
+
+func TestCheckOneStockBrandRejectsInvalidRows(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+
+	got, err := fs.kabutan().checkOneStockBrand(fs.URL + "/stock/kabuka?code=1111&ashi=day&page=1")
+	if err != nil {
+		t.Fatalf("checkOneStockBrand: %v", err)
+	}
+	// 売買停止(－)の行と終値が高値を上回る行は除外される
+	var dates []string
+	for _, v := range got {
+		dates = append(dates, v.Date.Format("2006/01/02"))
+	}
+	want := []string{"2025/04/11", "2025/04/08", "2025/04/07"}
+	if fmt.Sprint(dates) != fmt.Sprint(want) {
+		t.Errorf("dates = %v, want %v", dates, want)
+	}
+}
+
+func TestKabutanFetchDailyForexWithoutVolume(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+
+	// 為替は売買高のカラムがない(0970)、または － (0952)のため、売買高 0 として読む
+	tests := []struct {
+		code string
+		want []DailyPrice
+	}{
+		{"0970", []DailyPrice{
+			{Date: date(2025, 3, 28), Opening: 1.0798, High: 1.0845, Low: 1.0765, Closing: 1.0827},
+			{Date: date(2025, 3, 27), Opening: 1.0738, High: 1.0821, Low: 1.0733, Closing: 1.0798},
+			{Date: date(2025, 3, 26), Opening: 1.0794, High: 1.0802, Low: 1.0735, Closing: 1.0738},
+		}},
+		{"0952", []DailyPrice{
+			{Date: date(2025, 5, 16), Opening: 193.73, High: 193.83, Low: 193.05, Closing: 193.42},
+			{Date: date(2025, 5, 15), Opening: 194.45, High: 194.60, Low: 193.44, Closing: 193.72},
+			{Date: date(2025, 5, 14), Opening: 196.28, High: 196.40, Low: 194.24, Closing: 194.45},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := fs.kabutan().FetchDaily(tt.code, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("%s FetchDaily: %v", tt.code, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %d rows, want %d: %+v", tt.code, len(got), len(tt.want), got)
+		}
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s row %d = %+v, want %+v", tt.code, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestKabutanFetchDailyDetectsLayoutChange(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+
+	got, err := fs.kabutan().FetchDaily("2222", time.Time{}, time.Time{})
+	if !errors.Is(err, ErrLayoutChanged) {
+		t.Fatalf("err = %v (rows %d), want ErrLayoutChanged", err, len(got))
+	}
+	var layoutErr *LayoutError
+	if !errors.As(err, &layoutErr) {
+		t.Fatalf("err = %v, want *LayoutError", err)
+	}
+	if v := layoutErr.Validation; v.Total != 3 || v.Rejected != 3 || v.Reasons[RejectParse] != 3 {
+		t.Errorf("validation = %+v, want 3/3 rows rejected by parse", v)
+	}
+	// レイアウト変更は再試行しない
+	if pages := fs.requestedPages(); len(pages) != 1 {
+		t.Errorf("requested pages = %v, want 1", pages)
+	}
+}
+
+func TestKabutanFetchDailyDetectsMissingTable(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+
+	// テーブルのクラス名が変わると行を読めないが、ページ送りの終わりとはせずレイアウト変更とする
+	got, err := fs.kabutan().FetchDaily("3333", time.Time{}, time.Time{})
+	if !errors.Is(err, ErrLayoutChanged) {
+		t.Fatalf("err = %v (rows %d), want ErrLayoutChanged", err, len(got))
+	}
+	var layoutErr *LayoutError
+	if !errors.As(err, &layoutErr) || layoutErr.Validation.IsTableFound || layoutErr.Validation.Total != 0 {
+		t.Errorf("err = %#v, want *LayoutError without the table", err)
+	}
+	if !strings.Contains(err.Error(), "table not found") {
+		t.Errorf("err = %v, want table not found", err)
+	}
+	if pages := fs.requestedPages(); len(pages) != 1 {
+		t.Errorf("requested pages = %v, want 1", pages)
+	}
+}
+
+func TestValidateDailyPrice(t *testing.T) {
+	tests := []struct {
+		name  string
+		price DailyPrice
+		want  string
+	}{
+		{"valid", DailyPrice{Opening: 100, High: 110, Low: 95, Closing: 105}, ""},
+		{"flat", DailyPrice{Opening: 100, High: 100, Low: 100, Closing: 100}, ""},
+		{"zero opening", DailyPrice{Opening: 0, High: 110, Low: 95, Closing: 105}, RejectZero},
+		{"zero closing", DailyPrice{Opening: 100, High: 110, Low: 95, Closing: 0}, RejectZero},
+		{"high below low", DailyPrice{Opening: 100, High: 90, Low: 95, Closing: 92}, RejectHighLow},
+		{"opening above high", DailyPrice{Opening: 111, High: 110, Low: 95, Closing: 105}, RejectOpeningRange},
+		{"closing below low", DailyPrice{Opening: 100, High: 110, Low: 95, Closing: 94}, RejectClosingRange},
+		{"closing above high", DailyPrice{Opening: 100, High: 110, Low: 95, Closing: 111}, RejectClosingRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateDailyPrice(tt.price); got != tt.want {
+				t.Errorf("ValidateDailyPrice(%+v) = %q, want %q", tt.price, got, tt.want)
+			}
+		})
+	}
+}
+
+// Backfill の onPage に渡されたページ番号と日付
+type backfillPage struct {
+	page  int
+	dates []string
+}
+
+func collectBackfill(k *Kabutan, code string, bar BarType, start time.Time, startPage int) ([]backfillPage, error) {
+	var pages []backfillPage
+	err := k.Backfill(code, bar, start, startPage, func(page int, prices []DailyPrice) error {
+		p := backfillPage{page: page}
+		for _, v := range prices {
+			p.dates = append(p.dates, v.Date.Format("2006/01/02"))
+		}
+		pages = append(pages, p)
+		return nil
+	})
+	return pages, err
+}
+
+func TestKabutanBackfill(t *testing.T) {
+	tests := []struct {
+		name         string
+		code         string
+		bar          BarType
+		start        time.Time
+		startPage    int
+		wantPages    string
+		wantRequests []string
+	}{
+		{
+			name:         "walks until the table is empty",
+			code:         "2586",
+			bar:          BarDaily,
+			startPage:    1,
+			wantPages:    "[{1 [2025/05/16 2025/05/15 2025/05/14 2025/05/13]} {2 [2025/05/12 2025/05/08]}]",
+			wantRequests: []string{"2586/1", "2586/2", "2586/3"},
+		},
+		{
+			name:         "stops at the start date",
+			code:         "2586",
+			bar:          BarDaily,
+			start:        date(2025, 5, 13),
+			startPage:    1,
+			wantPages:    "[{1 [2025/05/16 2025/05/15 2025/05/14 2025/05/13]} {2 []}]",
+			wantRequests: []string{"2586/1", "2586/2"},
+		},
+		{
+			name:         "resumes from a checkpointed page",
+			code:         "2586",
+			bar:          BarDaily,
+			startPage:    2,
+			wantPages:    "[{2 [2025/05/12 2025/05/08]}]",
+			wantRequests: []string{"2586/2", "2586/3"},
+		},
+		{
+			name:         "weekly view",
+			code:         "2586",
+			bar:          BarWeekly,
+			start:        date(2025, 5, 1),
+			startPage:    1,
+			wantPages:    "[{1 [2025/05/12 2025/05/07]}]",
+			wantRequests: []string{"2586_wek/1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newKabutanFixtureServer(t)
+
+			pages, err := collectBackfill(fs.kabutan(), tt.code, tt.bar, tt.start, tt.startPage)
+			if err != nil {
+				t.Fatalf("Backfill: %v", err)
+			}
+			if got := fmt.Sprint(pages); got != tt.wantPages {
+				t.Errorf("pages = %s, want %s", got, tt.wantPages)
+			}
+			if got := fs.requestedPages(); fmt.Sprint(got) != fmt.Sprint(tt.wantRequests) {
+				t.Errorf("requested pages = %v, want %v", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestKabutanBackfillStopsOnError(t *testing.T) {
+	fs := newKabutanFixtureServer(t)
+
+	// 1ページ目は成功、2ページ目で失敗する。1ページ目は onPage に渡されているため次回は2ページ目から再開できる
+	var calls []int
+	err := fs.kabutan().Backfill("2586", BarDaily, time.Time{}, 1, func(page int, prices []DailyPrice) error {
+		calls = append(calls, page)
+		fs.mu.Lock()
+		fs.statuses = []int{http.StatusNotFound}
+		fs.mu.Unlock()
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Backfill returned nil, want error")
+	}
+	if fmt.Sprint(calls) != "[1]" {
+		t.Errorf("onPage called for pages %v, want [1]", calls)
+	}
+}
@@ -0,0 +1,109 @@
+// datasource 株価データ取得元パッケージ
+package datasource // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ---- const
+
+// 除外理由
+const (
+	RejectDate         = "date"          // 日付が読めない
+	RejectParse        = "parse"         // 数値が読めない
+	RejectZero         = "zero"          // 四本値に0以下の値がある
+	RejectHighLow      = "high<low"      // 高値が安値を下回る
+	RejectOpeningRange = "opening-range" // 始値が[安値, 高値]の範囲外
+	RejectClosingRange = "closing-range" // 終値が[安値, 高値]の範囲外
+)
+
+// 除外行の割合がこれを超えるとページのレイアウト変更とみなす
+const DefaultMaxRejectRatio = 0.5
+
+// ---- struct
+
+// PageValidation 1ページ分のスクレイピング結果の検証結果
+type PageValidation struct {
+	URL          string         // 対象ページ
+	IsTableFound bool           // 時系列のテーブルがあったか(ない場合はレイアウト変更とみなす)
+	Total        int            // データ行数
+	Rejected     int            // 除外した行数
+	Reasons      map[string]int // 除外理由毎の件数
+}
+
+// LayoutError 時系列のテーブルがない、または除外行の割合が閾値を超え、ページのレイアウト変更が疑われる際のエラー
+type LayoutError struct {
+	Validation     PageValidation // 該当ページの検証結果
+	MaxRejectRatio float64        // 閾値
+}
+
+// ---- Global Variable
+
+// ErrLayoutChanged LayoutError を errors.Is で判定するためのエラー
+var ErrLayoutChanged = errors.New("layout changed")
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// RejectRatio (public)データ行に占める除外行の割合を返す
+func (v *PageValidation) RejectRatio() float64 {
+	if v.Total == 0 {
+		return 0
+	}
+	return float64(v.Rejected) / float64(v.Total)
+}
+
+// String (public)除外理由毎の件数を文字列にする
+func (v *PageValidation) String() string {
+	var reasons []string
+	for _, k := range slices.Sorted(maps.Keys(v.Reasons)) {
+		reasons = append(reasons, fmt.Sprintf("%s=%d", k, v.Reasons[k]))
+	}
+	return fmt.Sprintf("%s rejected %d/%d rows [%s]", v.URL, v.Rejected, v.Total, strings.Join(reasons, " "))
+}
+
+// Error (public)エラー文字列を返す
+func (e *LayoutError) Error() string {
+	if e.Validation.IsTableFound == false {
+		return fmt.Sprintf("layout changed: %s table not found", e.Validation.URL)
+	}
+	return fmt.Sprintf("layout changed: %s (ratio %.2f > %.2f)", e.Validation.String(), e.Validation.RejectRatio(), e.MaxRejectRatio)
+}
+
+// Is (public)ErrLayoutChanged と一致とみなす
+func (e *LayoutError) Is(target error) bool {
+	return target == ErrLayoutChanged
+}
+
+// ValidateDailyPrice (public)四本値の整合性を検証し、問題があれば除外理由を返す(問題なければ空文字)
+func ValidateDailyPrice(p DailyPrice) string {
+	if p.Opening <= 0 || p.High <= 0 || p.Low <= 0 || p.Closing <= 0 {
+		return RejectZero
+	}
+	if p.High < p.Low {
+		return RejectHighLow
+	}
+	if p.Opening < p.Low || p.Opening > p.High {
+		return RejectOpeningRange
+	}
+	if p.Closing < p.Low || p.Closing > p.High {
+		return RejectClosingRange
+	}
+	return ""
+}
+
+//---- private function ----
+
+// 除外行を記録する
+func (v *PageValidation) reject(reason string) {
+	if v.Reasons == nil {
+		v.Reasons = map[string]int{}
+	}
+	v.Rejected++
+	v.Reasons[reason]++
+}
@@ -43,7 +43,7 @@ func FileIoRead(filename string) ([]byte, error) {
 // FileIoWrite (public)ファイルを一括で書き込む
 func FileIoWrite(filename string, fileContents []byte, isAppend bool) error {
 
-	flag := os.O_WRONLY | os.O_CREATE
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
 	if isAppend == true {
 		flag = os.O_RDWR | os.O_CREATE | os.O_APPEND
 	}
@@ -84,7 +84,7 @@ func FileIoCsvRead(filename string) ([][]string, error) {
 // FileIoCsvWrite (public)Csvファイルを一括で書き込む
 func FileIoCsvWrite(filename string, csvContents [][]string, isAppend bool) error {
 
-	flag := os.O_WRONLY | os.O_CREATE
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
 	if isAppend == true {
 		flag = os.O_RDWR | os.O_CREATE | os.O_APPEND
 	}
@@ -0,0 +1,122 @@
+// forecast 時系列の予測モデルパッケージ
+package forecast // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"math"
+
+	"sv_stockcheck/arima"
+)
+
+// ---- struct
+
+// ARIMA 系列の1期の差分に ARIMA(Order) を当てはめ、差分の予測を最後の値に積み上げるモデル
+// (arima_insights.py と同じく、差分しない次数では差分の平均(ドリフト)を推定する)
+type ARIMA struct {
+	levelColumns
+	Order arima.Order
+	Model *arima.Model // 差分の系列に当てはめたモデル
+
+	last float64
+}
+
+// AutoARIMA 当てはめる系列の差分から探索範囲 Space で次数を選び直す ARIMA
+// 次数は最初の Fit と、前回選択した系列から Reselect 本以上増えた(または短くなった)系列の Fit で選択し、それ以外は前回の次数で当てはめる
+// 選択には Fit に渡した系列のみを用いるため、予測の起点より後のデータは次数に影響しない
+type AutoARIMA struct {
+	ARIMA
+	Space    arima.SearchSpace
+	Reselect int                                       // 次数を選び直す間隔(系列の本数。1 以下は Fit 毎に選択する)
+	Selector func(diff []float64) (arima.Order, error) // 差分の系列から次数を選ぶ関数(nil は Space で AutoSelect する。保存した次数の再利用などに差し替える)
+
+	selectedLength int // 前回次数を選択した系列の本数(0 は未選択)
+}
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// NewARIMA (public)ARIMA を作成する
+func NewARIMA(order arima.Order) *ARIMA {
+	return &ARIMA{levelColumns: levelColumns{name: "ARIMA"}, Order: order}
+}
+
+// NewAutoARIMA (public)探索範囲 space の中から reselect 本毎に次数を選び直す AutoARIMA を作成する
+func NewAutoARIMA(space arima.SearchSpace, reselect int) *AutoARIMA {
+	return &AutoARIMA{ARIMA: *NewARIMA(arima.Order{}), Space: space, Reselect: reselect}
+}
+
+// Fit (public)系列の1期の差分(先頭は欠測値)に ARIMA を最尤推定で当てはめる。NaN は欠測値として扱う
+func (f *ARIMA) Fit(history []float64) error {
+
+	model, err := arima.Fit(differences(history), f.Order)
+	if err != nil {
+		return err
+	}
+	f.Model = model
+	f.last = math.NaN()
+	if len(history) > 0 {
+		f.last = history[len(history)-1]
+	}
+	return nil
+}
+
+// Fit (public)必要な場合は系列の差分で次数を選び直してから、ARIMA を当てはめる
+func (f *AutoARIMA) Fit(history []float64) error {
+
+	if f.selectedLength == 0 || len(history) < f.selectedLength || len(history)-f.selectedLength >= f.Reselect {
+		order, err := f.selectOrder(differences(history))
+		if err != nil {
+			return err
+		}
+		f.Order = order
+		f.selectedLength = len(history)
+	}
+	return f.ARIMA.Fit(history)
+}
+
+// Predict (public)差分の予測を最後の値に積み上げて予測値とする
+// 誤差の分散は差分の MA(∞) 表現の係数を累積した値から求める
+func (f *ARIMA) Predict(steps int) []Prediction {
+
+	diffs := f.Model.Forecast(steps)
+	psi := f.Model.Psi(steps)
+	predictions := make([]Prediction, steps)
+	value, cumulative, variance := f.last, 0.0, 0.0
+	for h := range predictions {
+		value += diffs[h]
+		cumulative += psi[h]
+		variance += cumulative * cumulative
+		predictions[h] = Prediction{Value: value, StdDev: math.Sqrt(f.Model.Sigma2 * variance)}
+	}
+	return predictions
+}
+
+//---- private function ----
+
+// 差分の系列から次数を選ぶ
+func (f *AutoARIMA) selectOrder(diff []float64) (arima.Order, error) {
+	if f.Selector != nil {
+		return f.Selector(diff)
+	}
+	selection, err := arima.AutoSelect(diff, f.Space)
+	if err != nil {
+		return arima.Order{}, err
+	}
+	return selection.Order, nil
+}
+
+// 系列の1期の差分(先頭は欠測値)
+func differences(history []float64) []float64 {
+
+	diff := make([]float64, len(history))
+	for t := range diff {
+		if t == 0 {
+			diff[t] = math.NaN()
+			continue
+		}
+		diff[t] = history[t] - history[t-1]
+	}
+	return diff
+}
@@ -0,0 +1,161 @@
+// forecast 時系列の予測モデルパッケージ
+package forecast // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"fmt"
+	"math"
+)
+
+// 引数の時系列は全て日付昇順(index 0 が最古)とする(arima パッケージと同じ)
+// 予測モデルは Fit で系列に当てはめた後、Predict で系列の最後の値の次の時点から steps 期先までを予測する
+// 推定した状態を保持するため、複数の系列を並行して予測する場合は系列毎にモデルを作成する
+
+// ---- struct
+
+// Prediction 1期分の予測
+type Prediction struct {
+	Value  float64 // 予測値
+	StdDev float64 // 予測誤差の標準偏差
+}
+
+// Forecaster 予測モデル
+type Forecaster interface {
+	Name() string                   // 予測モデルの名前
+	Fit(history []float64) error    // 日付昇順の系列に当てはめる
+	Predict(steps int) []Prediction // 系列の最後の値の次の時点から steps 期先までの予測
+	ColumnNames() []string          // ModelData に出力する予測値と残差のカラム名
+	// 1期先の予測 prediction、予測に用いた系列の最後の値 last、実績 actual から ColumnNames のカラムの値を求める
+	ColumnValues(prediction Prediction, last float64, actual float64) []float64
+}
+
+// 系列の値を予測するモデルのカラム(<名前>Predict: 予測値、<名前>PredictDiff: 実績 - 予測値)
+type levelColumns struct {
+	name string
+}
+
+// Naive 最後の値をそのまま予測値とするモデル(ランダムウォーク)
+type Naive struct {
+	levelColumns
+	Sigma2 float64 // 1期の差分の分散
+
+	last float64
+}
+
+// Drift 最初と最後の値を結ぶ直線の傾き(差分の平均)を最後の値に積み上げるモデル(ドリフト付きランダムウォーク)
+type Drift struct {
+	levelColumns
+	Slope  float64 // 1期あたりの変化(差分の平均)
+	Sigma2 float64 // 差分の傾きからの誤差の分散
+
+	last float64
+	n    int
+}
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// NewNaive (public)Naive を作成する
+func NewNaive() *Naive {
+	return &Naive{levelColumns: levelColumns{name: "Naive"}}
+}
+
+// Fit (public)差分の分散を推定する
+func (f *Naive) Fit(history []float64) error {
+
+	if err := checkHistory(f.name, history, 2); err != nil {
+		return err
+	}
+	sum := 0.0
+	for t := 1; t < len(history); t++ {
+		d := history[t] - history[t-1]
+		sum += d * d
+	}
+	f.Sigma2 = sum / float64(len(history)-1)
+	f.last = history[len(history)-1]
+	return nil
+}
+
+// Predict (public)最後の値を予測値とし、h 期先の誤差の分散は h 期分の差分の分散とする
+func (f *Naive) Predict(steps int) []Prediction {
+	predictions := make([]Prediction, steps)
+	for h := range predictions {
+		predictions[h] = Prediction{Value: f.last, StdDev: math.Sqrt(f.Sigma2 * float64(h+1))}
+	}
+	return predictions
+}
+
+// NewDrift (public)Drift を作成する
+func NewDrift() *Drift {
+	return &Drift{levelColumns: levelColumns{name: "Drift"}}
+}
+
+// Fit (public)差分の平均と、その周りの分散を推定する
+func (f *Drift) Fit(history []float64) error {
+
+	if err := checkHistory(f.name, history, 3); err != nil {
+		return err
+	}
+	n := len(history)
+	f.Slope = (history[n-1] - history[0]) / float64(n-1)
+	sum := 0.0
+	for t := 1; t < n; t++ {
+		e := history[t] - history[t-1] - f.Slope
+		sum += e * e
+	}
+	f.Sigma2 = sum / float64(n-2)
+	f.last, f.n = history[n-1], n
+	return nil
+}
+
+// Predict (public)最後の値に傾きを積み上げて予測値とし、誤差の分散には傾きの推定誤差を含める
+func (f *Drift) Predict(steps int) []Prediction {
+	predictions := make([]Prediction, steps)
+	for h := range predictions {
+		k := float64(h + 1)
+		predictions[h] = Prediction{Value: f.last + k*f.Slope, StdDev: math.Sqrt(f.Sigma2 * k * (1 + k/float64(f.n-1)))}
+	}
+	return predictions
+}
+
+// Name (public)予測モデルの名前
+func (c levelColumns) Name() string {
+	return c.name
+}
+
+// ColumnNames (public)予測値と残差(実績 - 予測値)のカラム名
+func (c levelColumns) ColumnNames() []string {
+	return []string{c.name + "Predict", c.name + "PredictDiff"}
+}
+
+// ColumnValues (public)予測値と残差(実績 - 予測値)
+func (c levelColumns) ColumnValues(prediction Prediction, last float64, actual float64) []float64 {
+	return []float64{prediction.Value, actual - prediction.Value}
+}
+
+//---- private function ----
+
+// 系列が minimum 個以上の値を持ち、欠測値(NaN)を含まないことを確認する
+func checkHistory(name string, history []float64, minimum int) error {
+	if len(history) < minimum {
+		return fmt.Errorf("%s needs %d observations, got %d", name, minimum, len(history))
+	}
+	for t, v := range history {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%s: history[%d] is not a number", name, t)
+		}
+	}
+	return nil
+}
+
+// 実数全体を (0, 1) に写す
+func logistic(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// logistic の逆関数
+func logit(p float64) float64 {
+	return math.Log(p / (1 - p))
+}
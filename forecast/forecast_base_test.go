@@ -0,0 +1,276 @@
+package forecast
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"sv_stockcheck/arima"
+)
+
+var _ Forecaster = (*Naive)(nil)
+var _ Forecaster = (*Drift)(nil)
+var _ Forecaster = (*ARIMA)(nil)
+var _ Forecaster = (*AutoARIMA)(nil)
+var _ Forecaster = (*HoltWinters)(nil)
+var _ Forecaster = (*GARCH)(nil)
+
+// 予測値と誤差の標準偏差を比較する
+func checkPredictions(t *testing.T, name string, got []Prediction, want []Prediction, tol float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: len = %d, want %d", name, len(got), len(want))
+	}
+	for h := range want {
+		if math.Abs(got[h].Value-want[h].Value) > tol || math.Abs(got[h].StdDev-want[h].StdDev) > tol {
+			t.Errorf("%s: prediction[%d] = %+v, want %+v", name, h, got[h], want[h])
+		}
+	}
+}
+
+func TestNaiveDrift(t *testing.T) {
+	history := []float64{10, 12, 11, 15, 14}
+
+	naive := NewNaive()
+	if err := naive.Fit(history); err != nil {
+		t.Fatal(err)
+	}
+	// 差分 2, -1, 4, -1 の二乗平均は 22/4
+	sigma := math.Sqrt(22.0 / 4)
+	checkPredictions(t, "naive", naive.Predict(2), []Prediction{{14, sigma}, {14, sigma * math.Sqrt(2)}}, 1e-12)
+
+	drift := NewDrift()
+	if err := drift.Fit(history); err != nil {
+		t.Fatal(err)
+	}
+	// 傾き 1、傾きからの誤差 1, -2, 3, -2 の二乗和 18 を自由度 3 で割る
+	sigma2 := 18.0 / 3
+	checkPredictions(t, "drift", drift.Predict(2), []Prediction{
+		{15, math.Sqrt(sigma2 * 1 * (1 + 1.0/4))},
+		{16, math.Sqrt(sigma2 * 2 * (1 + 2.0/4))},
+	}, 1e-12)
+
+	if got := drift.ColumnValues(Prediction{Value: 15}, 14, 16); got[0] != 15 || got[1] != 1 {
+		t.Errorf("ColumnValues = %v, want [15 1]", got)
+	}
+	if names := drift.ColumnNames(); names[0] != "DriftPredict" || names[1] != "DriftPredictDiff" {
+		t.Errorf("ColumnNames = %v", names)
+	}
+	if err := drift.Fit([]float64{1, math.NaN(), 3}); err == nil {
+		t.Error("Fit with NaN expected error")
+	}
+	if err := naive.Fit([]float64{1}); err == nil {
+		t.Error("Fit with 1 observation expected error")
+	}
+}
+
+func TestARIMA(t *testing.T) {
+	// 差分がホワイトノイズ(ARIMA(0,0,0) の平均)の場合はドリフト付きランダムウォークの予測
+	random := rand.New(rand.NewPCG(1, 1))
+	history := make([]float64, 500)
+	for i := range history {
+		history[i] = 100 + 0.2*float64(i) + random.NormFloat64()
+		if i > 0 {
+			history[i] = history[i-1] + 0.2 + random.NormFloat64()
+		}
+	}
+	f := NewARIMA(arima.Order{})
+	if err := f.Fit(history); err != nil {
+		t.Fatal(err)
+	}
+	if f.Name() != "ARIMA" || f.ColumnNames()[0] != "ARIMAPredict" {
+		t.Errorf("Name = %s, ColumnNames = %v", f.Name(), f.ColumnNames())
+	}
+	last, mean, sigma := history[len(history)-1], f.Model.Mean, math.Sqrt(f.Model.Sigma2)
+	checkPredictions(t, "ARIMA(0,0,0)", f.Predict(3), []Prediction{
+		{last + mean, sigma},
+		{last + 2*mean, sigma * math.Sqrt(2)},
+		{last + 3*mean, sigma * math.Sqrt(3)},
+	}, 1e-9)
+
+	// AR(1) の差分の予測は最後の値に φ^h を掛けた差分を積み上げ、誤差の係数は 1, 1+φ, 1+φ+φ², ...
+	f = NewARIMA(arima.Order{P: 1})
+	if err := f.Fit(history); err != nil {
+		t.Fatal(err)
+	}
+	phi, mean := f.Model.AR[0], f.Model.Mean
+	lastDiff := history[len(history)-1] - history[len(history)-2]
+	value, deviation, psi, variance := last, lastDiff-mean, 0.0, 0.0
+	var want []Prediction
+	for h := 0; h < 3; h++ {
+		deviation *= phi
+		value += mean + deviation
+		psi += math.Pow(phi, float64(h))
+		variance += psi * psi
+		want = append(want, Prediction{value, math.Sqrt(f.Model.Sigma2 * variance)})
+	}
+	checkPredictions(t, "ARIMA(1,0,0)", f.Predict(3), want, 1e-9)
+}
+
+func TestAutoARIMA(t *testing.T) {
+	// AR(1) の差分(φ = 0.6)の系列
+	random := rand.New(rand.NewPCG(2, 2))
+	history := make([]float64, 200)
+	history[0] = 100
+	diff := 0.0
+	for i := 1; i < len(history); i++ {
+		diff = 0.6*diff + random.NormFloat64()
+		history[i] = history[i-1] + diff
+	}
+	space := arima.SearchSpace{MaxP: 2, MaxD: 1, MaxQ: 1, Criterion: arima.CriterionAIC}
+	selected := func(n int) arima.Order {
+		selection, err := arima.AutoSelect(differences(history[:n]), space)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return selection.Order
+	}
+
+	// 次数は Fit に渡した系列のみで選択し、Reselect 本増えるまでは前回の次数で当てはめる
+	f := NewAutoARIMA(space, 50)
+	if f.Name() != "ARIMA" || f.ColumnNames()[0] != "ARIMAPredict" {
+		t.Errorf("Name = %s, ColumnNames = %v", f.Name(), f.ColumnNames())
+	}
+	for _, step := range []struct {
+		length       int
+		wantSelected int // 次数を選択した系列の本数
+	}{
+		{100, 100},
+		{149, 100},
+		{150, 150},
+		{199, 150},
+		{120, 120}, // 短くなった場合は選び直す
+	} {
+		if err := f.Fit(history[:step.length]); err != nil {
+			t.Fatal(err)
+		}
+		if f.selectedLength != step.wantSelected || f.Order != selected(step.wantSelected) {
+			t.Errorf("Fit(%d): order %s selected at %d, want %s at %d", step.length, f.Order, f.selectedLength, selected(step.wantSelected), step.wantSelected)
+		}
+		if f.Model.Order != f.Order || len(f.Predict(2)) != 2 {
+			t.Errorf("Fit(%d): model order = %s, want %s", step.length, f.Model.Order, f.Order)
+		}
+	}
+
+	// 選択に必要な本数に満たない系列はエラー
+	if err := NewAutoARIMA(space, 50).Fit(history[:10]); err == nil {
+		t.Error("Fit of 10 values expected error")
+	}
+}
+
+func TestHoltWinters(t *testing.T) {
+	// 線形トレンドと周期7の季節成分の系列はほぼ誤差なく予測できる
+	pattern := []float64{3, -1, 0, 2, -2, -1, -1}
+	var history []float64
+	for i := 0; i < 70; i++ {
+		history = append(history, 50+0.5*float64(i)+pattern[i%7])
+	}
+	f, err := NewHoltWinters(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Fit(history); err != nil {
+		t.Fatal(err)
+	}
+	for h, p := range f.Predict(10) {
+		i := len(history) + h
+		if want := 50 + 0.5*float64(i) + pattern[i%7]; math.Abs(p.Value-want) > 1e-4 {
+			t.Errorf("seasonal prediction[%d] = %v, want %v", h, p.Value, want)
+		}
+	}
+
+	// 季節項なし(Holt の線形トレンド法)で推定した平滑化係数は制約の範囲内
+	random := rand.New(rand.NewPCG(2, 2))
+	history = history[:0]
+	for i := 0; i < 300; i++ {
+		history = append(history, 0.3*float64(i)+random.NormFloat64())
+	}
+	f, _ = NewHoltWinters(0)
+	if err := f.Fit(history); err != nil {
+		t.Fatal(err)
+	}
+	if !(0 < f.Alpha && f.Alpha < 1 && 0 < f.Beta && f.Beta < f.Alpha && f.Gamma == 0) {
+		t.Errorf("alpha = %v, beta = %v, gamma = %v", f.Alpha, f.Beta, f.Gamma)
+	}
+	// 推定した平滑化係数の誤差の二乗和は周りの係数より小さい
+	sse, _, _, _ := f.smooth(history, f.Alpha, f.Beta, 0)
+	for _, scale := range []float64{0.8, 1.2} {
+		if other, _, _, _ := f.smooth(history, f.Alpha*scale, f.Beta, 0); other < sse {
+			t.Errorf("alpha %v has smaller SSE %v than %v", f.Alpha*scale, other, sse)
+		}
+		if other, _, _, _ := f.smooth(history, f.Alpha, f.Beta*scale, 0); other < sse {
+			t.Errorf("beta %v has smaller SSE %v than %v", f.Beta*scale, other, sse)
+		}
+	}
+	predictions := f.Predict(3)
+	if step := predictions[2].Value - predictions[1].Value; math.Abs(step-f.trend) > 1e-9 {
+		t.Errorf("prediction step = %v, want trend %v", step, f.trend)
+	}
+	// h 期先の誤差の分散は σ² (1 + Σ (α + jβ)²)
+	c1, c2 := f.Alpha+f.Beta, f.Alpha+2*f.Beta
+	if want := math.Sqrt(f.Sigma2 * (1 + c1*c1 + c2*c2)); math.Abs(predictions[2].StdDev-want) > 1e-12 {
+		t.Errorf("std dev = %v, want %v", predictions[2].StdDev, want)
+	}
+
+	if _, err := NewHoltWinters(1); err == nil {
+		t.Error("NewHoltWinters(1) expected error")
+	}
+	f, _ = NewHoltWinters(7)
+	if err := f.Fit(history[:16]); err == nil {
+		t.Error("Fit with 16 observations expected error")
+	}
+}
+
+func TestGARCH(t *testing.T) {
+	// 百分率の収益率で ω = 0.05、α = 0.1、β = 0.85 の GARCH(1,1)
+	random := rand.New(rand.NewPCG(3, 3))
+	omega, alpha, beta := 0.05, 0.1, 0.85
+	variance := omega / (1 - alpha - beta)
+	history := []float64{1000}
+	for i := 0; i < 5000; i++ {
+		e := math.Sqrt(variance) * random.NormFloat64()
+		history = append(history, history[len(history)-1]*math.Exp((0.02+e)/100))
+		variance = omega + alpha*e*e + beta*variance
+	}
+	f := NewGARCH()
+	if err := f.Fit(history); err != nil {
+		t.Fatal(err)
+	}
+	checks := []struct {
+		name string
+		got  float64
+		want float64
+		tol  float64
+	}{
+		{"mu", f.Mu * 100, 0.02, 0.02},
+		{"omega", f.Omega * 10000, omega, 0.02},
+		{"alpha", f.Alpha, alpha, 0.03},
+		{"beta", f.Beta, beta, 0.04},
+	}
+	for _, c := range checks {
+		if math.Abs(c.got-c.want) > c.tol {
+			t.Errorf("%s = %v, want %v±%v", c.name, c.got, c.want, c.tol)
+		}
+	}
+
+	// 条件付き分散の予測は無条件分散に近づく
+	last := history[len(history)-1]
+	predictions := f.Predict(500)
+	unconditional := f.Omega / (1 - f.Alpha - f.Beta)
+	step := predictions[499].StdDev*predictions[499].StdDev - predictions[498].StdDev*predictions[498].StdDev
+	if math.Abs(step/(last*last)-unconditional) > 1e-3*unconditional {
+		t.Errorf("long horizon variance = %v, want %v", step/(last*last), unconditional)
+	}
+	if math.Abs(predictions[0].Value-last*math.Exp(f.Mu)) > 1e-9 {
+		t.Errorf("prediction = %v, want %v", predictions[0].Value, last*math.Exp(f.Mu))
+	}
+
+	// ボラティリティと標準化した残差
+	values := f.ColumnValues(Prediction{Value: 100, StdDev: 2}, 100, 100*math.Exp(0.03))
+	if math.Abs(values[0]-0.02) > 1e-12 || math.Abs(values[1]-1.5) > 1e-12 {
+		t.Errorf("ColumnValues = %v, want [0.02 1.5]", values)
+	}
+	if err := f.Fit([]float64{1, 2, 3}); err == nil {
+		t.Error("Fit with 3 observations expected error")
+	}
+}
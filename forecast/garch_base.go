@@ -0,0 +1,132 @@
+// forecast 時系列の予測モデルパッケージ
+package forecast // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"fmt"
+	"math"
+
+	"sv_stockcheck/optimize"
+)
+
+// 対数収益率 r_t = log(y_t / y_t-1) の GARCH(1,1)
+//   r_t = μ + ε_t、ε_t = σ_t z_t (z_t は標準正規分布)、σ²_t = ω + α ε²_t-1 + β σ²_t-1
+// 正規分布の尤度を最大化して推定する(ω > 0、α > 0、β > 0、α + β < 1 に制約し、σ²_1 は標本分散とする)
+// 収益率は数値の安定のため百分率で推定する
+
+// ---- const
+
+const garchEvaluations = 1000   // 推定の目的関数の評価回数の上限(パラメータ1個あたり)
+const minGarchObservations = 10 // 推定に必要な収益率の数
+
+// ---- struct
+
+// GARCH 対数収益率の GARCH(1,1) で条件付き分散(ボラティリティ)を予測するモデル
+// 予測値は最後の値に収益率の平均を積み上げた値とする
+type GARCH struct {
+	Mu    float64 // 収益率の平均
+	Omega float64 // 条件付き分散の定数項
+	Alpha float64 // 前期の誤差の二乗の係数
+	Beta  float64 // 前期の条件付き分散の係数
+
+	last     float64
+	variance float64 // 最後の値の次の時点の条件付き分散
+}
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// NewGARCH (public)GARCH を作成する
+func NewGARCH() *GARCH {
+	return &GARCH{}
+}
+
+// Name (public)予測モデルの名前
+func (f *GARCH) Name() string {
+	return "GARCH"
+}
+
+// ColumnNames (public)1期先の収益率の標準偏差(ボラティリティ)と標準化した残差のカラム名
+func (f *GARCH) ColumnNames() []string {
+	return []string{"GARCHVolatility", "GARCHResidual"}
+}
+
+// ColumnValues (public)1期先の収益率の標準偏差と、実績の収益率の予測からの誤差を標準偏差で割った値
+func (f *GARCH) ColumnValues(prediction Prediction, last float64, actual float64) []float64 {
+	volatility := prediction.StdDev / last
+	return []float64{volatility, math.Log(actual/prediction.Value) / volatility}
+}
+
+// Fit (public)系列の対数収益率に GARCH(1,1) を最尤推定で当てはめる。系列の値は正とする
+func (f *GARCH) Fit(history []float64) error {
+
+	if err := checkHistory(f.Name(), history, minGarchObservations+1); err != nil {
+		return err
+	}
+	returns := make([]float64, len(history)-1)
+	for t := range returns {
+		if history[t] <= 0 || history[t+1] <= 0 {
+			return fmt.Errorf("%s needs positive values, got %v", f.Name(), min(history[t], history[t+1]))
+		}
+		returns[t] = 100 * math.Log(history[t+1]/history[t])
+	}
+	mean, variance := 0.0, 0.0
+	for _, r := range returns {
+		mean += r / float64(len(returns))
+	}
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean) / float64(len(returns))
+	}
+	if variance == 0 {
+		return fmt.Errorf("%s: returns have no variance", f.Name())
+	}
+
+	decode := func(x []float64) (float64, float64, float64, float64) {
+		alpha := logistic(x[2])
+		return x[0], variance * math.Exp(x[1]), alpha, (1 - alpha) * logistic(x[3])
+	}
+	objective := func(x []float64) float64 {
+		mu, omega, alpha, beta := decode(x)
+		logLikelihood, _ := garchFilter(returns, variance, mu, omega, alpha, beta)
+		return -logLikelihood
+	}
+	// 初期値は α = 0.1、β = 0.8 (ω は無条件分散が標本分散と一致する値)
+	start := []float64{mean, math.Log(0.1), logit(0.1), logit(0.8 / 0.9)}
+	x, _ := optimize.NelderMead(objective, start, garchEvaluations*len(start))
+
+	mu, omega, alpha, beta := decode(x)
+	_, next := garchFilter(returns, variance, mu, omega, alpha, beta)
+	f.Mu, f.Omega, f.Alpha, f.Beta = mu/100, omega/10000, alpha, beta
+	f.variance = next / 10000
+	f.last = history[len(history)-1]
+	return nil
+}
+
+// Predict (public)最後の値に収益率の平均を積み上げて予測値とし、誤差の標準偏差は h 期分の条件付き分散の和から求める
+// h+1 期先の条件付き分散は ω + (α + β) σ²_h (無条件分散 ω / (1 - α - β) に近づく)
+func (f *GARCH) Predict(steps int) []Prediction {
+
+	predictions := make([]Prediction, steps)
+	variance, cumulative := f.variance, 0.0
+	for h := range predictions {
+		cumulative += variance
+		variance = f.Omega + (f.Alpha+f.Beta)*variance
+		predictions[h] = Prediction{Value: f.last * math.Exp(float64(h+1)*f.Mu), StdDev: f.last * math.Sqrt(cumulative)}
+	}
+	return predictions
+}
+
+//---- private function ----
+
+// 収益率 returns の GARCH(1,1) の対数尤度と、最後の次の時点の条件付き分散を返す(初期の条件付き分散は initial)
+func garchFilter(returns []float64, initial float64, mu float64, omega float64, alpha float64, beta float64) (float64, float64) {
+	logLikelihood, variance := 0.0, initial
+	for _, r := range returns {
+		e := r - mu
+		logLikelihood -= 0.5 * (math.Log(2*math.Pi) + math.Log(variance) + e*e/variance)
+		variance = omega + alpha*e*e + beta*variance
+	}
+	return logLikelihood, variance
+}
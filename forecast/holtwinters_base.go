@@ -0,0 +1,154 @@
+// forecast 時系列の予測モデルパッケージ
+package forecast // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"fmt"
+	"math"
+
+	"sv_stockcheck/optimize"
+)
+
+// 加法型の Holt-Winters 法(誤差修正形式。Period が 0 の場合は季節項のない Holt の線形トレンド法)
+//   予測 ŷ_t = l + b + s_t-m、誤差 e_t = y_t - ŷ_t
+//   水準 l ← l + b + α e_t、傾き b ← b + β e_t、季節 s_t = s_t-m + γ e_t
+// 平滑化係数は 0 < α < 1、0 < β < α、0 < γ < 1 - α の範囲で1期先の誤差の二乗和を最小化して推定する
+
+// ---- const
+
+const holtWintersEvaluations = 1000 // 推定の目的関数の評価回数の上限(パラメータ1個あたり)
+
+// ---- struct
+
+// HoltWinters 加法型の Holt-Winters 指数平滑法
+type HoltWinters struct {
+	levelColumns
+	Period int     // 季節の周期(0 は季節項なし)
+	Alpha  float64 // 水準の平滑化係数
+	Beta   float64 // 傾きの平滑化係数
+	Gamma  float64 // 季節の平滑化係数
+	Sigma2 float64 // 1期先の誤差の分散
+
+	level    float64
+	trend    float64
+	seasonal []float64 // 時点の周期での位相毎の最新の季節成分
+	n        int
+}
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// NewHoltWinters (public)季節の周期 period の HoltWinters を作成する(0 は季節項なし)
+func NewHoltWinters(period int) (*HoltWinters, error) {
+	if period < 0 || period == 1 {
+		return nil, fmt.Errorf("invalid Holt-Winters period %d (0 or >= 2)", period)
+	}
+	return &HoltWinters{levelColumns: levelColumns{name: "HoltWinters"}, Period: period}, nil
+}
+
+// Fit (public)平滑化係数を推定する。初期値は最初の2周期(季節項なしは最初の2個)の値から求める
+func (f *HoltWinters) Fit(history []float64) error {
+
+	start := max(2, 2*f.Period)
+	if err := checkHistory(f.name, history, start+3); err != nil {
+		return err
+	}
+	decode := func(x []float64) (float64, float64, float64) {
+		alpha := logistic(x[0])
+		beta := alpha * logistic(x[1])
+		gamma := 0.0
+		if f.Period > 0 {
+			gamma = (1 - alpha) * logistic(x[2])
+		}
+		return alpha, beta, gamma
+	}
+	objective := func(x []float64) float64 {
+		alpha, beta, gamma := decode(x)
+		sse, _, _, _ := f.smooth(history, alpha, beta, gamma)
+		return sse
+	}
+	x := []float64{logit(0.3), logit(0.1)}
+	if f.Period > 0 {
+		x = append(x, logit(0.1))
+	}
+	x, _ = optimize.NelderMead(objective, x, holtWintersEvaluations*len(x))
+
+	f.Alpha, f.Beta, f.Gamma = decode(x)
+	var sse float64
+	sse, f.level, f.trend, f.seasonal = f.smooth(history, f.Alpha, f.Beta, f.Gamma)
+	f.Sigma2 = sse / float64(len(history)-start)
+	f.n = len(history)
+	return nil
+}
+
+// Predict (public)水準に傾きの h 倍と h 期先の季節成分を加えて予測値とする
+// h 期先の誤差の分散は σ² (1 + Σ_j=1..h-1 (α + jβ + γ [j が周期の倍数])²)
+func (f *HoltWinters) Predict(steps int) []Prediction {
+
+	predictions := make([]Prediction, steps)
+	variance := 1.0
+	for h := range predictions {
+		k := h + 1
+		value := f.level + float64(k)*f.trend
+		if f.Period > 0 {
+			value += f.seasonal[(f.n-1+k)%f.Period]
+		}
+		if h > 0 {
+			c := f.Alpha + float64(h)*f.Beta
+			if f.Period > 0 && h%f.Period == 0 {
+				c += f.Gamma
+			}
+			variance += c * c
+		}
+		predictions[h] = Prediction{Value: value, StdDev: math.Sqrt(f.Sigma2 * variance)}
+	}
+	return predictions
+}
+
+//---- private function ----
+
+// 平滑化係数 alpha、beta、gamma で系列を平滑化し、1期先の誤差の二乗和と最後の水準、傾き、季節成分を返す
+func (f *HoltWinters) smooth(history []float64, alpha float64, beta float64, gamma float64) (float64, float64, float64, []float64) {
+
+	// 初期値(季節項ありは最初の周期の最後の時点、なしは2個目の時点の状態)
+	m := f.Period
+	var level, trend float64
+	var seasonal []float64
+	start := 2
+	if m == 0 {
+		level, trend = history[1], history[1]-history[0]
+	} else {
+		first, second := 0.0, 0.0
+		for i := 0; i < m; i++ {
+			first += history[i] / float64(m)
+			second += history[m+i] / float64(m)
+		}
+		trend = (second - first) / float64(m)
+		seasonal = make([]float64, m)
+		for i := range seasonal {
+			seasonal[i] = history[i] - (first + trend*(float64(i)-float64(m-1)/2))
+		}
+		level = first + trend*float64(m-1)/2
+		start = m
+	}
+
+	sse := 0.0
+	for t := start; t < len(history); t++ {
+		predicted := level + trend
+		if m > 0 {
+			predicted += seasonal[t%m]
+		}
+		e := history[t] - predicted
+		if t >= max(2, 2*m) {
+			sse += e * e
+		}
+		level += trend + alpha*e
+		trend += beta * e
+		if m > 0 {
+			seasonal[t%m] += gamma * e
+		}
+	}
+	return sse, level, trend, seasonal
+}
@@ -0,0 +1,204 @@
+// indicator テクニカル指標の計算パッケージ
+package indicator // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// 引数、戻り値の時系列は全て日付降順(index 0 が最新)とする
+// 計算に必要なデータが揃わない日付の値は NaN とする
+
+// ---- const
+
+// Smoothing 平滑化の方式
+type Smoothing string
+
+const (
+	SmoothingSMA    Smoothing = "sma"    // 単純移動平均
+	SmoothingWilder Smoothing = "wilder" // Wilder の平滑化(RMA。平滑化係数 1/period)
+	SmoothingEMA    Smoothing = "ema"    // 指数移動平均(平滑化係数 2/(period+1))
+)
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// ParseSmoothing (public)文字列を Smoothing に変換する
+func ParseSmoothing(str string) (Smoothing, error) {
+	switch Smoothing(strings.ToLower(str)) {
+	case SmoothingSMA, SmoothingWilder, SmoothingEMA:
+		return Smoothing(strings.ToLower(str)), nil
+	}
+	return "", fmt.Errorf("unknown smoothing %q (sma / wilder / ema)", str)
+}
+
+// Smooth (public)values を期間 period、方式 method で平滑化する
+// SMA は各日付から過去 period 個の単純平均
+// Wilder・EMA は最古の有効な period 個の単純平均を初期値とし、以降を指数平滑化する
+func Smooth(values []float64, period int, method Smoothing) []float64 {
+
+	result := make([]float64, len(values))
+	for i := range result {
+		result[i] = math.NaN()
+	}
+	if period <= 0 {
+		return result
+	}
+
+	if method == SmoothingSMA {
+		for i := 0; i+period <= len(values); i++ {
+			sum := 0.0
+			for _, v := range values[i : i+period] {
+				sum += v // NaN を含む場合は NaN となる
+			}
+			result[i] = sum / float64(period)
+		}
+		return result
+	}
+
+	alpha := 1.0 / float64(period)
+	if method == SmoothingEMA {
+		alpha = 2.0 / float64(period+1)
+	}
+	// 有効なデータがある一番古い箇所を検索
+	oldest := len(values) - 1
+	for oldest >= 0 && math.IsNaN(values[oldest]) {
+		oldest--
+	}
+	seed := oldest - period + 1
+	if seed < 0 {
+		return result
+	}
+	sum := 0.0
+	for _, v := range values[seed : oldest+1] {
+		sum += v
+	}
+	result[seed] = sum / float64(period)
+	for i := seed - 1; i >= 0; i-- {
+		result[i] = alpha*values[i] + (1-alpha)*result[i+1]
+	}
+	return result
+}
+
+// RSI (public)RSI(Relative Strength Index)を計算する
+// 前日からの上昇幅・下落幅をそれぞれ method で平滑化し、100 - 100 / (1 + 上昇幅 / 下落幅) を返す
+// 期間内に下落がない場合は 100 とする
+func RSI(closing []float64, period int, method Smoothing) []float64 {
+
+	gain := make([]float64, len(closing))
+	loss := make([]float64, len(closing))
+	for i := range closing {
+		if i+1 >= len(closing) {
+			// 最古の日付は前日がないため変化なし(NaN)とする
+			gain[i] = math.NaN()
+			loss[i] = math.NaN()
+			continue
+		}
+		change := closing[i] - closing[i+1]
+		gain[i] = max(change, 0)
+		loss[i] = max(-change, 0)
+	}
+
+	avgGain := Smooth(gain, period, method)
+	avgLoss := Smooth(loss, period, method)
+	rsi := make([]float64, len(closing))
+	for i := range closing {
+		switch {
+		case math.IsNaN(avgGain[i]) || math.IsNaN(avgLoss[i]):
+			rsi[i] = math.NaN()
+		case avgLoss[i] == 0:
+			rsi[i] = 100
+		default:
+			rsi[i] = 100 - (100 / (1 + avgGain[i]/avgLoss[i]))
+		}
+	}
+	return rsi
+}
+
+// TrueRange (public)真の値幅(当日の高値・安値・前日終値のうち最大の幅)を計算する
+// 最古の日付は前日終値がないため 高値 - 安値 とする
+func TrueRange(high []float64, low []float64, closing []float64) []float64 {
+	tr := make([]float64, len(closing))
+	for i := range closing {
+		if i+1 >= len(closing) {
+			tr[i] = high[i] - low[i]
+			continue
+		}
+		tr[i] = slices.Max([]float64{high[i] - low[i], math.Abs(high[i] - closing[i+1]), math.Abs(low[i] - closing[i+1])})
+	}
+	return tr
+}
+
+// ATR (public)ATR(Average True Range)を計算する
+// 真の値幅を method で平滑化する
+func ATR(high []float64, low []float64, closing []float64, period int, method Smoothing) []float64 {
+	return Smooth(TrueRange(high, low, closing), period, method)
+}
+
+// EMA (public)期間 period の指数移動平均を計算する(Smooth の SmoothingEMA と同じ)
+func EMA(values []float64, period int) []float64 {
+	return Smooth(values, period, SmoothingEMA)
+}
+
+// StdDev (public)各日付から過去 period 個の母集団標準偏差を計算する
+// NaN を含む日付は NaN となる
+func StdDev(values []float64, period int) []float64 {
+	result := make([]float64, len(values))
+	for i := range values {
+		if period <= 0 || i+period > len(values) {
+			result[i] = math.NaN()
+			continue
+		}
+		mean := 0.0
+		for _, v := range values[i : i+period] {
+			mean += v
+		}
+		mean /= float64(period)
+		sumOfSquares := 0.0
+		for _, v := range values[i : i+period] {
+			deviation := v - mean
+			sumOfSquares += deviation * deviation
+		}
+		result[i] = math.Sqrt(sumOfSquares / float64(period))
+	}
+	return result
+}
+
+// MADRate (public)移動平均線の乖離率((値 - 移動平均) / 移動平均 * 100)を計算する
+// 移動平均が NaN の日付は NaN、0 の日付は 0 とする
+func MADRate(values []float64, average []float64) []float64 {
+	result := make([]float64, len(values))
+	for i := range values {
+		result[i] = MADRateValue(values[i], average[i])
+	}
+	return result
+}
+
+// MADRateValue (public)1つの日付の値 value と移動平均 average の乖離率を計算する(MADRate と同じ)
+func MADRateValue(value float64, average float64) float64 {
+	switch {
+	case math.IsNaN(average):
+		return math.NaN()
+	case average == 0:
+		return 0
+	}
+	return (value - average) / average * 100
+}
+
+// Shift (public)各日付の値を shift 本前(古い方)の値にずらす。ずらす元がない日付は NaN
+func Shift(values []float64, shift int) []float64 {
+	result := make([]float64, len(values))
+	for i := range values {
+		if i+shift >= len(values) {
+			result[i] = math.NaN()
+			continue
+		}
+		result[i] = values[i+shift]
+	}
+	return result
+}
@@ -0,0 +1,167 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+)
+
+// 日付昇順で記載した参照データを日付降順に並べ替える
+func descending(ascending []float64) []float64 {
+	result := make([]float64, len(ascending))
+	for i, v := range ascending {
+		result[len(ascending)-1-i] = v
+	}
+	return result
+}
+
+// 日付降順の計算結果と期待値(NaN は計算不可の日付)を許容誤差 tolerance で比較する
+func assertSeries(t *testing.T, name string, got []float64, want []float64, tolerance float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: len = %d, want %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) {
+			if !math.IsNaN(got[i]) {
+				t.Errorf("%s[%d] = %v, want NaN", name, i, got[i])
+			}
+			continue
+		}
+		if math.IsNaN(got[i]) || math.Abs(got[i]-want[i]) > tolerance {
+			t.Errorf("%s[%d] = %v, want %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+// 期待値の前に計算不可の日付分の NaN を付けて日付降順にする
+func withLeadingNaN(n int, ascending []float64) []float64 {
+	values := make([]float64, 0, n+len(ascending))
+	for i := 0; i < n; i++ {
+		values = append(values, math.NaN())
+	}
+	return descending(append(values, ascending...))
+}
+
+func TestSmooth(t *testing.T) {
+	nan := math.NaN()
+	values := []float64{5, 4, 3, 2, 1} // 日付降順(最古が 1)
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		method Smoothing
+		want   []float64
+	}{
+		{"sma", values, 3, SmoothingSMA, []float64{4, 3, 2, nan, nan}},
+		{"wilder", values, 3, SmoothingWilder, []float64{31.0 / 9, 8.0 / 3, 2, nan, nan}},
+		{"ema", values, 3, SmoothingEMA, []float64{4, 3, 2, nan, nan}},
+		{"wilder skips oldest NaN", []float64{5, 4, 3, 2, nan}, 3, SmoothingWilder, []float64{11.0 / 3, 3, nan, nan, nan}},
+		{"sma with NaN", []float64{5, 4, 3, 2, nan}, 3, SmoothingSMA, []float64{4, 3, nan, nan, nan}},
+		{"not enough data", []float64{2, 1}, 3, SmoothingWilder, []float64{nan, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, "Smooth", Smooth(tt.values, tt.period, tt.method), tt.want, 1e-9)
+		})
+	}
+}
+
+func TestParseSmoothing(t *testing.T) {
+	for _, str := range []string{"sma", "Wilder", "EMA"} {
+		if _, err := ParseSmoothing(str); err != nil {
+			t.Errorf("ParseSmoothing(%q) error = %v", str, err)
+		}
+	}
+	if _, err := ParseSmoothing("rma"); err == nil {
+		t.Errorf("ParseSmoothing(%q) error = nil, want error", "rma")
+	}
+}
+
+// 参照データは StockCharts ChartSchool の RSI / ATR の計算例(14期間)の入力値と掲載値
+// RSI の終値は計算例の表計算シートの値(小数第4位まで)で、掲載値(小数第2位に丸めた値)と一致する
+var chartSchoolRSIClosing = []float64{44.3389, 44.0902, 44.1497, 43.6124, 44.3278, 44.8264, 45.0955, 45.4245, 45.8433, 46.0826, 45.8931,
+	46.0328, 45.6140, 46.2820, 46.2820, 46.0028, 46.0328, 46.4116, 46.2222, 45.6439, 46.2122, 46.2521, 45.7137, 46.4515, 45.7835,
+	45.3548, 44.0288, 44.1783, 44.2181, 44.5672, 43.4205, 42.6628, 43.1314}
+
+var chartSchoolATRHigh = []float64{48.70, 48.72, 48.90, 48.87, 48.82, 49.05, 49.20, 49.35, 49.92, 50.19, 50.12, 49.66, 49.88, 50.19, 50.36,
+	50.57, 50.65, 50.43, 49.63, 50.33, 50.29, 50.17, 49.32, 48.50, 48.32, 46.80, 47.80, 48.39, 48.66, 48.79}
+var chartSchoolATRLow = []float64{47.79, 48.14, 48.39, 48.37, 48.24, 48.64, 48.94, 48.86, 49.50, 49.87, 49.20, 48.90, 49.43, 49.73, 49.26,
+	50.09, 50.30, 49.21, 48.98, 49.61, 49.20, 49.43, 48.08, 47.64, 41.55, 44.28, 47.31, 47.20, 47.90, 47.73}
+var chartSchoolATRClosing = []float64{48.16, 48.61, 48.75, 48.63, 48.74, 49.03, 49.07, 49.32, 49.91, 50.13, 49.53, 49.50, 49.75, 50.03, 50.31,
+	50.52, 50.41, 49.34, 49.37, 50.23, 49.24, 49.93, 48.43, 48.18, 46.57, 45.41, 47.77, 47.72, 48.62, 47.85}
+
+// 掲載値は小数第2位に丸めた値のため、丸めの誤差 0.005 を許容する
+func TestRSIWilderReference(t *testing.T) {
+	want := withLeadingNaN(14, []float64{70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38,
+		54.71, 50.42, 39.99, 41.46, 41.87, 45.46, 37.30, 33.08, 37.77})
+	got := RSI(descending(chartSchoolRSIClosing), 14, SmoothingWilder)
+	assertSeries(t, "RSI", got, want, 0.005+1e-9)
+}
+
+// ATR の入力値(高値・安値・終値)は小数第2位に丸めて掲載されているため、
+// 掲載値の丸めの誤差 0.005 に加えて入力値の丸めによる True Range の誤差を含めて 0.01 を許容する
+func TestATRWilderReference(t *testing.T) {
+	want := withLeadingNaN(13, []float64{0.56, 0.59, 0.59, 0.57, 0.62, 0.62, 0.64, 0.67, 0.69, 0.77, 0.78,
+		1.21, 1.30, 1.38, 1.37, 1.34, 1.32})
+	got := ATR(descending(chartSchoolATRHigh), descending(chartSchoolATRLow), descending(chartSchoolATRClosing), 14, SmoothingWilder)
+	assertSeries(t, "ATR", got, want, 0.01)
+}
+
+func TestRSISmoothing(t *testing.T) {
+	nan := math.NaN()
+	// 日付昇順で 1, 2, 3, 2, 3 (変化 +1, +1, -1, +1)
+	closing := descending([]float64{1, 2, 3, 2, 3})
+	tests := []struct {
+		name   string
+		method Smoothing
+		want   []float64
+	}{
+		{"sma", SmoothingSMA, []float64{50, 50, 100, nan, nan}},
+		// 初期値 上昇 1、下落 0 から 上昇 0.5, 下落 0.5 → 上昇 0.75, 下落 0.25
+		{"wilder", SmoothingWilder, []float64{75, 50, 100, nan, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, "RSI", RSI(closing, 2, tt.method), tt.want, 1e-9)
+		})
+	}
+}
+
+func TestTrueRange(t *testing.T) {
+	// 日付降順。最古の日付は 高値 - 安値、それ以外は前日終値からの幅も含めた最大値
+	high := []float64{12, 11, 10}
+	low := []float64{11, 9, 9}
+	closing := []float64{11.5, 9.5, 9.5}
+	want := []float64{2.5, 2, 1}
+	assertSeries(t, "TrueRange", TrueRange(high, low, closing), want, 1e-9)
+}
+
+func TestStdDev(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name   string
+		values []float64
+		want   []float64
+	}{
+		// 期間2: 2 と 4 の母集団標準偏差は 1
+		{"population", []float64{4, 2, 2, 8}, []float64{1, 0, 3, nan}},
+		{"with NaN", []float64{4, nan, 2, 8}, []float64{nan, nan, 3, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, "StdDev", StdDev(tt.values, 2), tt.want, 1e-9)
+		})
+	}
+}
+
+func TestMADRate(t *testing.T) {
+	nan := math.NaN()
+	values := []float64{110, 90, 5, 1}
+	average := []float64{100, 100, 0, nan}
+	assertSeries(t, "MADRate", MADRate(values, average), []float64{10, -10, 0, nan}, 1e-9)
+}
+
+func TestShift(t *testing.T) {
+	nan := math.NaN()
+	assertSeries(t, "Shift", Shift([]float64{4, 3, 2, 1}, 2), []float64{2, 1, nan, nan}, 1e-9)
+}
@@ -0,0 +1,69 @@
+// indicator テクニカル指標の計算パッケージ
+package indicator // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"math"
+	"slices"
+)
+
+// 買われすぎ・売られすぎを表すオシレーター系のテクニカル指標
+
+// ---- const
+
+const cciConstant = 0.015 // CCI の平均偏差に掛ける定数(Lambert の定義)
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// Stochastics (public)ストキャスティクスの %K とウィリアムズ%R を計算する
+// 期間 period の最高値・最安値に対する終値の位置を %K = 0〜100、%R = -100〜0 で返す
+// 期間内の高値と安値が等しい場合は中間値(50、-50)とする
+func Stochastics(high []float64, low []float64, closing []float64, period int) ([]float64, []float64) {
+	fastK := make([]float64, len(closing))
+	williamsR := make([]float64, len(closing))
+	for i := range closing {
+		if period <= 0 || i+period > len(closing) {
+			fastK[i] = math.NaN()
+			williamsR[i] = math.NaN()
+			continue
+		}
+		highest := slices.Max(high[i : i+period])
+		lowest := slices.Min(low[i : i+period])
+		if highest == lowest {
+			fastK[i] = 50
+			williamsR[i] = -50
+			continue
+		}
+		fastK[i] = (closing[i] - lowest) / (highest - lowest) * 100
+		williamsR[i] = (highest - closing[i]) / (highest - lowest) * -100
+	}
+	return fastK, williamsR
+}
+
+// CCI (public)CCI(Commodity Channel Index)を計算する
+// 典型価格の期間 period の移動平均からの乖離を平均偏差の0.015倍で割った値を返す。平均偏差が0の場合は0とする
+func CCI(high []float64, low []float64, closing []float64, period int) []float64 {
+	typicalPrice := TypicalPrice(high, low, closing)
+	average := Smooth(typicalPrice, period, SmoothingSMA)
+	cci := make([]float64, len(closing))
+	for i := range closing {
+		if math.IsNaN(average[i]) {
+			cci[i] = math.NaN()
+			continue
+		}
+		meanDeviation := 0.0
+		for _, tp := range typicalPrice[i : i+period] {
+			meanDeviation += math.Abs(tp - average[i])
+		}
+		meanDeviation /= float64(period)
+		if meanDeviation == 0 {
+			cci[i] = 0
+			continue
+		}
+		cci[i] = (typicalPrice[i] - average[i]) / (cciConstant * meanDeviation)
+	}
+	return cci
+}
@@ -0,0 +1,51 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStochastics(t *testing.T) {
+	nan := math.NaN()
+	flat := []float64{10, 10, 10}
+	tests := []struct {
+		name    string
+		high    []float64
+		low     []float64
+		closing []float64
+		wantK   []float64
+		wantR   []float64
+	}{
+		// 期間2: 最新は 最高値 12、最安値 9 に対し終値 11
+		{"range", []float64{12, 11, 10}, []float64{10, 9, 9}, []float64{11, 10, 9.5}, []float64{200.0 / 3, 50, nan}, []float64{-100.0 / 3, -50, nan}},
+		{"flat", flat, flat, flat, []float64{50, 50, nan}, []float64{-50, -50, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fastK, williamsR := Stochastics(tt.high, tt.low, tt.closing, 2)
+			assertSeries(t, "%K", fastK, tt.wantK, 1e-9)
+			assertSeries(t, "%R", williamsR, tt.wantR, 1e-9)
+		})
+	}
+}
+
+func TestCCI(t *testing.T) {
+	nan := math.NaN()
+	flat := []float64{10, 10, 10}
+	tests := []struct {
+		name    string
+		high    []float64
+		low     []float64
+		closing []float64
+		want    []float64
+	}{
+		// 典型価格は古い順に 10, 11, 13。最新の期間3: 平均 34/3、平均偏差 (4/3 + 1/3 + 5/3) / 3 = 10/9
+		{"trend", []float64{14, 12, 11}, []float64{12, 10, 9}, []float64{13, 11, 10}, []float64{(13 - 34.0/3) / (0.015 * 10.0 / 9), nan, nan}},
+		{"flat", flat, flat, flat, []float64{0, nan, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, "CCI", CCI(tt.high, tt.low, tt.closing, 3), tt.want, 1e-9)
+		})
+	}
+}
@@ -0,0 +1,686 @@
+// indicator テクニカル指標の計算パッケージ
+package indicator // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"math"
+	"slices"
+)
+
+// 逐次計算器(Stream)は系列関数と異なり、日付昇順(古い日付から)に1本ずつ値を与え、与えた日付の指標値を返す
+// 同じデータを与えた場合の値は系列関数の同じ日付の値と一致する(移動合計の丸め誤差を除く)
+// 状態はフィールドに全て保持するため、保存して次回の実行で続きから計算できる
+
+// ---- struct
+
+// SmoothStream Smooth の逐次計算器
+type SmoothStream struct {
+	Period int       // 期間
+	Method Smoothing // 平滑化の方式
+	Window []float64 // SMA: 直近 Period 個の値(日付昇順、NaN は 0 として保持)。Wilder・EMA: 初期値の計算に用いる値
+	Sum    float64   // SMA: Window の合計
+	NaNAge int       // SMA: 直近の NaN が Window から外れるまでの本数(0 より大きい間は NaN を返す)
+	Value  float64   // Wilder・EMA: 直前の日付の平滑化値
+	Seeded bool      // Wilder・EMA: 初期値を計算済みか
+}
+
+// RSIStream RSI の逐次計算器
+type RSIStream struct {
+	Previous    float64      // 前日の終値
+	HasPrevious bool         // 前日の終値があるか
+	Gain        SmoothStream // 上昇幅の平滑化
+	Loss        SmoothStream // 下落幅の平滑化
+}
+
+// ATRStream ATR の逐次計算器
+type ATRStream struct {
+	PreviousClosing float64      // 前日の終値
+	HasPrevious     bool         // 前日の終値があるか
+	TrueRange       SmoothStream // 真の値幅の平滑化
+}
+
+// MACDStream MACD の逐次計算器
+type MACDStream struct {
+	Fast      SmoothStream // 短期の移動平均
+	Slow      SmoothStream // 長期の移動平均
+	SignalSMA SmoothStream // MACD の単純移動平均
+	SignalEMA SmoothStream // MACD の指数移動平均
+}
+
+// MACDPoint 1つの日付の MACD の各値(MACDResult の同じ日付の値)
+type MACDPoint struct {
+	Value     float64
+	SignalSMA float64
+	HistoSMA  float64
+	SignalEMA float64
+	HistoEMA  float64
+}
+
+// DMIStream DMI の逐次計算器
+type DMIStream struct {
+	PreviousHigh    float64      // 前日の高値
+	PreviousLow     float64      // 前日の安値
+	PreviousClosing float64      // 前日の終値
+	HasPrevious     bool         // 前日の足があるか
+	PlusDM          SmoothStream // +DM の平滑化
+	MinusDM         SmoothStream // -DM の平滑化
+	TrueRange       SmoothStream // 真の値幅の平滑化
+	ADX             SmoothStream // DX の平滑化
+}
+
+// ParabolicSARStream パラボリックSARの逐次計算器
+type ParabolicSARStream struct {
+	Acceleration SARAcceleration // 加速因子
+	Count        int             // 与えた足の本数(2本目から計算する)
+	IsUp         bool            // 上昇トレンドか
+	AF           float64         // 現在の加速因子
+	Extreme      float64         // トレンド中の極値(上昇は最高値、下降は最安値)
+	SAR          float64         // 前日の SAR
+	PreviousHigh float64         // 前日の高値
+	PreviousLow  float64         // 前日の安値
+	OlderHigh    float64         // 前々日の高値
+	OlderLow     float64         // 前々日の安値
+	FirstClosing float64         // 最古の日付の終値(トレンドの初期方向の判定用)
+}
+
+// OBVStream OBV の逐次計算器
+type OBVStream struct {
+	Previous    float64 // 前日の終値
+	HasPrevious bool    // 前日の終値があるか
+	Value       float64 // 前日までの累計
+}
+
+// ADLineStream A/Dライン の逐次計算器
+type ADLineStream struct {
+	Value float64 // 前日までの累計
+}
+
+// StdDevStream StdDev の逐次計算器
+// 期間内の平均と偏差の二乗和を、加える値と期間から外れる値で更新する(Welford の方法)
+type StdDevStream struct {
+	Period int       // 期間
+	Window []float64 // 直近 Period 個の値(日付昇順)
+	Mean   float64   // Window の平均
+	M2     float64   // Window の平均からの偏差の二乗和
+}
+
+// ExtremeDeque 期間内の最大値(最小値)の候補を保持する単調な両端キュー。先頭が期間内の最大値(最小値)となる
+type ExtremeDeque struct {
+	Bars   []int     // 値を与えた足の番号(1 から数える)
+	Values []float64 // 値(最大値のキューは先頭から減少、最小値のキューは先頭から増加)
+}
+
+// HighLowStream 期間内の最高値・最安値の逐次計算器(Stochastics、MidPrice の期間内の高値・安値)
+type HighLowStream struct {
+	Period  int          // 期間
+	Count   int          // 与えた足の本数
+	Highest ExtremeDeque // 最高値の候補
+	Lowest  ExtremeDeque // 最安値の候補
+}
+
+// StochasticsStream Stochastics の逐次計算器
+type StochasticsStream struct {
+	Range HighLowStream // 期間内の最高値・最安値
+}
+
+// CCIStream CCI の逐次計算器
+// 平均偏差は期間内の全ての典型価格から計算するため、1本あたり期間分の計算となる(他の逐次計算器は1本あたり一定)
+type CCIStream struct {
+	Period int       // 期間
+	Window []float64 // 直近 Period 個の典型価格(日付昇順)
+}
+
+// WindowSum 直近 Period 個の値の移動合計
+type WindowSum struct {
+	Period  int       // 期間
+	Window  []float64 // 直近 Period 個の値(日付昇順)
+	Sum     float64   // Window の合計
+	NonZero int       // Window の 0 でない値の個数(0 の場合は Sum を 0 に戻し、合計が 0 の判定に丸め誤差を残さない)
+}
+
+// VWAPStream VWAP の逐次計算器
+type VWAPStream struct {
+	PriceVolume WindowSum // 典型価格 * 出来高
+	Volume      WindowSum // 出来高
+	Price       WindowSum // 典型価格
+}
+
+// MFIStream MFI の逐次計算器
+type MFIStream struct {
+	PreviousTypicalPrice float64   // 前日の典型価格
+	HasPrevious          bool      // 前日の足があるか
+	Positive             WindowSum // 典型価格が前日より上昇した日の 典型価格 * 出来高(他の日は 0)
+	Negative             WindowSum // 典型価格が前日より下落した日の 典型価格 * 出来高(他の日は 0)
+}
+
+// CMFStream CMF の逐次計算器
+type CMFStream struct {
+	FlowVolume WindowSum // マネーフロー出来高
+	Volume     WindowSum // 出来高
+}
+
+// IchimokuStream 一目均衡表の逐次計算器
+// 先行スパン・遅行スパンの比較に用いる Periods.Kijun 本前の値は、直近 Periods.Kijun 本分のみ保持する
+type IchimokuStream struct {
+	Periods IchimokuPeriods // 転換線、基準線、先行スパンBの期間
+	Count   int             // 与えた足の本数
+	Tenkan  HighLowStream   // 転換線の期間の最高値・最安値
+	Kijun   HighLowStream   // 基準線の期間の最高値・最安値
+	SenkouB HighLowStream   // 先行スパンBの期間の最高値・最安値
+	LeadA   []float64       // 直近 Periods.Kijun 本の当日に計算した先行スパンA(日付昇順、計算できない日付は 0)
+	LeadB   []float64       // 直近 Periods.Kijun 本の当日に計算した先行スパンB(日付昇順、計算できない日付は 0)
+	Closing []float64       // 直近 Periods.Kijun 本の終値(日付昇順)
+}
+
+// IchimokuPoint 1つの日付の一目均衡表の各値
+type IchimokuPoint struct {
+	Tenkan      float64 // 転換線
+	Kijun       float64 // 基準線
+	SenkouA     float64 // 当日の雲の先行スパンA(Periods.Kijun 本前に計算した値)
+	SenkouB     float64 // 当日の雲の先行スパンB(Periods.Kijun 本前に計算した値)
+	SenkouALead float64 // 当日に計算した先行スパンA
+	SenkouBLead float64 // 当日に計算した先行スパンB
+	ChikouDiff  float64 // 当日の終値と Periods.Kijun 本前の終値の差
+}
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// NewSmoothStream (public)期間 period、方式 method の SmoothStream を生成する
+func NewSmoothStream(period int, method Smoothing) *SmoothStream {
+	return &SmoothStream{Period: period, Method: method}
+}
+
+// Next (public)次の日付の値 value を与え、その日付の平滑化値を返す
+func (s *SmoothStream) Next(value float64) float64 {
+	if s.Period <= 0 {
+		return math.NaN()
+	}
+	if s.Method == SmoothingSMA {
+		return s.nextSMA(value)
+	}
+
+	if s.Seeded == true {
+		s.Value = s.alpha()*value + (1-s.alpha())*s.Value
+		return s.Value
+	}
+	// 最古の有効な値より前の NaN は読み飛ばす
+	if len(s.Window) == 0 && math.IsNaN(value) {
+		return math.NaN()
+	}
+	s.Window = append(s.Window, value)
+	if len(s.Window) < s.Period {
+		return math.NaN()
+	}
+	sum := 0.0
+	for _, v := range s.Window {
+		sum += v
+	}
+	s.Value, s.Seeded, s.Window = sum/float64(s.Period), true, nil
+	return s.Value
+}
+
+// NewRSIStream (public)期間 period、方式 method の RSIStream を生成する
+func NewRSIStream(period int, method Smoothing) *RSIStream {
+	return &RSIStream{Gain: *NewSmoothStream(period, method), Loss: *NewSmoothStream(period, method)}
+}
+
+// Next (public)次の日付の終値 closing を与え、その日付の RSI を返す
+func (s *RSIStream) Next(closing float64) float64 {
+	gain, loss := math.NaN(), math.NaN() // 最古の日付は前日がないため NaN
+	if s.HasPrevious == true {
+		change := closing - s.Previous
+		gain, loss = max(change, 0), max(-change, 0)
+	}
+	s.Previous, s.HasPrevious = closing, true
+
+	avgGain := s.Gain.Next(gain)
+	avgLoss := s.Loss.Next(loss)
+	switch {
+	case math.IsNaN(avgGain) || math.IsNaN(avgLoss):
+		return math.NaN()
+	case avgLoss == 0:
+		return 100
+	}
+	return 100 - (100 / (1 + avgGain/avgLoss))
+}
+
+// NewATRStream (public)期間 period、方式 method の ATRStream を生成する
+func NewATRStream(period int, method Smoothing) *ATRStream {
+	return &ATRStream{TrueRange: *NewSmoothStream(period, method)}
+}
+
+// Next (public)次の日付の高値・安値・終値を与え、その日付の ATR を返す
+func (s *ATRStream) Next(high float64, low float64, closing float64) float64 {
+	tr := high - low // 最古の日付は前日終値がないため 高値 - 安値
+	if s.HasPrevious == true {
+		tr = slices.Max([]float64{high - low, math.Abs(high - s.PreviousClosing), math.Abs(low - s.PreviousClosing)})
+	}
+	s.PreviousClosing, s.HasPrevious = closing, true
+	return s.TrueRange.Next(tr)
+}
+
+// NewMACDStream (public)移動平均の方式 base、短期 fast、長期 slow、シグナル signal の MACDStream を生成する
+func NewMACDStream(base Smoothing, fast int, slow int, signal int) *MACDStream {
+	return &MACDStream{
+		Fast:      *NewSmoothStream(fast, base),
+		Slow:      *NewSmoothStream(slow, base),
+		SignalSMA: *NewSmoothStream(signal, SmoothingSMA),
+		SignalEMA: *NewSmoothStream(signal, SmoothingEMA),
+	}
+}
+
+// Next (public)次の日付の終値 closing を与え、その日付の MACD を返す
+func (s *MACDStream) Next(closing float64) MACDPoint {
+	value := s.Fast.Next(closing) - s.Slow.Next(closing)
+	signalSMA := s.SignalSMA.Next(value)
+	signalEMA := s.SignalEMA.Next(value)
+	return MACDPoint{Value: value, SignalSMA: signalSMA, HistoSMA: value - signalSMA, SignalEMA: signalEMA, HistoEMA: value - signalEMA}
+}
+
+// NewDMIStream (public)期間 period の DMIStream を生成する
+func NewDMIStream(period int) *DMIStream {
+	return &DMIStream{
+		PlusDM:    *NewSmoothStream(period, SmoothingWilder),
+		MinusDM:   *NewSmoothStream(period, SmoothingWilder),
+		TrueRange: *NewSmoothStream(period, SmoothingWilder),
+		ADX:       *NewSmoothStream(period, SmoothingWilder),
+	}
+}
+
+// Next (public)次の日付の高値・安値・終値を与え、その日付の +DI、-DI、ADX を返す
+func (s *DMIStream) Next(high float64, low float64, closing float64) (float64, float64, float64) {
+	plusDM, minusDM, tr := math.NaN(), math.NaN(), math.NaN() // 最古の日付は前日がないため NaN
+	if s.HasPrevious == true {
+		plusDM, minusDM = 0, 0
+		upMove := high - s.PreviousHigh
+		downMove := s.PreviousLow - low
+		if upMove > downMove && upMove > 0 {
+			plusDM = upMove
+		}
+		if downMove > upMove && downMove > 0 {
+			minusDM = downMove
+		}
+		tr = slices.Max([]float64{high - low, math.Abs(high - s.PreviousClosing), math.Abs(low - s.PreviousClosing)})
+	}
+	s.PreviousHigh, s.PreviousLow, s.PreviousClosing, s.HasPrevious = high, low, closing, true
+
+	smoothedPlusDM := s.PlusDM.Next(plusDM)
+	smoothedMinusDM := s.MinusDM.Next(minusDM)
+	smoothedTrueRange := s.TrueRange.Next(tr)
+	var plusDI, minusDI, dx float64
+	switch {
+	case math.IsNaN(smoothedTrueRange):
+		plusDI, minusDI, dx = math.NaN(), math.NaN(), math.NaN()
+	case smoothedTrueRange == 0:
+		plusDI, minusDI, dx = 0, 0, 0
+	default:
+		plusDI = smoothedPlusDM / smoothedTrueRange * 100
+		minusDI = smoothedMinusDM / smoothedTrueRange * 100
+		if plusDI+minusDI != 0 {
+			dx = math.Abs(plusDI-minusDI) / (plusDI + minusDI) * 100
+		}
+	}
+	return plusDI, minusDI, s.ADX.Next(dx)
+}
+
+// NewParabolicSARStream (public)加速因子 acceleration の ParabolicSARStream を生成する
+func NewParabolicSARStream(acceleration SARAcceleration) *ParabolicSARStream {
+	return &ParabolicSARStream{Acceleration: acceleration}
+}
+
+// Next (public)次の日付の高値・安値・終値を与え、その日付の SAR とトレンド(上昇 1、下降 -1)を返す
+func (s *ParabolicSARStream) Next(high float64, low float64, closing float64) (float64, float64) {
+	s.Count++
+	switch s.Count {
+	case 1:
+		// 最古の日付は NaN
+		s.PreviousHigh, s.PreviousLow, s.FirstClosing = high, low, closing
+		return math.NaN(), math.NaN()
+	case 2:
+		// 2日間の安値(下降は高値)を SAR とする
+		s.IsUp = closing >= s.FirstClosing
+		s.AF = s.Acceleration.Start
+		if s.IsUp == true {
+			s.SAR, s.Extreme = min(s.PreviousLow, low), max(s.PreviousHigh, high)
+		} else {
+			s.SAR, s.Extreme = max(s.PreviousHigh, high), min(s.PreviousLow, low)
+		}
+	default:
+		next := s.SAR + s.AF*(s.Extreme-s.SAR)
+		if s.IsUp == true {
+			// 前日・前々日の安値より上にはしない
+			next = slices.Min([]float64{next, s.PreviousLow, s.OlderLow})
+			if low < next {
+				s.IsUp, next, s.Extreme, s.AF = false, s.Extreme, low, s.Acceleration.Start
+			} else if high > s.Extreme {
+				s.Extreme, s.AF = high, min(s.AF+s.Acceleration.Step, s.Acceleration.Max)
+			}
+		} else {
+			// 前日・前々日の高値より下にはしない
+			next = slices.Max([]float64{next, s.PreviousHigh, s.OlderHigh})
+			if high > next {
+				s.IsUp, next, s.Extreme, s.AF = true, s.Extreme, high, s.Acceleration.Start
+			} else if low < s.Extreme {
+				s.Extreme, s.AF = low, min(s.AF+s.Acceleration.Step, s.Acceleration.Max)
+			}
+		}
+		s.SAR = next
+	}
+	s.OlderHigh, s.OlderLow = s.PreviousHigh, s.PreviousLow
+	s.PreviousHigh, s.PreviousLow = high, low
+	return s.SAR, trendValue(s.IsUp)
+}
+
+// Next (public)次の日付の終値・出来高を与え、その日付の OBV を返す
+func (s *OBVStream) Next(closing float64, volume float64) float64 {
+	if s.HasPrevious == true {
+		switch {
+		case closing > s.Previous:
+			s.Value += volume
+		case closing < s.Previous:
+			s.Value -= volume
+		}
+	}
+	s.Previous, s.HasPrevious = closing, true
+	return s.Value
+}
+
+// Next (public)次の日付の高値・安値・終値・出来高を与え、その日付の A/Dライン を返す
+func (s *ADLineStream) Next(high float64, low float64, closing float64, volume float64) float64 {
+	s.Value += moneyFlowMultiplier(high, low, closing) * volume
+	return s.Value
+}
+
+// NewStdDevStream (public)期間 period の StdDevStream を生成する
+func NewStdDevStream(period int) *StdDevStream {
+	return &StdDevStream{Period: period}
+}
+
+// Next (public)次の日付の値 value(NaN は与えない)を与え、その日付の標準偏差を返す
+func (s *StdDevStream) Next(value float64) float64 {
+	if s.Period <= 0 {
+		return math.NaN()
+	}
+	s.Window = append(s.Window, value)
+	if len(s.Window) <= s.Period {
+		delta := value - s.Mean
+		s.Mean += delta / float64(len(s.Window))
+		s.M2 += delta * (value - s.Mean)
+	} else {
+		removed, previousMean := s.Window[0], s.Mean
+		s.Window = s.Window[1:]
+		s.Mean += (value - removed) / float64(s.Period)
+		s.M2 += (value - removed) * (value - s.Mean + removed - previousMean)
+	}
+	if len(s.Window) < s.Period {
+		return math.NaN()
+	}
+	return math.Sqrt(max(s.M2, 0) / float64(s.Period))
+}
+
+// NewHighLowStream (public)期間 period の HighLowStream を生成する
+func NewHighLowStream(period int) *HighLowStream {
+	return &HighLowStream{Period: period}
+}
+
+// Next (public)次の日付の高値・安値を与え、その日付までの期間内の最高値・最安値を返す
+func (s *HighLowStream) Next(high float64, low float64) (float64, float64) {
+	if s.Period <= 0 {
+		return math.NaN(), math.NaN()
+	}
+	s.Count++
+	oldest := s.Count - s.Period + 1
+	highest := s.Highest.push(s.Count, high, oldest, func(back float64) bool { return back <= high })
+	lowest := s.Lowest.push(s.Count, low, oldest, func(back float64) bool { return back >= low })
+	if s.Count < s.Period {
+		return math.NaN(), math.NaN()
+	}
+	return highest, lowest
+}
+
+// NewStochasticsStream (public)期間 period の StochasticsStream を生成する
+func NewStochasticsStream(period int) *StochasticsStream {
+	return &StochasticsStream{Range: *NewHighLowStream(period)}
+}
+
+// Next (public)次の日付の高値・安値・終値を与え、その日付の %K とウィリアムズ%R を返す
+func (s *StochasticsStream) Next(high float64, low float64, closing float64) (float64, float64) {
+	highest, lowest := s.Range.Next(high, low)
+	switch {
+	case math.IsNaN(highest):
+		return math.NaN(), math.NaN()
+	case highest == lowest:
+		return 50, -50
+	}
+	return (closing - lowest) / (highest - lowest) * 100, (highest - closing) / (highest - lowest) * -100
+}
+
+// NewCCIStream (public)期間 period の CCIStream を生成する
+func NewCCIStream(period int) *CCIStream {
+	return &CCIStream{Period: period}
+}
+
+// Next (public)次の日付の高値・安値・終値を与え、その日付の CCI を返す
+func (s *CCIStream) Next(high float64, low float64, closing float64) float64 {
+	if s.Period <= 0 {
+		return math.NaN()
+	}
+	s.Window = append(s.Window, typicalPriceOf(high, low, closing))
+	if len(s.Window) > s.Period {
+		s.Window = s.Window[1:]
+	}
+	if len(s.Window) < s.Period {
+		return math.NaN()
+	}
+	// 系列関数と同じく新しい日付から合計し、平均を一致させる
+	sum := 0.0
+	for j := len(s.Window) - 1; j >= 0; j-- {
+		sum += s.Window[j]
+	}
+	average := sum / float64(s.Period)
+	meanDeviation := 0.0
+	for j := len(s.Window) - 1; j >= 0; j-- {
+		meanDeviation += math.Abs(s.Window[j] - average)
+	}
+	meanDeviation /= float64(s.Period)
+	if meanDeviation == 0 {
+		return 0
+	}
+	return (s.Window[len(s.Window)-1] - average) / (cciConstant * meanDeviation)
+}
+
+// NewVWAPStream (public)期間 period の VWAPStream を生成する
+func NewVWAPStream(period int) *VWAPStream {
+	return &VWAPStream{PriceVolume: WindowSum{Period: period}, Volume: WindowSum{Period: period}, Price: WindowSum{Period: period}}
+}
+
+// Next (public)次の日付の高値・安値・終値・出来高を与え、その日付の VWAP を返す
+func (s *VWAPStream) Next(high float64, low float64, closing float64, volume float64) float64 {
+	price := typicalPriceOf(high, low, closing)
+	s.PriceVolume.add(price * volume)
+	s.Volume.add(volume)
+	if s.Price.add(price) == false {
+		return math.NaN()
+	}
+	if s.Volume.Sum == 0 {
+		return s.Price.Sum / float64(s.Price.Period)
+	}
+	return s.PriceVolume.Sum / s.Volume.Sum
+}
+
+// NewMFIStream (public)期間 period の MFIStream を生成する
+func NewMFIStream(period int) *MFIStream {
+	return &MFIStream{Positive: WindowSum{Period: period}, Negative: WindowSum{Period: period}}
+}
+
+// Next (public)次の日付の高値・安値・終値・出来高を与え、その日付の MFI を返す
+func (s *MFIStream) Next(high float64, low float64, closing float64, volume float64) float64 {
+	price := typicalPriceOf(high, low, closing)
+	if s.HasPrevious == false {
+		// 最古の日付は前日の典型価格がないため期間に含めない
+		s.PreviousTypicalPrice, s.HasPrevious = price, true
+		return math.NaN()
+	}
+	positive, negative := 0.0, 0.0
+	if price > s.PreviousTypicalPrice {
+		positive = price * volume
+	} else if price < s.PreviousTypicalPrice {
+		negative = price * volume
+	}
+	s.PreviousTypicalPrice = price
+	s.Negative.add(negative)
+	if s.Positive.add(positive) == false {
+		return math.NaN()
+	}
+	switch {
+	case s.Positive.Sum == 0 && s.Negative.Sum == 0:
+		return 50
+	case s.Negative.Sum == 0:
+		return 100
+	}
+	return 100 - (100 / (1 + s.Positive.Sum/s.Negative.Sum))
+}
+
+// NewCMFStream (public)期間 period の CMFStream を生成する
+func NewCMFStream(period int) *CMFStream {
+	return &CMFStream{FlowVolume: WindowSum{Period: period}, Volume: WindowSum{Period: period}}
+}
+
+// Next (public)次の日付の高値・安値・終値・出来高を与え、その日付の CMF を返す
+func (s *CMFStream) Next(high float64, low float64, closing float64, volume float64) float64 {
+	s.FlowVolume.add(moneyFlowMultiplier(high, low, closing) * volume)
+	if s.Volume.add(volume) == false {
+		return math.NaN()
+	}
+	if s.Volume.Sum == 0 {
+		return 0
+	}
+	return s.FlowVolume.Sum / s.Volume.Sum
+}
+
+// NewIchimokuStream (public)期間 periods の IchimokuStream を生成する
+func NewIchimokuStream(periods IchimokuPeriods) *IchimokuStream {
+	return &IchimokuStream{
+		Periods: periods,
+		Tenkan:  *NewHighLowStream(periods.Tenkan),
+		Kijun:   *NewHighLowStream(periods.Kijun),
+		SenkouB: *NewHighLowStream(periods.SenkouB),
+	}
+}
+
+// Next (public)次の日付の高値・安値・終値を与え、その日付の一目均衡表の各値を返す
+func (s *IchimokuStream) Next(high float64, low float64, closing float64) IchimokuPoint {
+	s.Count++
+	var point IchimokuPoint
+	tenkanHigh, tenkanLow := s.Tenkan.Next(high, low)
+	kijunHigh, kijunLow := s.Kijun.Next(high, low)
+	senkouBHigh, senkouBLow := s.SenkouB.Next(high, low)
+	point.Tenkan = (tenkanHigh + tenkanLow) / 2
+	point.Kijun = (kijunHigh + kijunLow) / 2
+	point.SenkouALead = (point.Tenkan + point.Kijun) / 2
+	point.SenkouBLead = (senkouBHigh + senkouBLow) / 2
+
+	// Periods.Kijun 本前の値(Periods.Kijun 本前の足がない、または先行スパンを計算できなかった日付は NaN)
+	shift := s.Periods.Kijun
+	point.SenkouA, point.SenkouB, point.ChikouDiff = math.NaN(), math.NaN(), math.NaN()
+	if len(s.Closing) == shift {
+		if s.Count-shift >= max(s.Periods.Tenkan, s.Periods.Kijun) {
+			point.SenkouA = s.LeadA[0]
+		}
+		if s.Count-shift >= s.Periods.SenkouB {
+			point.SenkouB = s.LeadB[0]
+		}
+		point.ChikouDiff = closing - s.Closing[0]
+	}
+	s.LeadA = shiftWindow(s.LeadA, zeroIfNaN(point.SenkouALead), shift)
+	s.LeadB = shiftWindow(s.LeadB, zeroIfNaN(point.SenkouBLead), shift)
+	s.Closing = shiftWindow(s.Closing, closing, shift)
+	return point
+}
+
+//---- private function ----
+
+// Wilder・EMA の平滑化係数
+func (s *SmoothStream) alpha() float64 {
+	if s.Method == SmoothingEMA {
+		return 2.0 / float64(s.Period+1)
+	}
+	return 1.0 / float64(s.Period)
+}
+
+// SMA の逐次計算。直近 Period 個の移動合計を更新する
+func (s *SmoothStream) nextSMA(value float64) float64 {
+	if s.NaNAge > 0 {
+		s.NaNAge--
+	}
+	if math.IsNaN(value) {
+		// NaN を含む期間は NaN とするため、NaN が期間から外れるまでの本数を記録する
+		s.NaNAge = s.Period
+		value = 0
+	}
+	s.Window = append(s.Window, value)
+	s.Sum += value
+	if len(s.Window) > s.Period {
+		s.Sum -= s.Window[0]
+		s.Window = s.Window[1:]
+	}
+	if len(s.Window) < s.Period || s.NaNAge > 0 {
+		return math.NaN()
+	}
+	return s.Sum / float64(s.Period)
+}
+
+// 移動合計に次の日付の値 value を加え、期間分の値が揃ったかを返す
+func (w *WindowSum) add(value float64) bool {
+	if w.Period <= 0 {
+		return false
+	}
+	w.Window = append(w.Window, value)
+	w.Sum += value
+	if value != 0 {
+		w.NonZero++
+	}
+	if len(w.Window) > w.Period {
+		if w.Window[0] != 0 {
+			w.NonZero--
+		}
+		w.Sum -= w.Window[0]
+		w.Window = w.Window[1:]
+	}
+	if w.NonZero == 0 {
+		w.Sum = 0
+	}
+	return len(w.Window) == w.Period
+}
+
+// 足の番号 bar の値 value を加え、番号が oldest より前の値と isReplaced が true となる末尾の値を取り除いて、期間内の最大値(最小値)を返す
+func (d *ExtremeDeque) push(bar int, value float64, oldest int, isReplaced func(back float64) bool) float64 {
+	for len(d.Values) > 0 && isReplaced(d.Values[len(d.Values)-1]) {
+		d.Bars, d.Values = d.Bars[:len(d.Bars)-1], d.Values[:len(d.Values)-1]
+	}
+	d.Bars, d.Values = append(d.Bars, bar), append(d.Values, value)
+	for d.Bars[0] < oldest {
+		d.Bars, d.Values = d.Bars[1:], d.Values[1:]
+	}
+	return d.Values[0]
+}
+
+// 直近 length 個の値(日付昇順)に value を加え、length 個を超えた古い値を取り除く
+func shiftWindow(window []float64, value float64, length int) []float64 {
+	window = append(window, value)
+	if len(window) > length {
+		window = window[1:]
+	}
+	return window
+}
+
+// 状態を JSON で保存するため、NaN を 0 として保持する
+func zeroIfNaN(value float64) float64 {
+	if math.IsNaN(value) {
+		return 0
+	}
+	return value
+}
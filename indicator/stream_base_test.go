@@ -0,0 +1,240 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+)
+
+// 日付降順の系列を日付昇順に逐次計算器へ与え、結果を日付降順に並べて返す
+func streamSeries(length int, next func(i int) float64) []float64 {
+	result := make([]float64, length)
+	for i := length - 1; i >= 0; i-- {
+		result[i] = next(i)
+	}
+	return result
+}
+
+func TestSmoothStream(t *testing.T) {
+	nan := math.NaN()
+	closing := descending(chartSchoolRSIClosing)
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+	}{
+		{"reference", closing, 14},
+		{"short period", closing, 3},
+		{"oldest NaN", []float64{5, 4, 3, 2, nan}, 3},
+		{"NaN inside", []float64{6, 5, 4, nan, 2, 1, 0}, 2},
+		{"not enough data", []float64{2, 1}, 3},
+	}
+	for _, tt := range tests {
+		for _, method := range []Smoothing{SmoothingSMA, SmoothingWilder, SmoothingEMA} {
+			t.Run(tt.name+"/"+string(method), func(t *testing.T) {
+				stream := NewSmoothStream(tt.period, method)
+				got := streamSeries(len(tt.values), func(i int) float64 { return stream.Next(tt.values[i]) })
+				assertSeries(t, "SmoothStream", got, Smooth(tt.values, tt.period, method), 1e-9)
+			})
+		}
+	}
+}
+
+func TestRSIStream(t *testing.T) {
+	closing := descending(chartSchoolRSIClosing)
+	for _, method := range []Smoothing{SmoothingSMA, SmoothingWilder, SmoothingEMA} {
+		t.Run(string(method), func(t *testing.T) {
+			stream := NewRSIStream(14, method)
+			got := streamSeries(len(closing), func(i int) float64 { return stream.Next(closing[i]) })
+			assertSeries(t, "RSIStream", got, RSI(closing, 14, method), 1e-9)
+		})
+	}
+}
+
+func TestATRStream(t *testing.T) {
+	high, low, closing := descending(chartSchoolATRHigh), descending(chartSchoolATRLow), descending(chartSchoolATRClosing)
+	for _, method := range []Smoothing{SmoothingSMA, SmoothingWilder, SmoothingEMA} {
+		t.Run(string(method), func(t *testing.T) {
+			stream := NewATRStream(14, method)
+			got := streamSeries(len(closing), func(i int) float64 { return stream.Next(high[i], low[i], closing[i]) })
+			assertSeries(t, "ATRStream", got, ATR(high, low, closing, 14, method), 1e-9)
+		})
+	}
+}
+
+func TestMACDStream(t *testing.T) {
+	closing := descending(chartSchoolRSIClosing)
+	for _, base := range []Smoothing{SmoothingSMA, SmoothingEMA} {
+		t.Run(string(base), func(t *testing.T) {
+			want, _ := MACD(Smooth(closing, 5, base), Smooth(closing, 12, base), 4)
+			stream := NewMACDStream(base, 5, 12, 4)
+			points := make([]MACDPoint, len(closing))
+			for i := len(closing) - 1; i >= 0; i-- {
+				points[i] = stream.Next(closing[i])
+			}
+			field := func(get func(p MACDPoint) float64) []float64 {
+				values := make([]float64, len(points))
+				for i, p := range points {
+					values[i] = get(p)
+				}
+				return values
+			}
+			assertSeries(t, "Value", field(func(p MACDPoint) float64 { return p.Value }), want.Value, 1e-9)
+			assertSeries(t, "SignalSMA", field(func(p MACDPoint) float64 { return p.SignalSMA }), want.SignalSMA, 1e-9)
+			assertSeries(t, "HistoSMA", field(func(p MACDPoint) float64 { return p.HistoSMA }), want.HistoSMA, 1e-9)
+			assertSeries(t, "SignalEMA", field(func(p MACDPoint) float64 { return p.SignalEMA }), want.SignalEMA, 1e-9)
+			assertSeries(t, "HistoEMA", field(func(p MACDPoint) float64 { return p.HistoEMA }), want.HistoEMA, 1e-9)
+		})
+	}
+}
+
+func TestDMIStream(t *testing.T) {
+	high, low, closing := descending(chartSchoolATRHigh), descending(chartSchoolATRLow), descending(chartSchoolATRClosing)
+	wantPlus, wantMinus, wantADX := DMI(high, low, closing, 5)
+	stream := NewDMIStream(5)
+	plusDI, minusDI, adx := make([]float64, len(closing)), make([]float64, len(closing)), make([]float64, len(closing))
+	for i := len(closing) - 1; i >= 0; i-- {
+		plusDI[i], minusDI[i], adx[i] = stream.Next(high[i], low[i], closing[i])
+	}
+	assertSeries(t, "+DI", plusDI, wantPlus, 1e-9)
+	assertSeries(t, "-DI", minusDI, wantMinus, 1e-9)
+	assertSeries(t, "ADX", adx, wantADX, 1e-9)
+}
+
+func TestParabolicSARStream(t *testing.T) {
+	tests := []struct {
+		name         string
+		high         []float64
+		low          []float64
+		closing      []float64
+		acceleration SARAcceleration
+	}{
+		{"reference", descending(chartSchoolATRHigh), descending(chartSchoolATRLow), descending(chartSchoolATRClosing), DefaultSARAcceleration},
+		{"reference fast", descending(chartSchoolATRHigh), descending(chartSchoolATRLow), descending(chartSchoolATRClosing), SARAcceleration{Start: 0.1, Step: 0.1, Max: 0.5}},
+		{"down start", []float64{8, 9, 10}, []float64{7, 8, 9}, []float64{7.5, 8.5, 9.5}, DefaultSARAcceleration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantSAR, wantTrend := ParabolicSAR(tt.high, tt.low, tt.closing, tt.acceleration)
+			stream := NewParabolicSARStream(tt.acceleration)
+			sar, trend := make([]float64, len(tt.closing)), make([]float64, len(tt.closing))
+			for i := len(tt.closing) - 1; i >= 0; i-- {
+				sar[i], trend[i] = stream.Next(tt.high[i], tt.low[i], tt.closing[i])
+			}
+			assertSeries(t, "SAR", sar, wantSAR, 1e-9)
+			assertSeries(t, "trend", trend, wantTrend, 1e-9)
+		})
+	}
+}
+
+func TestVolumeStream(t *testing.T) {
+	obvStream := &OBVStream{}
+	obv := streamSeries(len(volumeClosing), func(i int) float64 { return obvStream.Next(volumeClosing[i], volumeVolume[i]) })
+	assertSeries(t, "OBV", obv, OBV(volumeClosing, volumeVolume), 1e-9)
+
+	adStream := &ADLineStream{}
+	ad := streamSeries(len(volumeClosing), func(i int) float64 {
+		return adStream.Next(volumeHigh[i], volumeLow[i], volumeClosing[i], volumeVolume[i])
+	})
+	assertSeries(t, "ADLine", ad, ADLine(volumeHigh, volumeLow, volumeClosing, volumeVolume), 1e-9)
+}
+
+func TestStdDevStream(t *testing.T) {
+	closing := descending(chartSchoolRSIClosing)
+	for _, period := range []int{1, 3, 14} {
+		stream := NewStdDevStream(period)
+		got := streamSeries(len(closing), func(i int) float64 { return stream.Next(closing[i]) })
+		assertSeries(t, "StdDevStream", got, StdDev(closing, period), 1e-9)
+	}
+}
+
+func TestHighLowStream(t *testing.T) {
+	high, low, closing := descending(chartSchoolATRHigh), descending(chartSchoolATRLow), descending(chartSchoolATRClosing)
+	for _, period := range []int{1, 2, 9} {
+		stream := NewHighLowStream(period)
+		mid := streamSeries(len(high), func(i int) float64 {
+			highest, lowest := stream.Next(high[i], low[i])
+			return (highest + lowest) / 2
+		})
+		assertSeries(t, "HighLowStream", mid, MidPrice(high, low, period), 0)
+		// 期間内の候補のみを保持する
+		if len(stream.Highest.Values) > period || len(stream.Lowest.Values) > period {
+			t.Errorf("period %d: deque length = %d, %d", period, len(stream.Highest.Values), len(stream.Lowest.Values))
+		}
+
+		stoch := NewStochasticsStream(period)
+		fastK, williamsR := make([]float64, len(closing)), make([]float64, len(closing))
+		for i := len(closing) - 1; i >= 0; i-- {
+			fastK[i], williamsR[i] = stoch.Next(high[i], low[i], closing[i])
+		}
+		wantK, wantR := Stochastics(high, low, closing, period)
+		assertSeries(t, "%K", fastK, wantK, 1e-9)
+		assertSeries(t, "%R", williamsR, wantR, 1e-9)
+	}
+}
+
+func TestCCIStream(t *testing.T) {
+	high, low, closing := descending(chartSchoolATRHigh), descending(chartSchoolATRLow), descending(chartSchoolATRClosing)
+	flat := []float64{10, 10, 10, 10}
+	stream := NewCCIStream(5)
+	got := streamSeries(len(closing), func(i int) float64 { return stream.Next(high[i], low[i], closing[i]) })
+	assertSeries(t, "CCIStream", got, CCI(high, low, closing, 5), 0)
+	flatStream := NewCCIStream(3)
+	got = streamSeries(len(flat), func(i int) float64 { return flatStream.Next(flat[i], flat[i], flat[i]) })
+	assertSeries(t, "CCIStream flat", got, CCI(flat, flat, flat, 3), 0)
+}
+
+func TestWindowVolumeStream(t *testing.T) {
+	high, low, closing := descending(chartSchoolATRHigh), descending(chartSchoolATRLow), descending(chartSchoolATRClosing)
+	// 期間内の出来高が全て 0 となる日付を含める
+	volume := make([]float64, len(closing))
+	for i := range volume {
+		if i < 5 || i > 10 {
+			volume[i] = float64(1000 + 37*i)
+		}
+	}
+	for _, period := range []int{2, 5} {
+		vwap, mfi, cmf := NewVWAPStream(period), NewMFIStream(period), NewCMFStream(period)
+		gotVWAP := streamSeries(len(closing), func(i int) float64 { return vwap.Next(high[i], low[i], closing[i], volume[i]) })
+		gotMFI := streamSeries(len(closing), func(i int) float64 { return mfi.Next(high[i], low[i], closing[i], volume[i]) })
+		gotCMF := streamSeries(len(closing), func(i int) float64 { return cmf.Next(high[i], low[i], closing[i], volume[i]) })
+		assertSeries(t, "VWAPStream", gotVWAP, VWAP(high, low, closing, volume, period), 1e-9)
+		assertSeries(t, "MFIStream", gotMFI, MFI(high, low, closing, volume, period), 1e-9)
+		assertSeries(t, "CMFStream", gotCMF, CMF(high, low, closing, volume, period), 1e-9)
+	}
+}
+
+func TestIchimokuStream(t *testing.T) {
+	high, low, closing := descending(chartSchoolATRHigh), descending(chartSchoolATRLow), descending(chartSchoolATRClosing)
+	for _, periods := range []IchimokuPeriods{{Tenkan: 2, Kijun: 3, SenkouB: 5}, {Tenkan: 5, Kijun: 3, SenkouB: 4}} {
+		stream := NewIchimokuStream(periods)
+		points := make([]IchimokuPoint, len(closing))
+		for i := len(closing) - 1; i >= 0; i-- {
+			points[i] = stream.Next(high[i], low[i], closing[i])
+		}
+		field := func(get func(p IchimokuPoint) float64) []float64 {
+			values := make([]float64, len(points))
+			for i, p := range points {
+				values[i] = get(p)
+			}
+			return values
+		}
+		tenkan, kijun := MidPrice(high, low, periods.Tenkan), MidPrice(high, low, periods.Kijun)
+		leadA := make([]float64, len(closing))
+		for i := range leadA {
+			leadA[i] = (tenkan[i] + kijun[i]) / 2
+		}
+		leadB := MidPrice(high, low, periods.SenkouB)
+		chikou := Shift(closing, periods.Kijun)
+		chikouDiff := make([]float64, len(closing))
+		for i := range chikouDiff {
+			chikouDiff[i] = closing[i] - chikou[i]
+		}
+		assertSeries(t, "Tenkan", field(func(p IchimokuPoint) float64 { return p.Tenkan }), tenkan, 0)
+		assertSeries(t, "Kijun", field(func(p IchimokuPoint) float64 { return p.Kijun }), kijun, 0)
+		assertSeries(t, "SenkouALead", field(func(p IchimokuPoint) float64 { return p.SenkouALead }), leadA, 0)
+		assertSeries(t, "SenkouBLead", field(func(p IchimokuPoint) float64 { return p.SenkouBLead }), leadB, 0)
+		assertSeries(t, "SenkouA", field(func(p IchimokuPoint) float64 { return p.SenkouA }), Shift(leadA, periods.Kijun), 0)
+		assertSeries(t, "SenkouB", field(func(p IchimokuPoint) float64 { return p.SenkouB }), Shift(leadB, periods.Kijun), 0)
+		assertSeries(t, "ChikouDiff", field(func(p IchimokuPoint) float64 { return p.ChikouDiff }), chikouDiff, 0)
+	}
+}
@@ -0,0 +1,306 @@
+// indicator テクニカル指標の計算パッケージ
+package indicator // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sv_stockcheck/convert"
+)
+
+// トレンドの強さ・トレンド転換を表すテクニカル指標
+
+// ---- struct
+
+// SARAcceleration パラボリックSARの加速因子
+type SARAcceleration struct {
+	Start float64 // 初期値(トレンド転換時の値)
+	Step  float64 // 極値を更新する毎の増分
+	Max   float64 // 上限
+}
+
+// IchimokuPeriods 一目均衡表の期間
+type IchimokuPeriods struct {
+	Tenkan  int // 転換線の期間
+	Kijun   int // 基準線の期間(先行スパン、遅行スパンのずらす期間)
+	SenkouB int // 先行スパンBの期間
+}
+
+// MACDDefinition MACDの定義
+type MACDDefinition struct {
+	Name   string    // ModelDataのカラム名の接頭辞(カラム名は Name + "MACD"、Name + "MACDSignalSMA" など)
+	Base   Smoothing // 短期・長期の移動平均の種別(sma / ema)
+	Fast   int       // 短期の移動平均の期間
+	Slow   int       // 長期の移動平均の期間
+	Signal int       // シグナルラインの期間
+}
+
+// MACDResult MACDの各系列
+type MACDResult struct {
+	Value     []float64 // MACD(短期の移動平均 - 長期の移動平均)
+	SignalSMA []float64 // シグナルライン(MACD の単純移動平均)
+	HistoSMA  []float64 // ヒストグラム(MACD - SignalSMA)
+	SignalEMA []float64 // シグナルライン(MACD の指数移動平均)
+	HistoEMA  []float64 // ヒストグラム(MACD - SignalEMA)
+}
+
+// ---- Global Variable
+
+// DefaultSARAcceleration Wilder の標準の加速因子(0.02 から 0.02 ずつ 0.2 まで)
+var DefaultSARAcceleration = SARAcceleration{Start: 0.02, Step: 0.02, Max: 0.2}
+
+// DefaultIchimokuPeriods 一目均衡表の標準の期間(転換線 9、基準線 26、先行スパンB 52)
+var DefaultIchimokuPeriods = IchimokuPeriods{Tenkan: 9, Kijun: 26, SenkouB: 52}
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// Validate (public)加速因子が 0 < Start <= Max、0 <= Step であることを確認する
+func (a SARAcceleration) Validate() error {
+	if a.Start <= 0 || a.Step < 0 || a.Max < a.Start {
+		return fmt.Errorf("invalid parabolic SAR acceleration (start=%v, step=%v, max=%v)", a.Start, a.Step, a.Max)
+	}
+	return nil
+}
+
+// Validate (public)一目均衡表の期間が全て 1 以上であることを確認する
+func (p IchimokuPeriods) Validate() error {
+	if p.Tenkan < 1 || p.Kijun < 1 || p.SenkouB < 1 {
+		return fmt.Errorf("invalid ichimoku periods (tenkan=%d, kijun=%d, senkouB=%d)", p.Tenkan, p.Kijun, p.SenkouB)
+	}
+	return nil
+}
+
+// ParseMACDDefinitions (public)MACDの定義のカンマ区切り文字列を変換する
+// 定義は [名前=]種別:短期:長期:シグナル (例: short=sma:5:30:9、ema:12:26:9)。名前を省略した場合は ema12_26_9 のように付ける
+func ParseMACDDefinitions(str string) ([]MACDDefinition, error) {
+	var defs []MACDDefinition
+	for _, v := range convert.SplitList(str) {
+		var def MACDDefinition
+		spec := v
+		if name, rest, found := strings.Cut(v, "="); found {
+			def.Name, spec = name, rest
+		}
+		fields := strings.Split(spec, ":")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid macd %q ([name=]sma|ema:fast:slow:signal)", v)
+		}
+		def.Base = Smoothing(strings.ToLower(fields[0]))
+		if def.Base != SmoothingSMA && def.Base != SmoothingEMA {
+			return nil, fmt.Errorf("invalid macd %q (base must be sma or ema)", v)
+		}
+		periods := make([]int, 3)
+		for i, field := range fields[1:] {
+			period, err := strconv.Atoi(field)
+			if err != nil || period < 1 {
+				return nil, fmt.Errorf("invalid macd %q (periods must be positive integers)", v)
+			}
+			periods[i] = period
+		}
+		def.Fast, def.Slow, def.Signal = periods[0], periods[1], periods[2]
+		if def.Fast >= def.Slow {
+			return nil, fmt.Errorf("invalid macd %q (fast must be shorter than slow)", v)
+		}
+		if def.Name == "" {
+			def.Name = fmt.Sprintf("%s%d_%d_%d", def.Base, def.Fast, def.Slow, def.Signal)
+		}
+		if slices.ContainsFunc(defs, func(d MACDDefinition) bool { return d.Name == def.Name }) {
+			return nil, fmt.Errorf("duplicate macd name %q", def.Name)
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+// ParseSARAcceleration (public)パラボリックSARの加速因子(初期値,増分,上限)のカンマ区切り文字列を変換する
+func ParseSARAcceleration(str string) (SARAcceleration, error) {
+	fields := convert.SplitList(str)
+	if len(fields) != 3 {
+		return SARAcceleration{}, fmt.Errorf("invalid psar %q (start,step,max)", str)
+	}
+	values := make([]float64, 3)
+	for i, field := range fields {
+		value, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return SARAcceleration{}, fmt.Errorf("invalid psar %q (start,step,max)", str)
+		}
+		values[i] = value
+	}
+	acceleration := SARAcceleration{Start: values[0], Step: values[1], Max: values[2]}
+	return acceleration, acceleration.Validate()
+}
+
+// ParseIchimokuPeriods (public)一目均衡表の期間(転換線,基準線,先行スパンB)のカンマ区切り文字列を変換する
+func ParseIchimokuPeriods(str string) (IchimokuPeriods, error) {
+	fields := convert.SplitList(str)
+	if len(fields) != 3 {
+		return IchimokuPeriods{}, fmt.Errorf("invalid ichimoku %q (tenkan,kijun,senkouB)", str)
+	}
+	values := make([]int, 3)
+	for i, field := range fields {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return IchimokuPeriods{}, fmt.Errorf("invalid ichimoku %q (tenkan,kijun,senkouB)", str)
+		}
+		values[i] = value
+	}
+	periods := IchimokuPeriods{Tenkan: values[0], Kijun: values[1], SenkouB: values[2]}
+	return periods, periods.Validate()
+}
+
+// MACD (public)短期・長期の移動平均から MACD を計算する
+// シグナルラインは期間 signal の MACD の単純移動平均(SMA)、指数移動平均(EMA)の2種類を計算する
+func MACD(fastAverage []float64, slowAverage []float64, signal int) (MACDResult, error) {
+
+	if len(fastAverage) != len(slowAverage) {
+		return MACDResult{}, fmt.Errorf("length of fast and slow averages must be the same (%d, %d)", len(fastAverage), len(slowAverage))
+	}
+	value := make([]float64, len(fastAverage))
+	for i := range fastAverage {
+		value[i] = fastAverage[i] - slowAverage[i]
+	}
+	result := MACDResult{
+		Value:     value,
+		SignalSMA: Smooth(value, signal, SmoothingSMA),
+		SignalEMA: Smooth(value, signal, SmoothingEMA),
+		HistoSMA:  make([]float64, len(value)),
+		HistoEMA:  make([]float64, len(value)),
+	}
+	for i := range value {
+		result.HistoSMA[i] = value[i] - result.SignalSMA[i] // シグナルが NaN の日付は NaN
+		result.HistoEMA[i] = value[i] - result.SignalEMA[i]
+	}
+	return result, nil
+}
+
+// MidPrice (public)期間 period の最高値と最安値の中間値を計算する(一目均衡表の転換線、基準線、先行スパンB)
+func MidPrice(high []float64, low []float64, period int) []float64 {
+	result := make([]float64, len(high))
+	for i := range high {
+		if period <= 0 || i+period > len(high) {
+			result[i] = math.NaN()
+			continue
+		}
+		result[i] = (slices.Max(high[i:i+period]) + slices.Min(low[i:i+period])) / 2
+	}
+	return result
+}
+
+// DMI (public)期間 period の +DI、-DI、ADX を計算する(Wilder の平滑化)
+// +DM = 高値の上昇幅、-DM = 安値の下落幅(大きい方のみ、もう一方は 0)、+DI / -DI = 平滑化した ±DM / 平滑化した真の値幅 * 100
+// ADX は DX = |+DI - -DI| / (+DI + -DI) * 100 の平滑化。最古の日付は前日がないため計算しない
+func DMI(high []float64, low []float64, closing []float64, period int) ([]float64, []float64, []float64) {
+
+	plusDM := make([]float64, len(closing))
+	minusDM := make([]float64, len(closing))
+	trueRange := TrueRange(high, low, closing)
+	for i := range closing {
+		if i+1 >= len(closing) {
+			plusDM[i] = math.NaN()
+			minusDM[i] = math.NaN()
+			trueRange[i] = math.NaN()
+			continue
+		}
+		upMove := high[i] - high[i+1]
+		downMove := low[i+1] - low[i]
+		if upMove > downMove && upMove > 0 {
+			plusDM[i] = upMove
+		}
+		if downMove > upMove && downMove > 0 {
+			minusDM[i] = downMove
+		}
+	}
+
+	smoothedPlusDM := Smooth(plusDM, period, SmoothingWilder)
+	smoothedMinusDM := Smooth(minusDM, period, SmoothingWilder)
+	smoothedTrueRange := Smooth(trueRange, period, SmoothingWilder)
+	plusDI := make([]float64, len(closing))
+	minusDI := make([]float64, len(closing))
+	dx := make([]float64, len(closing))
+	for i := range closing {
+		if math.IsNaN(smoothedTrueRange[i]) {
+			plusDI[i], minusDI[i], dx[i] = math.NaN(), math.NaN(), math.NaN()
+			continue
+		}
+		if smoothedTrueRange[i] == 0 {
+			plusDI[i], minusDI[i], dx[i] = 0, 0, 0
+			continue
+		}
+		plusDI[i] = smoothedPlusDM[i] / smoothedTrueRange[i] * 100
+		minusDI[i] = smoothedMinusDM[i] / smoothedTrueRange[i] * 100
+		if plusDI[i]+minusDI[i] == 0 {
+			dx[i] = 0
+			continue
+		}
+		dx[i] = math.Abs(plusDI[i]-minusDI[i]) / (plusDI[i] + minusDI[i]) * 100
+	}
+	return plusDI, minusDI, Smooth(dx, period, SmoothingWilder)
+}
+
+// ParabolicSAR (public)パラボリックSARとトレンド(上昇 1、下降 -1)を計算する
+// 2番目に古い日付の終値が最古の日付の終値以上なら上昇トレンドから開始し、SAR は2日間の安値(下降は高値)とする
+// 以降は SAR = 前日SAR + 加速因子 * (極値 - 前日SAR) とし、SAR を超えて価格が反転した日にトレンドを転換する
+// 最古の日付は NaN
+func ParabolicSAR(high []float64, low []float64, closing []float64, acceleration SARAcceleration) ([]float64, []float64) {
+
+	sar := make([]float64, len(closing))
+	trend := make([]float64, len(closing))
+	for i := range sar {
+		sar[i], trend[i] = math.NaN(), math.NaN()
+	}
+	oldest := len(closing) - 1
+	if oldest < 1 {
+		return sar, trend
+	}
+
+	// 日付昇順に計算する(i は日付降順のインデックス)
+	isUp := closing[oldest-1] >= closing[oldest]
+	af := acceleration.Start
+	var extreme float64
+	if isUp {
+		sar[oldest-1] = min(low[oldest], low[oldest-1])
+		extreme = max(high[oldest], high[oldest-1])
+	} else {
+		sar[oldest-1] = max(high[oldest], high[oldest-1])
+		extreme = min(low[oldest], low[oldest-1])
+	}
+	trend[oldest-1] = trendValue(isUp)
+
+	for i := oldest - 2; i >= 0; i-- {
+		next := sar[i+1] + af*(extreme-sar[i+1])
+		if isUp {
+			// 前日・前々日の安値より上にはしない
+			next = slices.Min([]float64{next, low[i+1], low[i+2]})
+			if low[i] < next {
+				isUp, next, extreme, af = false, extreme, low[i], acceleration.Start
+			} else if high[i] > extreme {
+				extreme, af = high[i], min(af+acceleration.Step, acceleration.Max)
+			}
+		} else {
+			// 前日・前々日の高値より下にはしない
+			next = slices.Max([]float64{next, high[i+1], high[i+2]})
+			if high[i] > next {
+				isUp, next, extreme, af = true, extreme, high[i], acceleration.Start
+			} else if low[i] < extreme {
+				extreme, af = low[i], min(af+acceleration.Step, acceleration.Max)
+			}
+		}
+		sar[i] = next
+		trend[i] = trendValue(isUp)
+	}
+	return sar, trend
+}
+
+//---- private function ----
+
+// トレンドの方向を数値(上昇 1、下降 -1)に変換する
+func trendValue(isUp bool) float64 {
+	if isUp == true {
+		return 1
+	}
+	return -1
+}
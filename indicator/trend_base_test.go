@@ -0,0 +1,157 @@
+package indicator
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDMI(t *testing.T) {
+	// 日付昇順で記載
+	high := descending([]float64{10, 11, 12, 11.5, 13, 12, 11, 12.5})
+	low := descending([]float64{9, 9.5, 11, 10, 11.5, 10.5, 9.5, 11})
+	closing := descending([]float64{9.5, 10.5, 11.5, 10.5, 12.5, 11, 10, 12})
+
+	// 期待値は Wilder の原典の方法(期間の合計を初期値とし、以降は 前回値 - 前回値 / 期間 + 当日値)で計算した値
+	plusDI, minusDI, adx := DMI(high, low, closing, 3)
+	assertSeries(t, "+DI", plusDI, withLeadingNaN(3, []float64{44.4444444444, 51.5151515152, 33.3333333333, 23.8596491228, 38.8717948718}), 1e-8)
+	assertSeries(t, "-DI", minusDI, withLeadingNaN(3, []float64{22.2222222222, 12.1212121212, 25.4901960784, 37.1929824561, 21.7435897436}), 1e-8)
+	assertSeries(t, "ADX", adx, withLeadingNaN(5, []float64{36.1904761905, 31.4066776136, 30.3568488095}), 1e-8)
+}
+
+func TestDMIFlat(t *testing.T) {
+	flat := []float64{10, 10, 10, 10}
+	plusDI, minusDI, adx := DMI(flat, flat, flat, 2)
+	nan := math.NaN()
+	assertSeries(t, "+DI", plusDI, []float64{0, 0, nan, nan}, 1e-9)
+	assertSeries(t, "-DI", minusDI, []float64{0, 0, nan, nan}, 1e-9)
+	assertSeries(t, "ADX", adx, []float64{0, nan, nan, nan}, 1e-9)
+}
+
+func TestParabolicSAR(t *testing.T) {
+	// 日付昇順で記載。上昇トレンドで開始し、最新の日付で安値が SAR を下回り下降トレンドに転換する
+	high := descending([]float64{10, 11, 12, 13, 12, 10})
+	low := descending([]float64{9, 10, 11, 12, 10, 8})
+	closing := descending([]float64{9.5, 10.5, 11.5, 12.5, 10.5, 8.5})
+
+	sar, trend := ParabolicSAR(high, low, closing, DefaultSARAcceleration)
+	// 2日目: 2日間の安値 9、3日目: 9 + 0.02 * (11 - 9) = 9.04 は前日・前々日の安値 9 で抑える
+	// 4日目: 9 + 0.04 * (12 - 9) = 9.12、5日目: 9.12 + 0.06 * (13 - 9.12) = 9.3528
+	// 6日目: 安値 8 が SAR を下回るため、極値 13 を SAR として下降トレンドに転換
+	assertSeries(t, "SAR", sar, withLeadingNaN(1, []float64{9, 9, 9.12, 9.3528, 13}), 1e-9)
+	assertSeries(t, "trend", trend, withLeadingNaN(1, []float64{1, 1, 1, 1, -1}), 1e-9)
+}
+
+func TestParabolicSARMaxAcceleration(t *testing.T) {
+	// 日付昇順で高値を更新し続ける上昇トレンド。加速因子は上限 0.04 で止まる
+	high := descending([]float64{10, 11, 12, 13, 14})
+	low := descending([]float64{9, 10, 11, 12, 13})
+	closing := descending([]float64{9.5, 10.5, 11.5, 12.5, 13.5})
+
+	sar, _ := ParabolicSAR(high, low, closing, SARAcceleration{Start: 0.02, Step: 0.02, Max: 0.04})
+	// 9 → 9.04(抑えられて 9) → 9 + 0.04 * (12 - 9) = 9.12 → 9.12 + 0.04 * (13 - 9.12) = 9.2752 (0.06 にはならない)
+	assertSeries(t, "SAR", sar, withLeadingNaN(1, []float64{9, 9, 9.12, 9.2752}), 1e-9)
+}
+
+func TestSARAccelerationValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       SARAcceleration
+		wantErr bool
+	}{
+		{"default", DefaultSARAcceleration, false},
+		{"zero start", SARAcceleration{Start: 0, Step: 0.02, Max: 0.2}, true},
+		{"max below start", SARAcceleration{Start: 0.1, Step: 0.02, Max: 0.05}, true},
+		{"negative step", SARAcceleration{Start: 0.02, Step: -0.01, Max: 0.2}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.a.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMACD(t *testing.T) {
+	nan := math.NaN()
+	fast := []float64{14, 12, 10, 9, nan}
+	slow := []float64{10, 10, 9, 9, nan}
+	// MACD は 4, 2, 1, 0, NaN。期間2のシグナルは SMA 3, 1.5, 0.5、EMA は初期値 0.5 から 2/3 * 2 + 1/3 * 0.5 = 1.5、2/3 * 4 + 1/3 * 1.5 = 3.1666…
+	result, err := MACD(fast, slow, 2)
+	if err != nil {
+		t.Fatalf("MACD error = %v", err)
+	}
+	assertSeries(t, "Value", result.Value, []float64{4, 2, 1, 0, nan}, 1e-9)
+	assertSeries(t, "SignalSMA", result.SignalSMA, []float64{3, 1.5, 0.5, nan, nan}, 1e-9)
+	assertSeries(t, "HistoSMA", result.HistoSMA, []float64{1, 0.5, 0.5, nan, nan}, 1e-9)
+	assertSeries(t, "SignalEMA", result.SignalEMA, []float64{19.0 / 6, 1.5, 0.5, nan, nan}, 1e-9)
+	assertSeries(t, "HistoEMA", result.HistoEMA, []float64{5.0 / 6, 0.5, 0.5, nan, nan}, 1e-9)
+
+	if _, err := MACD(fast, slow[1:], 2); err == nil {
+		t.Errorf("MACD with different lengths error = nil, want error")
+	}
+}
+
+func TestMidPrice(t *testing.T) {
+	nan := math.NaN()
+	high := []float64{12, 15, 11}
+	low := []float64{10, 9, 8}
+	assertSeries(t, "MidPrice", MidPrice(high, low, 2), []float64{12, 11.5, nan}, 1e-9)
+}
+
+func TestParseMACDDefinitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		str     string
+		want    []MACDDefinition
+		wantErr bool
+	}{
+		{"named and auto", "short=sma:5:30:9,EMA:12:26:9", []MACDDefinition{
+			{Name: "short", Base: SmoothingSMA, Fast: 5, Slow: 30, Signal: 9},
+			{Name: "ema12_26_9", Base: SmoothingEMA, Fast: 12, Slow: 26, Signal: 9},
+		}, false},
+		{"unknown base", "wilder:12:26:9", nil, true},
+		{"fast not shorter", "sma:26:12:9", nil, true},
+		{"missing field", "sma:12:26", nil, true},
+		{"duplicate name", "sma:5:30:9,sma:5:30:9", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMACDDefinitions(tt.str)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMACDDefinitions(%q) error = %v, wantErr %v", tt.str, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseMACDDefinitions(%q) = %v, want %v", tt.str, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSARAcceleration(t *testing.T) {
+	got, err := ParseSARAcceleration("0.02,0.02,0.2")
+	if err != nil || got != DefaultSARAcceleration {
+		t.Errorf("ParseSARAcceleration = %v, %v, want %v", got, err, DefaultSARAcceleration)
+	}
+	for _, str := range []string{"0.02,0.02", "a,0.02,0.2", "0.2,0.02,0.1"} {
+		if _, err := ParseSARAcceleration(str); err == nil {
+			t.Errorf("ParseSARAcceleration(%q) error = nil, want error", str)
+		}
+	}
+}
+
+func TestParseIchimokuPeriods(t *testing.T) {
+	got, err := ParseIchimokuPeriods("9,26,52")
+	if err != nil || got != DefaultIchimokuPeriods {
+		t.Errorf("ParseIchimokuPeriods = %v, %v, want %v", got, err, DefaultIchimokuPeriods)
+	}
+	if got, err := ParseIchimokuPeriods("2 3,5"); err != nil || got != (IchimokuPeriods{Tenkan: 2, Kijun: 3, SenkouB: 5}) {
+		t.Errorf("ParseIchimokuPeriods(\"2 3,5\") = %v, %v", got, err)
+	}
+	for _, str := range []string{"9,26", "9,26,52,1", "a,26,52", "9,0,52", "9,26,-1"} {
+		if _, err := ParseIchimokuPeriods(str); err == nil {
+			t.Errorf("ParseIchimokuPeriods(%q) error = nil, want error", str)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// indicator テクニカル指標の計算パッケージ
+package indicator // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"math"
+)
+
+// 出来高を用いるテクニカル指標(株式のみ。為替は出来高がないため計算しない)
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// OBV (public)OBV(On-Balance Volume)を計算する
+// 最古の日付を 0 とし、終値が前日より上昇した日は出来高を加算、下落した日は減算した累計を返す
+func OBV(closing []float64, volume []float64) []float64 {
+	obv := make([]float64, len(closing))
+	for i := len(closing) - 2; i >= 0; i-- {
+		switch {
+		case closing[i] > closing[i+1]:
+			obv[i] = obv[i+1] + volume[i]
+		case closing[i] < closing[i+1]:
+			obv[i] = obv[i+1] - volume[i]
+		default:
+			obv[i] = obv[i+1]
+		}
+	}
+	return obv
+}
+
+// ADLine (public)チャイキンのA/Dライン(Accumulation/Distribution Line)を計算する
+// マネーフロー出来高(MoneyFlowMultiplier * 出来高)の最古の日付からの累計を返す
+func ADLine(high []float64, low []float64, closing []float64, volume []float64) []float64 {
+	ad := make([]float64, len(closing))
+	sum := 0.0
+	for i := len(closing) - 1; i >= 0; i-- {
+		sum += moneyFlowMultiplier(high[i], low[i], closing[i]) * volume[i]
+		ad[i] = sum
+	}
+	return ad
+}
+
+// VWAP (public)期間 period の出来高加重平均価格(典型価格 (高値+安値+終値)/3 を出来高で加重)を計算する
+// 期間内の出来高が 0 の場合は典型価格の単純平均とする
+func VWAP(high []float64, low []float64, closing []float64, volume []float64, period int) []float64 {
+	typicalPrice := TypicalPrice(high, low, closing)
+	vwap := make([]float64, len(closing))
+	for i := range closing {
+		if i+period > len(closing) {
+			vwap[i] = math.NaN()
+			continue
+		}
+		sumPriceVolume, sumVolume, sumPrice := 0.0, 0.0, 0.0
+		for j := i; j < i+period; j++ {
+			sumPriceVolume += typicalPrice[j] * volume[j]
+			sumVolume += volume[j]
+			sumPrice += typicalPrice[j]
+		}
+		if sumVolume == 0 {
+			vwap[i] = sumPrice / float64(period)
+			continue
+		}
+		vwap[i] = sumPriceVolume / sumVolume
+	}
+	return vwap
+}
+
+// MFI (public)期間 period のMFI(Money Flow Index)を計算する
+// 典型価格 * 出来高 を典型価格が前日より上昇した日(ポジティブ)、下落した日(ネガティブ)に分けて合計し、
+// 100 - 100 / (1 + ポジティブ / ネガティブ) を返す。期間内にネガティブがない場合は 100、どちらもない場合は 50 とする
+func MFI(high []float64, low []float64, closing []float64, volume []float64, period int) []float64 {
+	typicalPrice := TypicalPrice(high, low, closing)
+	mfi := make([]float64, len(closing))
+	for i := range closing {
+		// 前日の典型価格と比較するため period + 1 本が必要
+		if i+period >= len(closing) {
+			mfi[i] = math.NaN()
+			continue
+		}
+		positive, negative := 0.0, 0.0
+		for j := i; j < i+period; j++ {
+			flow := typicalPrice[j] * volume[j]
+			if typicalPrice[j] > typicalPrice[j+1] {
+				positive += flow
+			} else if typicalPrice[j] < typicalPrice[j+1] {
+				negative += flow
+			}
+		}
+		switch {
+		case positive == 0 && negative == 0:
+			mfi[i] = 50
+		case negative == 0:
+			mfi[i] = 100
+		default:
+			mfi[i] = 100 - (100 / (1 + positive/negative))
+		}
+	}
+	return mfi
+}
+
+// CMF (public)期間 period のチャイキン・マネーフロー(Chaikin Money Flow)を計算する
+// 期間内のマネーフロー出来高の合計 / 出来高の合計 を返す。期間内の出来高が 0 の場合は 0 とする
+func CMF(high []float64, low []float64, closing []float64, volume []float64, period int) []float64 {
+	cmf := make([]float64, len(closing))
+	for i := range closing {
+		if i+period > len(closing) {
+			cmf[i] = math.NaN()
+			continue
+		}
+		sumFlowVolume, sumVolume := 0.0, 0.0
+		for j := i; j < i+period; j++ {
+			sumFlowVolume += moneyFlowMultiplier(high[j], low[j], closing[j]) * volume[j]
+			sumVolume += volume[j]
+		}
+		if sumVolume == 0 {
+			cmf[i] = 0
+			continue
+		}
+		cmf[i] = sumFlowVolume / sumVolume
+	}
+	return cmf
+}
+
+// TypicalPrice (public)典型価格 (高値+安値+終値)/3 を計算する
+func TypicalPrice(high []float64, low []float64, closing []float64) []float64 {
+	typicalPrice := make([]float64, len(closing))
+	for i := range closing {
+		typicalPrice[i] = typicalPriceOf(high[i], low[i], closing[i])
+	}
+	return typicalPrice
+}
+
+//---- private function ----
+
+// 1本の足の典型価格 (高値+安値+終値)/3
+func typicalPriceOf(high float64, low float64, closing float64) float64 {
+	return (high + low + closing) / 3
+}
+
+// マネーフロー・マルチプライヤ((終値-安値) - (高値-終値)) / (高値-安値) を計算する。高値と安値が等しい場合は 0
+func moneyFlowMultiplier(high float64, low float64, closing float64) float64 {
+	if high == low {
+		return 0
+	}
+	return ((closing - low) - (high - closing)) / (high - low)
+}
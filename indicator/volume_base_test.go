@@ -0,0 +1,90 @@
+package indicator
+
+import (
+	"math"
+	"testing"
+)
+
+// 日付降順の4日分の四本値と出来高
+// 典型価格は 古い順に 10, 11, 11, 10
+var (
+	volumeHigh    = []float64{11, 12, 12, 11}
+	volumeLow     = []float64{9, 10, 10, 9}
+	volumeClosing = []float64{10, 11, 11, 10}
+	volumeVolume  = []float64{400, 300, 200, 100}
+)
+
+func TestOBV(t *testing.T) {
+	// 古い順に 0、上昇 +200、変化なし、下落 -400
+	want := []float64{-200, 200, 200, 0}
+	assertSeries(t, "OBV", OBV(volumeClosing, volumeVolume), want, 1e-9)
+}
+
+func TestADLine(t *testing.T) {
+	high := []float64{12, 10, 10}
+	low := []float64{8, 10, 8}
+	closing := []float64{11, 10, 10}
+	volume := []float64{100, 50, 10}
+	// マルチプライヤは古い順に 1、0(高値 = 安値)、0.5
+	want := []float64{60, 10, 10}
+	assertSeries(t, "ADLine", ADLine(high, low, closing, volume), want, 1e-9)
+}
+
+func TestVWAP(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name   string
+		volume []float64
+		want   []float64
+	}{
+		{"weighted", volumeVolume, []float64{(10*400 + 11*300) / 700.0, (11*300 + 11*200) / 500.0, (11*200 + 10*100) / 300.0, nan}},
+		{"zero volume", []float64{0, 0, 0, 0}, []float64{10.5, 11, 10.5, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, "VWAP", VWAP(volumeHigh, volumeLow, volumeClosing, tt.volume, 2), tt.want, 1e-9)
+		})
+	}
+}
+
+func TestMFI(t *testing.T) {
+	nan := math.NaN()
+	flat := []float64{10, 10, 10, 10}
+	tests := []struct {
+		name    string
+		high    []float64
+		low     []float64
+		closing []float64
+		want    []float64
+	}{
+		// 期間2: 最新はネガティブ 10*400 のみ(11*300 は変化なし) → 0、2日前はポジティブ 11*200 のみ → 100
+		{"mixed", volumeHigh, volumeLow, volumeClosing, []float64{0, 100, nan, nan}},
+		{"flat", flat, flat, flat, []float64{50, 50, nan, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, "MFI", MFI(tt.high, tt.low, tt.closing, volumeVolume, 2), tt.want, 1e-9)
+		})
+	}
+}
+
+func TestCMF(t *testing.T) {
+	nan := math.NaN()
+	// マルチプライヤは古い順に 1、0(高値 = 安値)、0.5
+	high := []float64{12, 10, 10}
+	low := []float64{8, 10, 8}
+	closing := []float64{11, 10, 10}
+	tests := []struct {
+		name   string
+		volume []float64
+		want   []float64
+	}{
+		{"weighted", []float64{100, 50, 10}, []float64{50.0 / 150, 10.0 / 60, nan}},
+		{"zero volume", []float64{0, 0, 0}, []float64{0, 0, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertSeries(t, "CMF", CMF(high, low, closing, tt.volume, 2), tt.want, 1e-9)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// optimize 数値最適化パッケージ
+package optimize // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"math"
+)
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// NelderMead (public)Nelder-Mead 法で f を最小化し、最小の点と値を返す。maxEval は f の評価回数の上限
+// 初期の単体は start の各座標に 0.1 を加えた点とし、収束後に最良の点から1度だけ探索をやり直して早すぎる収束を避ける
+// 制約のある問題は変数変換で制約のない問題にするか、実行不可能な点で +Inf を返す
+func NelderMead(f func([]float64) float64, start []float64, maxEval int) ([]float64, float64) {
+
+	dim := len(start)
+	if dim == 0 {
+		return start, f(start)
+	}
+	best, bestValue := append([]float64(nil), start...), f(start)
+	for restart := 0; restart < 2; restart++ {
+		simplex := make([][]float64, dim+1)
+		values := make([]float64, dim+1)
+		for i := range simplex {
+			simplex[i] = append([]float64(nil), best...)
+			if i > 0 {
+				simplex[i][i-1] += 0.1
+			}
+			values[i] = f(simplex[i])
+		}
+		evaluations := dim + 1
+		for evaluations < maxEval {
+			// 値の昇順に並べ替え
+			for i := 1; i < len(simplex); i++ {
+				for j := i; j > 0 && values[j] < values[j-1]; j-- {
+					simplex[j], simplex[j-1] = simplex[j-1], simplex[j]
+					values[j], values[j-1] = values[j-1], values[j]
+				}
+			}
+			size := 0.0
+			for _, point := range simplex[1:] {
+				for j := range point {
+					size = max(size, math.Abs(point[j]-simplex[0][j]))
+				}
+			}
+			if size < 1e-8 && math.Abs(values[dim]-values[0]) < 1e-10*(1+math.Abs(values[0])) {
+				break
+			}
+
+			centroid := make([]float64, dim)
+			for _, point := range simplex[:dim] {
+				for j := range point {
+					centroid[j] += point[j] / float64(dim)
+				}
+			}
+			along := func(t float64) []float64 {
+				point := make([]float64, dim)
+				for j := range point {
+					point[j] = centroid[j] + t*(simplex[dim][j]-centroid[j])
+				}
+				return point
+			}
+			reflected := along(-1)
+			reflectedValue := f(reflected)
+			evaluations++
+			switch {
+			case reflectedValue < values[0]:
+				expanded := along(-2)
+				expandedValue := f(expanded)
+				evaluations++
+				if expandedValue < reflectedValue {
+					simplex[dim], values[dim] = expanded, expandedValue
+				} else {
+					simplex[dim], values[dim] = reflected, reflectedValue
+				}
+			case reflectedValue < values[dim-1]:
+				simplex[dim], values[dim] = reflected, reflectedValue
+			default:
+				t := 0.5
+				if reflectedValue < values[dim] {
+					t = -0.5
+				}
+				contracted := along(t)
+				contractedValue := f(contracted)
+				evaluations++
+				if contractedValue < min(reflectedValue, values[dim]) {
+					simplex[dim], values[dim] = contracted, contractedValue
+				} else {
+					for i := 1; i < len(simplex); i++ {
+						for j := range simplex[i] {
+							simplex[i][j] = simplex[0][j] + 0.5*(simplex[i][j]-simplex[0][j])
+						}
+						values[i] = f(simplex[i])
+					}
+					evaluations += dim
+				}
+			}
+		}
+		for i := range simplex {
+			if values[i] < bestValue {
+				best, bestValue = simplex[i], values[i]
+			}
+		}
+	}
+	return best, bestValue
+}
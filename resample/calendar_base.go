@@ -0,0 +1,112 @@
+// resample 足の変換(日足 → 週足・月足)パッケージ
+package resample // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"math"
+	"time"
+)
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// IsTradingDay (public)東京証券取引所の営業日かを判定する
+// 土日、国民の祝日・振替休日・国民の休日、年末年始(12/31〜1/3)を休業日とする
+// 法改正による一度限りの祝日移動(2020/2021年の五輪特例など)は考慮しない
+func IsTradingDay(date time.Time) bool {
+	if !IsWeekday(date) {
+		return false
+	}
+	if (date.Month() == time.December && date.Day() == 31) || (date.Month() == time.January && date.Day() <= 3) {
+		return false
+	}
+	return !IsHoliday(date)
+}
+
+// IsWeekday (public)土日以外かを判定する(祝日も取引のある為替の営業日判定に用いる)
+func IsWeekday(date time.Time) bool {
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+// IsHoliday (public)国民の祝日、振替休日、国民の休日かを判定する
+func IsHoliday(date time.Time) bool {
+	if isNationalHoliday(date) {
+		return true
+	}
+
+	// 振替休日: 祝日が日曜日の場合、その後の最も近い平日(祝日でない日)
+	if date.Weekday() != time.Sunday {
+		for d := date.AddDate(0, 0, -1); isNationalHoliday(d); d = d.AddDate(0, 0, -1) {
+			if d.Weekday() == time.Sunday {
+				return true
+			}
+		}
+	}
+
+	// 国民の休日: 前日と翌日が祝日である平日
+	if date.Weekday() != time.Sunday && isNationalHoliday(date.AddDate(0, 0, -1)) && isNationalHoliday(date.AddDate(0, 0, 1)) {
+		return true
+	}
+	return false
+}
+
+//---- private function ----
+
+// 国民の祝日に関する法律で定められた祝日かを判定する(2007年以降の規定)
+func isNationalHoliday(date time.Time) bool {
+
+	year, month, day := date.Date()
+	switch month {
+	case time.January:
+		// 元日、成人の日(第2月曜日)
+		return day == 1 || isNthMonday(date, 2)
+	case time.February:
+		// 建国記念の日、天皇誕生日(2020年以降)
+		return day == 11 || (year >= 2020 && day == 23)
+	case time.March:
+		// 春分の日
+		return day == vernalEquinoxDay(year)
+	case time.April:
+		// 昭和の日
+		return day == 29
+	case time.May:
+		// 憲法記念日、みどりの日、こどもの日
+		return day == 3 || day == 4 || day == 5
+	case time.July:
+		// 海の日(第3月曜日)
+		return isNthMonday(date, 3)
+	case time.August:
+		// 山の日(2016年以降)
+		return year >= 2016 && day == 11
+	case time.September:
+		// 敬老の日(第3月曜日)、秋分の日
+		return isNthMonday(date, 3) || day == autumnalEquinoxDay(year)
+	case time.October:
+		// スポーツの日(体育の日、第2月曜日)
+		return isNthMonday(date, 2)
+	case time.November:
+		// 文化の日、勤労感謝の日
+		return day == 3 || day == 23
+	case time.December:
+		// 天皇誕生日(2018年まで)
+		return year <= 2018 && day == 23
+	}
+	return false
+}
+
+// 月の第n月曜日かを判定する
+func isNthMonday(date time.Time, n int) bool {
+	return date.Weekday() == time.Monday && (date.Day()-1)/7 == n-1
+}
+
+// 春分日(1980〜2099年の近似式)
+func vernalEquinoxDay(year int) int {
+	return int(math.Floor(20.8431+0.242194*float64(year-1980))) - (year-1980)/4
+}
+
+// 秋分日(1980〜2099年の近似式)
+func autumnalEquinoxDay(year int) int {
+	return int(math.Floor(23.2488+0.242194*float64(year-1980))) - (year-1980)/4
+}
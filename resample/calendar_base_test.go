@@ -0,0 +1,85 @@
+package resample
+
+import (
+	"testing"
+	"time"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+func TestIsHoliday(t *testing.T) {
+	tests := []struct {
+		name string
+		date time.Time
+		want bool
+	}{
+		{"元日", day(2026, 1, 1), true},
+		{"成人の日(第2月曜日)", day(2026, 1, 12), true},
+		{"第1月曜日は平日", day(2026, 1, 5), false},
+		{"建国記念の日", day(2026, 2, 11), true},
+		{"天皇誕生日(2020年以降)", day(2026, 2, 23), true},
+		{"12/23 は2019年以降は平日", day(2019, 12, 23), false},
+		{"12/23 の天皇誕生日(2018年まで)", day(2016, 12, 23), true},
+		{"春分の日 2024", day(2024, 3, 20), true},
+		{"春分の日 2025", day(2025, 3, 20), true},
+		{"春分の日 2026", day(2026, 3, 20), true},
+		{"春分の日の前日", day(2026, 3, 19), false},
+		{"秋分の日 2025", day(2025, 9, 23), true},
+		{"秋分の日 2026", day(2026, 9, 23), true},
+		{"振替休日(建国記念の日が日曜日)", day(2024, 2, 12), true},
+		{"振替休日(天皇誕生日が日曜日)", day(2025, 2, 24), true},
+		{"振替休日(こどもの日が日曜日)", day(2024, 5, 6), true},
+		{"振替休日(みどりの日が日曜日、こどもの日の翌日)", day(2025, 5, 6), true},
+		{"振替休日(憲法記念日が日曜日、連休明け)", day(2026, 5, 6), true},
+		{"振替休日(秋分の日が日曜日)", day(2024, 9, 23), true},
+		{"振替休日(天皇誕生日が日曜日、2018年)", day(2018, 12, 24), true},
+		{"連休明けの平日", day(2026, 5, 7), false},
+		{"国民の休日(敬老の日と秋分の日の間)", day(2026, 9, 22), true},
+		{"国民の休日 2015", day(2015, 9, 22), true},
+		{"前日が祝日でない(敬老の日が9/15)", day(2025, 9, 22), false},
+		{"敬老の日(第3月曜日)", day(2026, 9, 21), true},
+		{"海の日(第3月曜日)", day(2026, 7, 20), true},
+		{"山の日", day(2026, 8, 11), true},
+		{"山の日(2016年より前)", day(2015, 8, 11), false},
+		{"スポーツの日(第2月曜日)", day(2026, 10, 12), true},
+		{"勤労感謝の日", day(2026, 11, 23), true},
+		{"年末は祝日ではない", day(2025, 12, 31), false},
+	}
+	for _, tt := range tests {
+		if got := IsHoliday(tt.date); got != tt.want {
+			t.Errorf("IsHoliday(%s %s) = %v, want %v", tt.date.Format("2006/01/02"), tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsTradingDay(t *testing.T) {
+	tests := []struct {
+		name    string
+		date    time.Time
+		trading bool
+		weekday bool
+	}{
+		{"平日", day(2026, 9, 24), true, true},
+		{"土曜日", day(2026, 9, 19), false, false},
+		{"日曜日", day(2026, 9, 20), false, false},
+		{"国民の休日", day(2026, 9, 22), false, true},
+		{"振替休日", day(2026, 5, 6), false, true},
+		{"秋分の日", day(2026, 9, 23), false, true},
+		{"大納会の翌日 12/31", day(2025, 12, 31), false, true},
+		{"元日", day(2026, 1, 1), false, true},
+		{"1/2", day(2026, 1, 2), false, true},
+		{"1/3 (平日)", day(2025, 1, 3), false, true},
+		{"大発会", day(2026, 1, 5), true, true},
+		{"大納会 12/30", day(2025, 12, 30), true, true},
+	}
+	for _, tt := range tests {
+		if got := IsTradingDay(tt.date); got != tt.trading {
+			t.Errorf("IsTradingDay(%s %s) = %v, want %v", tt.date.Format("2006/01/02"), tt.name, got, tt.trading)
+		}
+		if got := IsWeekday(tt.date); got != tt.weekday {
+			t.Errorf("IsWeekday(%s %s) = %v, want %v", tt.date.Format("2006/01/02"), tt.name, got, tt.weekday)
+		}
+	}
+}
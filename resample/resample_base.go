@@ -0,0 +1,105 @@
+// resample 足の変換(日足 → 週足・月足)パッケージ
+package resample // パッケージ名はディレクトリ名と同じにする
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ---- const
+
+// Period 変換後の足の期間
+type Period string
+
+const (
+	Weekly  Period = "weekly"  // 週足(月曜日〜金曜日)
+	Monthly Period = "monthly" // 月足
+)
+
+// ---- struct
+
+// Bar 1本の足の四本値と出来高
+type Bar struct {
+	Date    time.Time // 足の日付(期間内の最初の営業日)
+	Opening float64   // 始値(期間内の最初の営業日の始値)
+	High    float64   // 高値(期間内の最高値)
+	Low     float64   // 安値(期間内の最安値)
+	Closing float64   // 終値(期間内の最後の営業日の終値)
+	Volume  float64   // 出来高(期間内の合計)
+}
+
+// ---- Global Variable
+
+// ---- Package Global Variable
+
+//---- public function ----
+
+// ParsePeriod (public)文字列を Period に変換する
+func ParsePeriod(str string) (Period, error) {
+	switch Period(str) {
+	case Weekly, Monthly:
+		return Period(str), nil
+	}
+	return "", fmt.Errorf("unknown resample period %q (weekly / monthly)", str)
+}
+
+// Resample (public)日足を period の足にまとめ、日付降順で返す
+// isTradingDay が false の日付のデータは休業日のものとして除外する
+// (株式は IsTradingDay、祝日も取引のある為替は IsWeekday を指定する)
+func Resample(daily []Bar, period Period, isTradingDay func(time.Time) bool) []Bar {
+
+	// 日付昇順に並べてから期間毎にまとめる
+	sorted := make([]Bar, 0, len(daily))
+	for _, d := range daily {
+		if !isTradingDay(d.Date) {
+			continue
+		}
+		sorted = append(sorted, d)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	var retValue []Bar
+	var current Bar
+	var currentKey time.Time
+	for i, d := range sorted {
+		key := periodStart(d.Date, period)
+		if i == 0 || !key.Equal(currentKey) {
+			if i != 0 {
+				retValue = append(retValue, current)
+			}
+			current = d
+			currentKey = key
+			continue
+		}
+		current.High = max(current.High, d.High)
+		current.Low = min(current.Low, d.Low)
+		current.Closing = d.Closing
+		current.Volume += d.Volume
+	}
+	if len(sorted) > 0 {
+		retValue = append(retValue, current)
+	}
+
+	// 日付降順
+	for i, j := 0, len(retValue)-1; i < j; i, j = i+1, j-1 {
+		retValue[i], retValue[j] = retValue[j], retValue[i]
+	}
+	return retValue
+}
+
+//---- private function ----
+
+// 日付が属する期間の開始日(週足は月曜日、月足は1日)を返す
+func periodStart(date time.Time, period Period) time.Time {
+	year, month, day := date.Date()
+	switch period {
+	case Monthly:
+		return time.Date(year, month, 1, 0, 0, 0, 0, date.Location())
+	default:
+		offset := (int(date.Weekday()) + 6) % 7 // 月曜日からの日数
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, date.Location())
+	}
+}
@@ -0,0 +1,91 @@
+package resample
+
+import (
+	"testing"
+	"time"
+)
+
+// 日付、始値、高値、安値、終値、出来高から足を作る
+func bar(date time.Time, opening, high, low, closing, volume float64) Bar {
+	return Bar{Date: date, Opening: opening, High: high, Low: low, Closing: closing, Volume: volume}
+}
+
+func checkBars(t *testing.T, name string, got []Bar, want []Bar) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d bars, want %d: %+v", name, len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Date.Equal(want[i].Date) == false || got[i].Opening != want[i].Opening || got[i].High != want[i].High ||
+			got[i].Low != want[i].Low || got[i].Closing != want[i].Closing || got[i].Volume != want[i].Volume {
+			t.Errorf("%s: bar %d = %+v, want %+v", name, i, got[i], want[i])
+		}
+	}
+}
+
+// 2026/09/14(月) 〜 2026/10/02(金) の日足(日付降順)。9/21〜9/23 は祝日・国民の休日、9/19・9/20 は土日
+func dailyBars() []Bar {
+	return []Bar{
+		bar(day(2026, 10, 2), 112, 115, 110, 114, 500),
+		bar(day(2026, 10, 1), 108, 113, 107, 112, 400),
+		bar(day(2026, 9, 30), 109, 110, 104, 108, 300),
+		bar(day(2026, 9, 29), 106, 111, 105, 109, 200),
+		bar(day(2026, 9, 28), 105, 107, 103, 106, 100),
+		bar(day(2026, 9, 25), 102, 106, 101, 105, 90),
+		bar(day(2026, 9, 24), 100, 103, 99, 102, 80),
+		bar(day(2026, 9, 23), 999, 999, 1, 999, 9999), // 秋分の日(株式は除外)
+		bar(day(2026, 9, 22), 101, 104, 98, 100, 70),  // 国民の休日(株式は除外)
+		bar(day(2026, 9, 21), 103, 105, 100, 101, 60), // 敬老の日(株式は除外)
+		bar(day(2026, 9, 20), 999, 999, 1, 999, 9999), // 日曜日
+		bar(day(2026, 9, 18), 97, 104, 96, 103, 50),
+		bar(day(2026, 9, 17), 99, 100, 95, 97, 40),
+		bar(day(2026, 9, 16), 96, 101, 94, 99, 30),
+		bar(day(2026, 9, 15), 98, 99, 93, 96, 20),
+		bar(day(2026, 9, 14), 95, 98, 92, 98, 10),
+	}
+}
+
+func TestResampleWeekly(t *testing.T) {
+	// 週足は月曜日〜金曜日。足の日付は週の最初の営業日で、月を跨ぐ週も1本にまとめる
+	checkBars(t, "stock", Resample(dailyBars(), Weekly, IsTradingDay), []Bar{
+		bar(day(2026, 9, 28), 105, 115, 103, 114, 1500),
+		bar(day(2026, 9, 24), 100, 106, 99, 105, 170),
+		bar(day(2026, 9, 14), 95, 104, 92, 103, 150),
+	})
+
+	// 為替は祝日も営業日とし、週の最初の日付は祝日の月曜日
+	checkBars(t, "forex", Resample(dailyBars(), Weekly, IsWeekday), []Bar{
+		bar(day(2026, 9, 28), 105, 115, 103, 114, 1500),
+		bar(day(2026, 9, 21), 103, 999, 1, 105, 10299),
+		bar(day(2026, 9, 14), 95, 104, 92, 103, 150),
+	})
+}
+
+func TestResampleMonthly(t *testing.T) {
+	// 月足は月毎。入力の日付の順序に関わらず、始値は最初、終値は最後の営業日の値
+	daily := dailyBars()
+	daily[0], daily[len(daily)-1] = daily[len(daily)-1], daily[0]
+	checkBars(t, "monthly", Resample(daily, Monthly, IsTradingDay), []Bar{
+		bar(day(2026, 10, 1), 108, 115, 107, 114, 900),
+		bar(day(2026, 9, 14), 95, 111, 92, 108, 920),
+	})
+
+	if got := Resample(nil, Monthly, IsTradingDay); len(got) != 0 {
+		t.Errorf("Resample(nil) = %+v", got)
+	}
+	holidays := []Bar{bar(day(2026, 9, 21), 1, 1, 1, 1, 1), bar(day(2026, 9, 20), 1, 1, 1, 1, 1)}
+	if got := Resample(holidays, Weekly, IsTradingDay); len(got) != 0 {
+		t.Errorf("Resample(holidays only) = %+v", got)
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	for _, str := range []string{"weekly", "monthly"} {
+		if got, err := ParsePeriod(str); err != nil || string(got) != str {
+			t.Errorf("ParsePeriod(%q) = %v, %v", str, got, err)
+		}
+	}
+	if _, err := ParsePeriod("daily"); err == nil {
+		t.Error("ParsePeriod(daily) expected error")
+	}
+}
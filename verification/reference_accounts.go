@@ -0,0 +1,620 @@
+// verification ModelData のテクニカル指標の検証
+package main
+
+import (
+	"math"
+	"slices"
+
+	"sv_stockcheck/candle"
+	"sv_stockcheck/indicator"
+)
+
+// テクニカル指標の参照実装
+// indicator / candle パッケージの不具合を検出できるよう、indicator / candle パッケージの計算を用いずに定義どおりに計算する
+// 計算は日付昇順に並べ替えて古い方から順に行い、結果は日付降順(index 0 が最新)で返す
+//   SMA: 直近 n 本の単純平均(n 本に満たない、または NaN を含む場合は NaN)
+//   EMA / Wilder: 最初の有効な値から n 本の単純平均を初期値とし、以降は 前回値 + α (当日値 - 前回値) (EMA は α = 2 / (n + 1)、Wilder は α = 1 / n)
+//   標準偏差: 直近 n 本の平均からの偏差の二乗の平均の平方根(母集団標準偏差)
+//   RSI: 前日比の上昇幅・下落幅を平滑化し 100 - 100 / (1 + 上昇幅 / 下落幅) (下落幅が 0 は 100)
+//   ATR: True Range (最古の日付は 高値 - 安値)を平滑化
+//   MACD: 短期と長期の平均の差、シグナルは MACD の SMA / EMA、ヒストグラムは MACD - シグナル
+//   ストキャスティクス: 直近 n 本の最高値 H・最安値 L に対し %K = (終値 - L) / (H - L) * 100、%R = (H - 終値) / (H - L) * -100 (H = L は 50、-50)
+//   CCI: 典型価格の直近 n 本の平均 M・平均偏差 D に対し (典型価格 - M) / (0.015 D) (D = 0 は 0)
+//   DMI: ±DM(前日からの高値の上昇幅・安値の下落幅の大きい方のみ)と True Range を Wilder で平滑化し ±DI = ±DM / TR * 100、
+//        ADX は DX = |+DI - -DI| / (+DI + -DI) * 100 の Wilder の平滑化(最古の日付は前日がないため除く)
+//   OBV: 終値が前日より上昇した日は出来高を加算、下落した日は減算した累計(最古の日付は 0)
+//   A/D ライン: ((終値 - 安値) - (高値 - 終値)) / (高値 - 安値) * 出来高 の累計(高値 = 安値 の日は 0)
+//   VWAP: 直近 n 本の 典型価格 * 出来高 の合計 / 出来高の合計(出来高の合計が 0 は典型価格の平均)
+//   MFI: 直近 n 本の 典型価格 * 出来高 を典型価格が前日より上昇した日・下落した日に分けて合計し 100 - 100 / (1 + 上昇 / 下落)
+//        (下落がない場合は 100、どちらもない場合は 50)
+//   CMF: 直近 n 本の A/D ラインの増分の合計 / 出来高の合計(出来高の合計が 0 は 0)
+//   パラボリック SAR: Wilder の定義(2本目の終値が1本目以上なら上昇から開始し、SAR = 前日 SAR + AF (極値 - 前日 SAR)、
+//        前日・前々日の安値(下降は高値)を超えない、SAR を割り込んだ(上抜けた)日に極値を SAR として転換)
+//   一目均衡表: 転換線・基準線・先行スパンB は期間の最高値と最安値の中間、先行スパンA は転換線と基準線の平均、
+//        当日の雲は基準線の期間前に計算した先行スパン、遅行スパンは基準線の期間前の終値との差
+//   ローソク足パターン: candle パッケージのパターンの定義(判定の比率の定数のみ共有する)
+
+//---- private function ----
+
+// 日付降順の系列を日付昇順にする(日付昇順の系列は日付降順にする)
+func reversed(values []float64) []float64 {
+	result := slices.Clone(values)
+	slices.Reverse(result)
+	return result
+}
+
+// 直近 n 本の単純平均
+func refSMA(values []float64, n int) []float64 {
+
+	ascending := reversed(values)
+	result := make([]float64, len(ascending))
+	for t := range ascending {
+		result[t] = math.NaN()
+		if n <= 0 || t+1 < n {
+			continue
+		}
+		sum := 0.0
+		for k := t - n + 1; k <= t; k++ {
+			sum += ascending[k]
+		}
+		result[t] = sum / float64(n)
+	}
+	return reversed(result)
+}
+
+// 係数 alpha の指数平滑(最初の有効な値から n 本の単純平均を初期値とする)
+func refExponential(values []float64, n int, alpha float64) []float64 {
+
+	ascending := reversed(values)
+	result := make([]float64, len(ascending))
+	start := slices.IndexFunc(ascending, func(v float64) bool { return !math.IsNaN(v) })
+	previous := math.NaN()
+	for t := range ascending {
+		switch {
+		case n <= 0 || start < 0 || t < start+n-1:
+			result[t] = math.NaN()
+		case t == start+n-1:
+			sum := 0.0
+			for _, v := range ascending[start : t+1] {
+				sum += v
+			}
+			previous = sum / float64(n)
+			result[t] = previous
+		default:
+			previous += alpha * (ascending[t] - previous)
+			result[t] = previous
+		}
+	}
+	return reversed(result)
+}
+
+// 平滑化方式 method での n 本の平均
+func refSmooth(values []float64, n int, method indicator.Smoothing) []float64 {
+	switch method {
+	case indicator.SmoothingEMA:
+		return refExponential(values, n, 2/float64(n+1))
+	case indicator.SmoothingWilder:
+		return refExponential(values, n, 1/float64(n))
+	}
+	return refSMA(values, n)
+}
+
+// n 期間の RSI
+func refRSI(closing []float64, n int, method indicator.Smoothing) []float64 {
+
+	ascending := reversed(closing)
+	gain := make([]float64, len(ascending))
+	loss := make([]float64, len(ascending))
+	for t := range ascending {
+		if t == 0 {
+			gain[t], loss[t] = math.NaN(), math.NaN()
+			continue
+		}
+		change := ascending[t] - ascending[t-1]
+		gain[t], loss[t] = math.Max(change, 0), math.Max(-change, 0)
+	}
+	avgGain := reversed(refSmooth(reversed(gain), n, method))
+	avgLoss := reversed(refSmooth(reversed(loss), n, method))
+	result := make([]float64, len(ascending))
+	for t := range ascending {
+		switch {
+		case math.IsNaN(avgGain[t]) || math.IsNaN(avgLoss[t]):
+			result[t] = math.NaN()
+		case avgLoss[t] == 0:
+			result[t] = 100
+		default:
+			result[t] = 100 - 100/(1+avgGain[t]/avgLoss[t])
+		}
+	}
+	return reversed(result)
+}
+
+// n 期間の ATR
+func refATR(high []float64, low []float64, closing []float64, n int, method indicator.Smoothing) []float64 {
+
+	h, l, c := reversed(high), reversed(low), reversed(closing)
+	trueRange := make([]float64, len(c))
+	for t := range c {
+		trueRange[t] = h[t] - l[t]
+		if t > 0 {
+			trueRange[t] = math.Max(trueRange[t], math.Max(math.Abs(h[t]-c[t-1]), math.Abs(l[t]-c[t-1])))
+		}
+	}
+	return refSmooth(reversed(trueRange), n, method)
+}
+
+// MACD(fast、slow の平均の差)とシグナル(signal 本の SMA / EMA)、ヒストグラムをカラム名の接尾辞毎に返す
+func refMACD(closing []float64, def indicator.MACDDefinition) map[string][]float64 {
+
+	fast, slow := refSmooth(closing, def.Fast, def.Base), refSmooth(closing, def.Slow, def.Base)
+	macd := make([]float64, len(closing))
+	for i := range macd {
+		macd[i] = fast[i] - slow[i]
+	}
+	signalSMA, signalEMA := refSMA(macd, def.Signal), refSmooth(macd, def.Signal, indicator.SmoothingEMA)
+	histoSMA := make([]float64, len(macd))
+	histoEMA := make([]float64, len(macd))
+	for i := range macd {
+		histoSMA[i] = macd[i] - signalSMA[i]
+		histoEMA[i] = macd[i] - signalEMA[i]
+	}
+	return map[string][]float64{
+		"MACD":          macd,
+		"MACDSignalSMA": signalSMA,
+		"MACDHistoSMA":  histoSMA,
+		"MACDSignalEMA": signalEMA,
+		"MACDHistoEMA":  histoEMA,
+	}
+}
+
+// 直近 n 本の母集団標準偏差
+func refStdDev(values []float64, n int) []float64 {
+
+	ascending := reversed(values)
+	result := make([]float64, len(ascending))
+	for t := range ascending {
+		result[t] = math.NaN()
+		if n <= 0 || t+1 < n {
+			continue
+		}
+		window := ascending[t-n+1 : t+1]
+		mean := 0.0
+		for _, v := range window {
+			mean += v
+		}
+		mean /= float64(n)
+		variance := 0.0
+		for _, v := range window {
+			variance += (v - mean) * (v - mean)
+		}
+		result[t] = math.Sqrt(variance / float64(n))
+	}
+	return reversed(result)
+}
+
+// 平均 average に対する乖離率 (値 - 平均) / 平均 * 100 (平均が NaN は NaN、0 は 0。日付の並びは引数と同じ)
+func refMADRate(values []float64, average []float64) []float64 {
+	result := make([]float64, len(values))
+	for i := range values {
+		switch {
+		case math.IsNaN(average[i]):
+			result[i] = math.NaN()
+		case average[i] != 0:
+			result[i] = (values[i] - average[i]) / average[i] * 100
+		}
+	}
+	return result
+}
+
+// 直近 n 本の最高値と最安値(n 本に満たない場合は NaN)
+func refHighLow(high []float64, low []float64, n int) ([]float64, []float64) {
+
+	h, l := reversed(high), reversed(low)
+	highest := make([]float64, len(h))
+	lowest := make([]float64, len(h))
+	for t := range h {
+		highest[t], lowest[t] = math.NaN(), math.NaN()
+		if n <= 0 || t+1 < n {
+			continue
+		}
+		highest[t], lowest[t] = h[t], l[t]
+		for k := t - n + 1; k < t; k++ {
+			highest[t] = math.Max(highest[t], h[k])
+			lowest[t] = math.Min(lowest[t], l[k])
+		}
+	}
+	return reversed(highest), reversed(lowest)
+}
+
+// n 期間のストキャスティクスの %K とウィリアムズ%R
+func refStochastics(high []float64, low []float64, closing []float64, n int) ([]float64, []float64) {
+
+	highest, lowest := refHighLow(high, low, n)
+	fastK := make([]float64, len(closing))
+	williamsR := make([]float64, len(closing))
+	for i := range closing {
+		switch {
+		case math.IsNaN(highest[i]):
+			fastK[i], williamsR[i] = math.NaN(), math.NaN()
+		case highest[i] == lowest[i]:
+			fastK[i], williamsR[i] = 50, -50
+		default:
+			fastK[i] = (closing[i] - lowest[i]) / (highest[i] - lowest[i]) * 100
+			williamsR[i] = (highest[i] - closing[i]) / (highest[i] - lowest[i]) * -100
+		}
+	}
+	return fastK, williamsR
+}
+
+// 典型価格 (高値 + 安値 + 終値) / 3 (日付の並びは引数と同じ)
+func refTypicalPrice(high []float64, low []float64, closing []float64) []float64 {
+	result := make([]float64, len(closing))
+	for i := range closing {
+		result[i] = (high[i] + low[i] + closing[i]) / 3
+	}
+	return result
+}
+
+// n 期間の CCI
+func refCCI(high []float64, low []float64, closing []float64, n int) []float64 {
+
+	typical := reversed(refTypicalPrice(high, low, closing))
+	result := make([]float64, len(typical))
+	for t := range typical {
+		result[t] = math.NaN()
+		if n <= 0 || t+1 < n {
+			continue
+		}
+		window := typical[t-n+1 : t+1]
+		mean := 0.0
+		for _, v := range window {
+			mean += v
+		}
+		mean /= float64(n)
+		deviation := 0.0
+		for _, v := range window {
+			deviation += math.Abs(v - mean)
+		}
+		deviation /= float64(n)
+		result[t] = 0
+		if deviation != 0 {
+			result[t] = (typical[t] - mean) / (0.015 * deviation)
+		}
+	}
+	return reversed(result)
+}
+
+// n 期間の +DI、-DI、ADX
+func refDMI(high []float64, low []float64, closing []float64, n int) ([]float64, []float64, []float64) {
+
+	h, l, c := reversed(high), reversed(low), reversed(closing)
+	plusDM := make([]float64, len(c))
+	minusDM := make([]float64, len(c))
+	trueRange := make([]float64, len(c))
+	for t := range c {
+		if t == 0 {
+			plusDM[t], minusDM[t], trueRange[t] = math.NaN(), math.NaN(), math.NaN()
+			continue
+		}
+		up, down := h[t]-h[t-1], l[t-1]-l[t]
+		if up > 0 && up > down {
+			plusDM[t] = up
+		}
+		if down > 0 && down > up {
+			minusDM[t] = down
+		}
+		trueRange[t] = math.Max(h[t]-l[t], math.Max(math.Abs(h[t]-c[t-1]), math.Abs(l[t]-c[t-1])))
+	}
+	wilder := func(values []float64) []float64 {
+		return reversed(refSmooth(reversed(values), n, indicator.SmoothingWilder))
+	}
+	smoothedPlus, smoothedMinus, smoothedRange := wilder(plusDM), wilder(minusDM), wilder(trueRange)
+	plusDI := make([]float64, len(c))
+	minusDI := make([]float64, len(c))
+	dx := make([]float64, len(c))
+	for t := range c {
+		switch {
+		case math.IsNaN(smoothedRange[t]):
+			plusDI[t], minusDI[t], dx[t] = math.NaN(), math.NaN(), math.NaN()
+		case smoothedRange[t] == 0:
+			plusDI[t], minusDI[t], dx[t] = 0, 0, 0
+		default:
+			plusDI[t] = 100 * smoothedPlus[t] / smoothedRange[t]
+			minusDI[t] = 100 * smoothedMinus[t] / smoothedRange[t]
+			if plusDI[t]+minusDI[t] != 0 {
+				dx[t] = 100 * math.Abs(plusDI[t]-minusDI[t]) / (plusDI[t] + minusDI[t])
+			}
+		}
+	}
+	return reversed(plusDI), reversed(minusDI), reversed(wilder(dx))
+}
+
+// OBV
+func refOBV(closing []float64, volume []float64) []float64 {
+
+	c, v := reversed(closing), reversed(volume)
+	result := make([]float64, len(c))
+	for t := 1; t < len(c); t++ {
+		result[t] = result[t-1]
+		if c[t] > c[t-1] {
+			result[t] += v[t]
+		} else if c[t] < c[t-1] {
+			result[t] -= v[t]
+		}
+	}
+	return reversed(result)
+}
+
+// マネーフロー出来高 ((終値 - 安値) - (高値 - 終値)) / (高値 - 安値) * 出来高 (日付の並びは引数と同じ)
+func refMoneyFlowVolume(high []float64, low []float64, closing []float64, volume []float64) []float64 {
+	result := make([]float64, len(closing))
+	for i := range closing {
+		if high[i] != low[i] {
+			result[i] = ((closing[i] - low[i]) - (high[i] - closing[i])) / (high[i] - low[i]) * volume[i]
+		}
+	}
+	return result
+}
+
+// A/D ライン
+func refADLine(high []float64, low []float64, closing []float64, volume []float64) []float64 {
+
+	flow := reversed(refMoneyFlowVolume(high, low, closing, volume))
+	result := make([]float64, len(flow))
+	for t := range flow {
+		result[t] = flow[t]
+		if t > 0 {
+			result[t] += result[t-1]
+		}
+	}
+	return reversed(result)
+}
+
+// n 期間の VWAP
+func refVWAP(high []float64, low []float64, closing []float64, volume []float64, n int) []float64 {
+
+	typical, v := reversed(refTypicalPrice(high, low, closing)), reversed(volume)
+	result := make([]float64, len(typical))
+	for t := range typical {
+		result[t] = math.NaN()
+		if n <= 0 || t+1 < n {
+			continue
+		}
+		priceVolume, totalVolume, totalPrice := 0.0, 0.0, 0.0
+		for k := t - n + 1; k <= t; k++ {
+			priceVolume += typical[k] * v[k]
+			totalVolume += v[k]
+			totalPrice += typical[k]
+		}
+		if totalVolume == 0 {
+			result[t] = totalPrice / float64(n)
+		} else {
+			result[t] = priceVolume / totalVolume
+		}
+	}
+	return reversed(result)
+}
+
+// n 期間の MFI
+func refMFI(high []float64, low []float64, closing []float64, volume []float64, n int) []float64 {
+
+	typical, v := reversed(refTypicalPrice(high, low, closing)), reversed(volume)
+	result := make([]float64, len(typical))
+	for t := range typical {
+		// 前日の典型価格と比較するため n + 1 本が必要
+		result[t] = math.NaN()
+		if n <= 0 || t < n {
+			continue
+		}
+		up, down := 0.0, 0.0
+		for k := t - n + 1; k <= t; k++ {
+			if typical[k] > typical[k-1] {
+				up += typical[k] * v[k]
+			} else if typical[k] < typical[k-1] {
+				down += typical[k] * v[k]
+			}
+		}
+		switch {
+		case up == 0 && down == 0:
+			result[t] = 50
+		case down == 0:
+			result[t] = 100
+		default:
+			result[t] = 100 - 100/(1+up/down)
+		}
+	}
+	return reversed(result)
+}
+
+// n 期間の CMF
+func refCMF(high []float64, low []float64, closing []float64, volume []float64, n int) []float64 {
+
+	flow, v := reversed(refMoneyFlowVolume(high, low, closing, volume)), reversed(volume)
+	result := make([]float64, len(flow))
+	for t := range flow {
+		result[t] = math.NaN()
+		if n <= 0 || t+1 < n {
+			continue
+		}
+		totalFlow, totalVolume := 0.0, 0.0
+		for k := t - n + 1; k <= t; k++ {
+			totalFlow += flow[k]
+			totalVolume += v[k]
+		}
+		result[t] = 0
+		if totalVolume != 0 {
+			result[t] = totalFlow / totalVolume
+		}
+	}
+	return reversed(result)
+}
+
+// パラボリック SAR とトレンド(上昇 1、下降 -1)。最古の日付は NaN
+func refParabolicSAR(high []float64, low []float64, closing []float64, acceleration indicator.SARAcceleration) ([]float64, []float64) {
+
+	h, l, c := reversed(high), reversed(low), reversed(closing)
+	sar := make([]float64, len(c))
+	trend := make([]float64, len(c))
+	for t := range c {
+		sar[t], trend[t] = math.NaN(), math.NaN()
+	}
+	if len(c) < 2 {
+		return reversed(sar), reversed(trend)
+	}
+
+	rising := c[1] >= c[0]
+	af, extreme := acceleration.Start, math.Min(l[0], l[1])
+	sar[1] = math.Max(h[0], h[1])
+	if rising {
+		extreme, sar[1] = math.Max(h[0], h[1]), math.Min(l[0], l[1])
+	}
+	for t := 1; t < len(c); t++ {
+		if t >= 2 {
+			current := sar[t-1] + af*(extreme-sar[t-1])
+			switch {
+			case rising && l[t] < math.Min(current, math.Min(l[t-1], l[t-2])):
+				// 上昇中に SAR を割り込んだ日は極値を SAR として下降に転換する
+				rising, current, extreme, af = false, extreme, l[t], acceleration.Start
+			case rising:
+				current = math.Min(current, math.Min(l[t-1], l[t-2]))
+				if h[t] > extreme {
+					extreme, af = h[t], math.Min(af+acceleration.Step, acceleration.Max)
+				}
+			case h[t] > math.Max(current, math.Max(h[t-1], h[t-2])):
+				rising, current, extreme, af = true, extreme, h[t], acceleration.Start
+			default:
+				current = math.Max(current, math.Max(h[t-1], h[t-2]))
+				if l[t] < extreme {
+					extreme, af = l[t], math.Min(af+acceleration.Step, acceleration.Max)
+				}
+			}
+			sar[t] = current
+		}
+		trend[t] = -1
+		if rising {
+			trend[t] = 1
+		}
+	}
+	return reversed(sar), reversed(trend)
+}
+
+// 一目均衡表の各値をカラム名毎に返す
+func refIchimoku(high []float64, low []float64, closing []float64, periods indicator.IchimokuPeriods) map[string][]float64 {
+
+	middle := func(n int) []float64 {
+		highest, lowest := refHighLow(high, low, n)
+		result := make([]float64, len(highest))
+		for i := range result {
+			result[i] = (highest[i] + lowest[i]) / 2
+		}
+		return result
+	}
+	// 日付降順で shift 本古い日付の値(ない場合は NaN)
+	older := func(values []float64, shift int) []float64 {
+		result := make([]float64, len(values))
+		for i := range result {
+			result[i] = math.NaN()
+			if i+shift < len(values) {
+				result[i] = values[i+shift]
+			}
+		}
+		return result
+	}
+
+	tenkan, kijun, senkouBLead := middle(periods.Tenkan), middle(periods.Kijun), middle(periods.SenkouB)
+	senkouALead := make([]float64, len(tenkan))
+	for i := range tenkan {
+		senkouALead[i] = (tenkan[i] + kijun[i]) / 2
+	}
+	senkouA, senkouB, chikou := older(senkouALead, periods.Kijun), older(senkouBLead, periods.Kijun), older(closing, periods.Kijun)
+	chikouDiff := make([]float64, len(tenkan))
+	position := make([]float64, len(tenkan))
+	thickness := make([]float64, len(tenkan))
+	for i := range tenkan {
+		chikouDiff[i] = closing[i] - chikou[i]
+		thickness[i] = senkouA[i] - senkouB[i]
+		top, bottom := math.Max(senkouA[i], senkouB[i]), math.Min(senkouA[i], senkouB[i])
+		switch {
+		case math.IsNaN(senkouA[i]) || math.IsNaN(senkouB[i]):
+			position[i] = math.NaN()
+		case closing[i] > top:
+			position[i] = 1
+		case closing[i] < bottom:
+			position[i] = -1
+		}
+	}
+	return map[string][]float64{
+		"IchimokuTenkan":         tenkan,
+		"IchimokuKijun":          kijun,
+		"IchimokuSenkouA":        senkouA,
+		"IchimokuSenkouB":        senkouB,
+		"IchimokuSenkouALead":    senkouALead,
+		"IchimokuSenkouBLead":    senkouBLead,
+		"IchimokuChikouDiff":     chikouDiff,
+		"IchimokuCloudPosition":  position,
+		"IchimokuCloudThickness": thickness,
+	}
+}
+
+// ローソク足パターンの判定結果(強気 1、弱気 -1、該当なし 0)をカラム名毎に返す
+// 判定に必要な前日以前の足がない日付は 0
+func refCandles(opening []float64, high []float64, low []float64, closing []float64) map[string][]float64 {
+
+	o, h, l, c := reversed(opening), reversed(high), reversed(low), reversed(closing)
+	body := func(t int) float64 { return math.Abs(c[t] - o[t]) }
+	span := func(t int) float64 { return h[t] - l[t] }
+	up := func(t int) bool { return c[t] > o[t] }
+	down := func(t int) bool { return c[t] < o[t] }
+	long := func(t int) bool { return span(t) > 0 && body(t) >= candle.LongBodyRatio*span(t) }
+	isDoji := func(t int) bool { return body(t) <= candle.DojiBodyRatio*span(t) }
+	sign := func(bullish bool, bearish bool) float64 {
+		switch {
+		case bullish:
+			return 1
+		case bearish:
+			return -1
+		}
+		return 0
+	}
+
+	result := map[string][]float64{}
+	for _, name := range []string{"CandleDoji", "CandleHammer", "CandleEngulfing", "CandleHarami", "CandleStar", "CandleThreeSoldiers", "CandleGap"} {
+		result[name] = make([]float64, len(c))
+	}
+	for t := range c {
+		if isDoji(t) {
+			result["CandleDoji"][t] = 1
+		} else {
+			bodyTop, bodyBottom := math.Max(o[t], c[t]), math.Min(o[t], c[t])
+			upper, lower := h[t]-bodyTop, bodyBottom-l[t]
+			result["CandleHammer"][t] = sign(
+				lower >= candle.ShadowBodyRatio*body(t) && upper <= candle.ShortShadowRatio*span(t),
+				upper >= candle.ShadowBodyRatio*body(t) && lower <= candle.ShortShadowRatio*span(t))
+		}
+		if t >= 1 {
+			p := t - 1
+			result["CandleEngulfing"][t] = sign(
+				up(t) && down(p) && o[t] <= c[p] && c[t] >= o[p] && body(t) > body(p),
+				down(t) && up(p) && o[t] >= c[p] && c[t] <= o[p] && body(t) > body(p))
+			result["CandleHarami"][t] = sign(
+				long(p) && up(t) && down(p) && o[t] > c[p] && c[t] < o[p],
+				long(p) && down(t) && up(p) && o[t] < c[p] && c[t] > o[p])
+			result["CandleGap"][t] = sign(l[t] > h[p], h[t] < l[p])
+		}
+		if t >= 2 {
+			f, s := t-2, t-1
+			isStar := long(f) && body(s) <= candle.StarBodyRatio*body(f)
+			center := (o[f] + c[f]) / 2
+			result["CandleStar"][t] = sign(
+				isStar && down(f) && math.Max(o[s], c[s]) < c[f] && up(t) && c[t] > center,
+				isStar && up(f) && math.Min(o[s], c[s]) > c[f] && down(t) && c[t] < center)
+			result["CandleThreeSoldiers"][t] = sign(
+				up(f) && up(s) && up(t) && c[f] < c[s] && c[s] < c[t] && o[f] <= o[s] && o[s] <= c[f] && o[s] <= o[t] && o[t] <= c[s],
+				down(f) && down(s) && down(t) && c[f] > c[s] && c[s] > c[t] && o[f] >= o[s] && o[s] >= c[f] && o[s] >= o[t] && o[t] >= c[s])
+		}
+	}
+	for name, values := range result {
+		result[name] = reversed(values)
+	}
+	return result
+}
@@ -0,0 +1,443 @@
+// verification ModelData のテクニカル指標の検証
+package main
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sv_stockcheck/datasource"
+	"sv_stockcheck/fileio"
+	"sv_stockcheck/indicator"
+	"sv_stockcheck/resample"
+)
+
+// ModelData.csv のヘッダからテクニカル指標のカラムを判定し、同じディレクトリの RawData.csv の四本値・出来高から
+// 再計算した値と全ての行を比較する
+// 全ての指標は indicator / candle パッケージの計算を用いない参照実装(reference_accounts.go)で再計算する
+// 参照実装のないカラムは PASS とせず、unchecked として出力する
+// 差が許容誤差を超えるカラムがある、またはファイルが読み込めない場合は終了コード 1 とする
+// ModelData_weekly.csv / ModelData_monthly.csv は RawData.csv を週足・月足に変換して再計算する
+
+// ---- const
+
+const usageText = `usage: go run ./verification [flags] ModelData.csv [ModelData.csv ...]
+
+flags:
+`
+
+// csvdata_create_main.go と同じ固定の期間(一目均衡表の期間は -ichimoku で指定する)
+const windowStochSmoothing = 3 // ストキャスティクスの%Dの期間
+const bollingerBandK = 2       // ボリンジャーバンドの標準偏差の倍率
+
+// ---- struct
+
+// 再計算に用いる足(日付降順)
+type bars struct {
+	Date    []string // yyyy/mm/dd
+	Opening []float64
+	High    []float64
+	Low     []float64
+	Closing []float64
+	Volume  []float64
+}
+
+// カラム毎の検証結果
+type columnResult struct {
+	Column   string
+	Rows     int     // 比較した行数
+	Failed   int     // 許容誤差を超えた行数
+	MaxDiff  float64 // 最大の差(許容誤差に対する比較と同じく、再計算値の絶対値(1未満は1)で割った値)
+	Date     string  // 最大の差の日付
+	Expected float64 // 最大の差の日付の再計算値
+	Actual   float64 // 最大の差の日付の ModelData の値
+}
+
+// ---- Package Global Variable
+
+var tolerance = 1e-4 // 許容誤差(ModelData は小数第5位までの出力のため、丸めの差を許容する)
+var macdDefinitions []indicator.MACDDefinition
+var sarAcceleration = indicator.DefaultSARAcceleration
+var ichimokuPeriods = indicator.DefaultIchimokuPeriods
+var rawFileName = "" // RawData のファイル(省略時は ModelData と同じディレクトリの RawData.csv)
+
+// 再計算しないカラム(日付、曜日、マクロ指標、予測モデルの予測)の接頭辞
+var skipColumnPrefixes = []string{"date", "DayOfWeek", "InterestRateate", "UnemployRateate", "CPI", "GDP", "Tankan", "ARIMA", "HoltWinters", "GARCH", "Naive", "Drift", "Forecast"}
+
+// ModelData のファイル名から足の期間を取得する(ModelData_weekly.csv など)
+var periodFileName = regexp.MustCompile(`^ModelData_(\w+)\.csv$`)
+
+// 期間毎の指標のカラム名(名前+期間)
+var termColumn = regexp.MustCompile(`^([A-Za-z]+?)(\d+)$`)
+
+//---- private function ----
+
+// RawData を読み込み、ModelData の足の期間に変換する
+// 株式は日本の取引所の営業日、為替(出来高のカラムがない)は平日のみを対象に週足・月足に変換する
+func readBars(modelFileName string, isForex bool) (bars, error) {
+
+	rawPath := rawFileName
+	if rawPath == "" {
+		rawPath = filepath.Join(filepath.Dir(modelFileName), "RawData.csv")
+	}
+	prices, err := datasource.NewFile(rawPath).FetchDaily("", time.Time{}, time.Time{})
+	if err != nil {
+		return bars{}, err
+	}
+
+	var daily []resample.Bar
+	for _, p := range prices {
+		daily = append(daily, resample.Bar{Date: p.Date, Opening: p.Opening, High: p.High, Low: p.Low, Closing: p.Closing, Volume: p.Volume})
+	}
+	if match := periodFileName.FindStringSubmatch(filepath.Base(modelFileName)); match != nil {
+		period, err := resample.ParsePeriod(match[1])
+		if err != nil {
+			return bars{}, err
+		}
+		isTradingDay := resample.IsTradingDay
+		if isForex == true {
+			isTradingDay = resample.IsWeekday
+		}
+		daily = resample.Resample(daily, period, isTradingDay)
+	}
+
+	var b bars
+	for _, d := range daily {
+		b.Date = append(b.Date, d.Date.Format("2006/01/02"))
+		b.Opening = append(b.Opening, d.Opening)
+		b.High = append(b.High, d.High)
+		b.Low = append(b.Low, d.Low)
+		b.Closing = append(b.Closing, d.Closing)
+		b.Volume = append(b.Volume, d.Volume)
+	}
+	return b, nil
+}
+
+// 期間毎の指標を再計算する。対応しない名前の場合は false を返す
+func recalculateTerm(b bars, name string, term int) ([]float64, bool) {
+
+	sma := func(values []float64) []float64 { return refSMA(values, term) }
+	switch name {
+	case "MovingAve":
+		return sma(b.Closing), true
+	case "EMA":
+		return refSmooth(b.Closing, term, indicator.SmoothingEMA), true
+	case "Volatility":
+		return refStdDev(b.Closing, term), true
+	case "HighLowVolatility":
+		highLow := make([]float64, len(b.High))
+		for i := range b.High {
+			highLow[i] = b.High[i] - b.Low[i]
+		}
+		return sma(highLow), true
+	case "MADRate":
+		return refMADRate(b.Closing, sma(b.Closing)), true
+	case "upperBBand", "underBBand":
+		average, stdDev := sma(b.Closing), refStdDev(b.Closing, term)
+		sign := 1.0
+		if name == "underBBand" {
+			sign = -1
+		}
+		band := make([]float64, len(average))
+		for i := range average {
+			band[i] = average[i] + sign*bollingerBandK*stdDev[i]
+		}
+		return band, true
+	case "StochFastK", "StochFastD", "StochSlowD", "WilliamsR":
+		fastK, williamsR := refStochastics(b.High, b.Low, b.Closing, term)
+		fastD := refSMA(fastK, windowStochSmoothing)
+		return map[string][]float64{
+			"StochFastK": fastK,
+			"StochFastD": fastD,
+			"StochSlowD": refSMA(fastD, windowStochSmoothing),
+			"WilliamsR":  williamsR,
+		}[name], true
+	case "CCI":
+		return refCCI(b.High, b.Low, b.Closing, term), true
+	case "PlusDI", "MinusDI", "ADX":
+		plusDI, minusDI, adx := refDMI(b.High, b.Low, b.Closing, term)
+		return map[string][]float64{"PlusDI": plusDI, "MinusDI": minusDI, "ADX": adx}[name], true
+	case "VMovingAve":
+		return sma(b.Volume), true
+	case "VolumeRatio":
+		average := sma(b.Volume)
+		ratio := make([]float64, len(average))
+		for i := range average {
+			ratio[i] = b.Volume[i] / average[i]
+		}
+		return ratio, true
+	case "VolumeEMA":
+		return refSmooth(b.Volume, term, indicator.SmoothingEMA), true
+	case "VolumeMADRate":
+		return refMADRate(b.Volume, sma(b.Volume)), true
+	case "VWAP":
+		return refVWAP(b.High, b.Low, b.Closing, b.Volume, term), true
+	case "MFI":
+		return refMFI(b.High, b.Low, b.Closing, b.Volume, term), true
+	case "CMF":
+		return refCMF(b.High, b.Low, b.Closing, b.Volume, term), true
+	}
+
+	// ATR、RSI は平滑化方式をカラム名に含む(sma は ATR14、wilder は ATRWilder14、ema は ATREMA14)
+	for _, base := range []string{"ATR", "RSI"} {
+		suffix, found := strings.CutPrefix(name, base)
+		if found == false {
+			continue
+		}
+		method := map[string]indicator.Smoothing{"": indicator.SmoothingSMA, "Wilder": indicator.SmoothingWilder, "EMA": indicator.SmoothingEMA}
+		smoothing, ok := method[suffix]
+		if ok == false {
+			return nil, false
+		}
+		if base == "ATR" {
+			return refATR(b.High, b.Low, b.Closing, term, smoothing), true
+		}
+		return refRSI(b.Closing, term, smoothing), true
+	}
+	return nil, false
+}
+
+// MACDの指標を再計算する。カラム名の接頭辞は -macd の定義名、または sma5_30_9 の形式の自動で付けた名前
+func recalculateMACD(b bars, column string) ([]float64, bool) {
+
+	for _, suffix := range []string{"MACDSignalSMA", "MACDHistoSMA", "MACDSignalEMA", "MACDHistoEMA", "MACD"} {
+		name, found := strings.CutSuffix(column, suffix)
+		if found == false || name == "" {
+			continue
+		}
+		index := slices.IndexFunc(macdDefinitions, func(d indicator.MACDDefinition) bool { return d.Name == name })
+		var def indicator.MACDDefinition
+		if index >= 0 {
+			def = macdDefinitions[index]
+		} else {
+			defs, err := indicator.ParseMACDDefinitions(strings.Replace(strings.Replace(strings.Replace(name, "_", ":", -1), "sma", "sma:", 1), "ema", "ema:", 1))
+			if err != nil || len(defs) != 1 || defs[0].Name != name {
+				return nil, false
+			}
+			def = defs[0]
+		}
+		return refMACD(b.Closing, def)[suffix], true
+	}
+	return nil, false
+}
+
+// カラムの値を再計算する。再計算の対象外のカラムは false を返す
+func recalculate(b bars, column string) ([]float64, bool) {
+
+	switch column {
+	case "opening":
+		return b.Opening, true
+	case "high":
+		return b.High, true
+	case "low":
+		return b.Low, true
+	case "closing":
+		return b.Closing, true
+	case "volume":
+		return b.Volume, true
+	case "OBV":
+		return refOBV(b.Closing, b.Volume), true
+	case "ADLine":
+		return refADLine(b.High, b.Low, b.Closing, b.Volume), true
+	case "VCR":
+		// 出来高変化率。前日の出来高がない、または 0 の場合は 0
+		vcr := make([]float64, len(b.Volume))
+		for i := 0; i+1 < len(b.Volume); i++ {
+			if b.Volume[i+1] > 0 {
+				vcr[i] = (b.Volume[i] - b.Volume[i+1]) / b.Volume[i+1]
+			}
+		}
+		return vcr, true
+	case "PSAR", "PSARTrend":
+		sar, trend := refParabolicSAR(b.High, b.Low, b.Closing, sarAcceleration)
+		if column == "PSAR" {
+			return sar, true
+		}
+		return trend, true
+	}
+	if strings.HasPrefix(column, "Ichimoku") {
+		values, ok := refIchimoku(b.High, b.Low, b.Closing, ichimokuPeriods)[column]
+		return values, ok
+	}
+	if strings.HasPrefix(column, "Candle") {
+		values, ok := refCandles(b.Opening, b.High, b.Low, b.Closing)[column]
+		return values, ok
+	}
+	if values, ok := recalculateMACD(b, column); ok == true {
+		return values, true
+	}
+	if match := termColumn.FindStringSubmatch(column); match != nil {
+		term, _ := strconv.Atoi(match[2])
+		return recalculateTerm(b, match[1], term)
+	}
+	return nil, false
+}
+
+// ModelData の1ファイルを検証し、カラム毎の結果と再計算の対象外のカラムを返す
+func verifyModelFile(modelFileName string) ([]columnResult, []string, error) {
+
+	contents, err := fileio.FileIoCsvRead(modelFileName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(contents) == 0 {
+		return nil, nil, fmt.Errorf("%s: no header", modelFileName)
+	}
+	header := contents[0]
+	b, err := readBars(modelFileName, slices.Contains(header, "volume") == false)
+	if err != nil {
+		return nil, nil, err
+	}
+	rawIndex := make(map[string]int, len(b.Date))
+	for i, date := range b.Date {
+		rawIndex[date] = i
+	}
+	var rows []int // ModelData の各行に対応する RawData の行
+	for _, row := range contents[1:] {
+		i, ok := rawIndex[row[0]]
+		if ok == false {
+			return nil, nil, fmt.Errorf("%s: date %s not found in raw data", modelFileName, row[0])
+		}
+		rows = append(rows, i)
+	}
+
+	var results []columnResult
+	var unchecked []string
+	for j, column := range header {
+		if slices.ContainsFunc(skipColumnPrefixes, func(prefix string) bool { return strings.HasPrefix(column, prefix) }) {
+			continue
+		}
+		expected, ok := recalculate(b, column)
+		if ok == false {
+			unchecked = append(unchecked, column)
+			continue
+		}
+		result := columnResult{Column: column}
+		for r, row := range contents[1:] {
+			want := expected[rows[r]]
+			got, err := strconv.ParseFloat(row[j], 64)
+			diff := math.Inf(1)
+			switch {
+			case err != nil:
+			case got == want || (math.IsNaN(got) && math.IsNaN(want)):
+				diff = 0
+			case !math.IsNaN(got - want):
+				diff = math.Abs(got-want) / max(1, math.Abs(want))
+			}
+			result.Rows++
+			if diff > tolerance {
+				result.Failed++
+			}
+			if diff > result.MaxDiff || result.Date == "" {
+				result.MaxDiff, result.Date, result.Expected, result.Actual = diff, row[0], want, got
+			}
+		}
+		results = append(results, result)
+	}
+	return results, unchecked, nil
+}
+
+// 検証結果の集計(差の大きい順に top 件)を出力する
+func printSummary(modelFileName string, results []columnResult, unchecked []string, top int) {
+
+	failed := 0
+	for _, r := range results {
+		if r.Failed > 0 {
+			failed++
+		}
+	}
+	status := "PASS"
+	if failed > 0 {
+		status = "FAIL"
+	}
+	fmt.Printf("%s %s: %d columns checked, %d failed, %d unchecked\n", status, modelFileName, len(results), failed, len(unchecked))
+	if len(unchecked) > 0 {
+		fmt.Printf("  unchecked: %s\n", strings.Join(unchecked, ", "))
+	}
+
+	if len(results) == 0 || results[0].Rows == 0 {
+		return
+	}
+	sorted := slices.SortedFunc(slices.Values(results), func(a, b columnResult) int {
+		return cmp.Or(cmp.Compare(b.MaxDiff, a.MaxDiff), cmp.Compare(a.Column, b.Column))
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  column\tmaxdiff\tdate\texpected\tactual\tfailed/rows")
+	for _, r := range sorted[:min(top, len(sorted))] {
+		fmt.Fprintf(w, "  %s\t%.3g\t%s\t%.6f\t%.6f\t%d/%d\n", r.Column, r.MaxDiff, r.Date, r.Expected, r.Actual, r.Failed, r.Rows)
+	}
+	w.Flush()
+}
+
+// ---- main
+
+func run(args []string) (bool, error) {
+
+	fs := flag.NewFlagSet("verification", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usageText)
+		fs.PrintDefaults()
+	}
+	fs.Float64Var(&tolerance, "tolerance", tolerance, "許容誤差(再計算値の絶対値(1未満は1)に対する差)")
+	fs.StringVar(&rawFileName, "raw", "", "RawData のファイル (省略時は ModelData と同じディレクトリの RawData.csv)")
+	macd := fs.String("macd", "short=sma:5:30:9,long=sma:14:30:9,ema:12:26:9", "ModelData の作成に用いた MACD の定義 (sma5_30_9 のような自動で付けた名前は指定不要)")
+	psar := fs.String("psar", "0.02,0.02,0.2", "ModelData の作成に用いたパラボリック SAR の加速因子 (初期値,増分,上限)")
+	ichimoku := fs.String("ichimoku", "9,26,52", "ModelData の作成に用いた一目均衡表の期間 (転換線,基準線,先行スパンB)")
+	top := fs.Int("top", 10, "差の大きいカラムを出力する件数")
+	if err := fs.Parse(args); err != nil {
+		return false, err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return false, fmt.Errorf("no ModelData file")
+	}
+	var err error
+	if macdDefinitions, err = indicator.ParseMACDDefinitions(*macd); err != nil {
+		return false, err
+	}
+	if sarAcceleration, err = indicator.ParseSARAcceleration(*psar); err != nil {
+		return false, err
+	}
+	if ichimokuPeriods, err = indicator.ParseIchimokuPeriods(*ichimoku); err != nil {
+		return false, err
+	}
+
+	isPassed := true
+	var errs []error
+	for _, modelFileName := range fs.Args() {
+		results, unchecked, err := verifyModelFile(modelFileName)
+		if err != nil {
+			errs = append(errs, err)
+			isPassed = false
+			continue
+		}
+		printSummary(modelFileName, results, unchecked, *top)
+		if slices.ContainsFunc(results, func(r columnResult) bool { return r.Failed > 0 }) {
+			isPassed = false
+		}
+	}
+	return isPassed, errors.Join(errs...)
+}
+
+func main() {
+	isPassed, err := run(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+	}
+	if isPassed == false || err != nil {
+		os.Exit(1)
+	}
+}
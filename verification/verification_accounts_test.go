@@ -0,0 +1,275 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"sv_stockcheck/fileio"
+	"sv_stockcheck/indicator"
+)
+
+// 日付昇順で記載した値を日付降順に並べ替える
+func descending(ascending []float64) []float64 {
+	return reversed(ascending)
+}
+
+// 日付降順の値を許容誤差 tolerance で比較する(NaN は NaN と一致)
+func assertSeries(t *testing.T, name string, got []float64, want []float64, tolerance float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: len = %d, want %d", name, len(got), len(want))
+	}
+	for i := range want {
+		if math.IsNaN(want[i]) != math.IsNaN(got[i]) || math.Abs(got[i]-want[i]) > tolerance {
+			t.Errorf("%s[%d] = %v, want %v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func TestReferenceFormulas(t *testing.T) {
+	nan := math.NaN()
+	values := descending([]float64{1, 2, 3, 4, 5})
+	assertSeries(t, "SMA", refSMA(values, 3), descending([]float64{nan, nan, 2, 3, 4}), 1e-12)
+	// EMA(3) は α = 0.5: 初期値 2 → 3 → 4
+	assertSeries(t, "EMA", refSmooth(values, 3, indicator.SmoothingEMA), descending([]float64{nan, nan, 2, 3, 4}), 1e-12)
+	// Wilder(3) は α = 1/3: 初期値 2 → 2 + (4 - 2) / 3 → 前回値 + (5 - 前回値) / 3
+	assertSeries(t, "Wilder", refSmooth(values, 3, indicator.SmoothingWilder), descending([]float64{nan, nan, 2, 8.0 / 3, 8.0/3 + (5-8.0/3)/3}), 1e-12)
+	// 最古の NaN は除いて初期値を求める
+	assertSeries(t, "EMA leading NaN", refSmooth(descending([]float64{nan, 2, 4, 6}), 2, indicator.SmoothingEMA), descending([]float64{nan, nan, 3, 5}), 1e-12)
+
+	// RSI は StockCharts ChartSchool の計算例(14期間、Wilder)の掲載値(小数第2位)
+	closing := []float64{44.3389, 44.0902, 44.1497, 43.6124, 44.3278, 44.8264, 45.0955, 45.4245, 45.8433, 46.0826, 45.8931,
+		46.0328, 45.6140, 46.2820, 46.2820, 46.0028, 46.0328, 46.4116, 46.2222, 45.6439, 46.2122, 46.2521, 45.7137, 46.4515, 45.7835}
+	want := []float64{70.53, 66.32, 66.55, 69.41, 66.36, 57.97, 62.93, 63.26, 56.06, 62.38, 54.71}
+	rsi := refRSI(descending(closing), 14, indicator.SmoothingWilder)
+	assertSeries(t, "RSI", descending(rsi)[14:], want, 0.005+1e-9)
+
+	// ATR: True Range は 2 (高値 - 安値)、4 (前日終値 10 から高値 14)、3 (安値 9 から前日終値 12)
+	high, low, close := descending([]float64{11, 14, 11}), descending([]float64{9, 11, 9}), descending([]float64{10, 12, 10})
+	assertSeries(t, "ATR", refATR(high, low, close, 2, indicator.SmoothingSMA), descending([]float64{nan, 3, 3.5}), 1e-12)
+
+	// MACD(sma 2, 3, シグナル 2)
+	macd := refMACD(descending([]float64{1, 2, 4, 8, 16}), indicator.MACDDefinition{Base: indicator.SmoothingSMA, Fast: 2, Slow: 3, Signal: 2})
+	assertSeries(t, "MACD", macd["MACD"], descending([]float64{nan, nan, 3 - 7.0/3, 6 - 14.0/3, 12 - 28.0/3}), 1e-12)
+	assertSeries(t, "MACDSignalSMA", macd["MACDSignalSMA"], descending([]float64{nan, nan, nan, (2.0/3 + 4.0/3) / 2, (4.0/3 + 8.0/3) / 2}), 1e-12)
+	assertSeries(t, "MACDHistoSMA", macd["MACDHistoSMA"], descending([]float64{nan, nan, nan, 4.0/3 - 1, 8.0/3 - 2}), 1e-12)
+
+	// 1, 2, 3 の母集団標準偏差は sqrt(2/3)
+	assertSeries(t, "StdDev", refStdDev(values, 3), descending([]float64{nan, nan, math.Sqrt(2.0 / 3), math.Sqrt(2.0 / 3), math.Sqrt(2.0 / 3)}), 1e-12)
+
+	// ストキャスティクス(期間2): 最高値 12、最安値 8 に対し終値 11、最高値 12、最安値 9 に対し終値 10
+	fastK, williamsR := refStochastics(descending([]float64{10, 12, 11}), descending([]float64{8, 9, 9}), descending([]float64{9, 11, 10}), 2)
+	assertSeries(t, "StochFastK", fastK, descending([]float64{nan, 75, 100.0 / 3}), 1e-12)
+	assertSeries(t, "WilliamsR", williamsR, descending([]float64{nan, -25, -200.0 / 3}), 1e-12)
+
+	// CCI(期間3): 典型価格 10, 11, 13 の平均 34/3、平均偏差 10/9
+	cci := refCCI(descending([]float64{11, 12, 14}), descending([]float64{9, 10, 12}), descending([]float64{10, 11, 13}), 3)
+	assertSeries(t, "CCI", cci, descending([]float64{nan, nan, (13 - 34.0/3) / (0.015 * 10.0 / 9)}), 1e-12)
+
+	// DMI(期間1、平滑化なし): +DM 2・TR 3 の翌日は -DM 2・TR 4。DX はどちらも 100
+	plusDI, minusDI, adx := refDMI(descending([]float64{10, 12, 11}), descending([]float64{8, 9, 7}), descending([]float64{9, 11, 8}), 1)
+	assertSeries(t, "PlusDI", plusDI, descending([]float64{nan, 200.0 / 3, 0}), 1e-12)
+	assertSeries(t, "MinusDI", minusDI, descending([]float64{nan, 0, 50}), 1e-12)
+	assertSeries(t, "ADX", adx, descending([]float64{nan, 100, 100}), 1e-12)
+
+	// OBV、A/D ライン(マネーフロー・マルチプライヤは (3 - 1) / 4 = 0.5、高値 = 安値 の日は 0)
+	assertSeries(t, "OBV", refOBV(descending([]float64{10, 11, 11, 10}), descending([]float64{100, 200, 300, 400})), descending([]float64{0, 200, 200, -200}), 1e-12)
+	assertSeries(t, "ADLine", refADLine(descending([]float64{12, 10}), descending([]float64{8, 10}), descending([]float64{11, 10}), descending([]float64{100, 50})), descending([]float64{50, 50}), 1e-12)
+
+	// VWAP、MFI(期間2): 典型価格 10, 11, 10.5、出来高 100, 200, 300
+	typicalHigh, typicalLow, typicalClose := descending([]float64{10, 11, 10.5}), descending([]float64{10, 11, 10.5}), descending([]float64{10, 11, 10.5})
+	volume := descending([]float64{100, 200, 300})
+	assertSeries(t, "VWAP", refVWAP(typicalHigh, typicalLow, typicalClose, volume, 2), descending([]float64{nan, (1000 + 2200) / 300.0, (2200 + 3150) / 500.0}), 1e-12)
+	assertSeries(t, "MFI", refMFI(typicalHigh, typicalLow, typicalClose, volume, 2), descending([]float64{nan, nan, 100 - 100/(1+2200.0/3150)}), 1e-12)
+	assertSeries(t, "CMF", refCMF(typicalHigh, typicalLow, typicalClose, volume, 2), descending([]float64{nan, 0, 0}), 1e-12)
+
+	// パラボリック SAR: 上昇から開始し、5本目に SAR 9.3528 を割り込んで極値 13 を SAR として下降に転換する
+	sar, trend := refParabolicSAR(descending([]float64{10, 11, 12, 13, 12}), descending([]float64{9, 10, 11, 12, 9}), descending([]float64{9.5, 10.5, 11.5, 12.5, 10}), indicator.DefaultSARAcceleration)
+	assertSeries(t, "PSAR", sar, descending([]float64{nan, 9, 9, 9.12, 13}), 1e-12)
+	assertSeries(t, "PSARTrend", trend, descending([]float64{nan, 1, 1, 1, -1}), 1e-12)
+
+	// ローソク足: 2本目は前日の高値 11 より安値 11.5 が上の窓、3本目は実体 0.05 が値幅 1.5 の 1 割以下の十字線
+	candles := refCandles(descending([]float64{10, 12, 12.8}), descending([]float64{11, 13, 13.5}), descending([]float64{9, 11.5, 12}), descending([]float64{10.5, 12.8, 12.85}))
+	assertSeries(t, "CandleGap", candles["CandleGap"], descending([]float64{0, 1, 0}), 0)
+	assertSeries(t, "CandleDoji", candles["CandleDoji"], descending([]float64{0, 0, 1}), 0)
+}
+
+// testdata の RawData.csv と build-model で作成した golden の ModelData.csv を一時ディレクトリにコピーし、
+// ModelData.csv の行を modify で書き換える
+func modelFixture(t *testing.T, code string, modify func(header []string, rows [][]string)) string {
+	t.Helper()
+	dir := t.TempDir()
+	raw, err := os.ReadFile(filepath.Join("..", "testdata", "Resource", code, "RawData.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "RawData.csv"), raw, 0644); err != nil {
+		t.Fatal(err)
+	}
+	contents, err := fileio.FileIoCsvRead(filepath.Join("..", "testdata", "golden", code, "ModelData.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if modify != nil {
+		modify(contents[0], contents[1:])
+	}
+	modelFileName := filepath.Join(dir, "ModelData.csv")
+	if err := fileio.FileIoCsvWrite(modelFileName, contents, false); err != nil {
+		t.Fatal(err)
+	}
+	return modelFileName
+}
+
+// ModelData の column のカラムの row 行目に delta を加える
+func shiftValue(t *testing.T, column string, row int, delta float64) func(header []string, rows [][]string) {
+	return func(header []string, rows [][]string) {
+		j := slices.Index(header, column)
+		if j < 0 {
+			t.Fatalf("column %s not found", column)
+		}
+		value, err := strconv.ParseFloat(rows[row][j], 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows[row][j] = strconv.FormatFloat(value+delta, 'f', 5, 64)
+	}
+}
+
+func TestVerifyCorrectModelDataPasses(t *testing.T) {
+	for _, code := range []string{"2586", "0970"} {
+		isPassed, err := run([]string{modelFixture(t, code, nil)})
+		if err != nil || isPassed == false {
+			t.Errorf("%s: passed = %v, err = %v, want PASS", code, isPassed, err)
+		}
+	}
+}
+
+func TestVerifyResampledModelDataPasses(t *testing.T) {
+	originalMACD, originalIchimoku := macdDefinitions, ichimokuPeriods
+	t.Cleanup(func() { macdDefinitions, ichimokuPeriods = originalMACD, originalIchimoku })
+
+	// 期間の短い設定で作成した golden は週足・月足にも行があり、同じ設定を指定すると全て PASS する
+	for _, code := range []string{"2586", "0970"} {
+		dir := t.TempDir()
+		var modelFileNames []string
+		for _, name := range []string{"RawData.csv", "ModelData.csv", "ModelData_weekly.csv", "ModelData_monthly.csv"} {
+			src := filepath.Join("..", "testdata", "golden", code+"_small", name)
+			if name == "RawData.csv" {
+				src = filepath.Join("..", "testdata", "Resource", code, name)
+			} else {
+				modelFileNames = append(modelFileNames, filepath.Join(dir, name))
+			}
+			contents, err := os.ReadFile(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, name), contents, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		isPassed, err := run(append([]string{"-macd", "sma:2:4:2", "-ichimoku", "2,3,5"}, modelFileNames...))
+		if err != nil || isPassed == false {
+			t.Errorf("%s: passed = %v, err = %v, want PASS", code, isPassed, err)
+		}
+		// 一目均衡表の期間が異なると FAIL となる
+		if isPassed, err := run(append([]string{"-macd", "sma:2:4:2"}, modelFileNames...)); isPassed == true || err != nil {
+			t.Errorf("%s default -ichimoku: passed = %v, err = %v, want FAIL", code, isPassed, err)
+		}
+	}
+}
+
+func TestVerifyCorruptedModelDataFails(t *testing.T) {
+	// 指標の値を1箇所だけ書き換えると、そのカラムのみ失敗する
+	for _, column := range []string{"MovingAve5", "EMA14", "RSI14", "ATR30", "shortMACD", "ema12_26_9MACDSignalEMA", "longMACDHistoSMA", "CCI14", "PSAR",
+		"Volatility30", "upperBBand14", "StochSlowD14", "WilliamsR5", "ADX14", "VWAP14", "MFI5", "CMF30", "IchimokuSenkouA", "CandleGap"} {
+		t.Run(column, func(t *testing.T) {
+			results, _, err := verifyModelFile(modelFixture(t, "2586", shiftValue(t, column, 10, 0.5)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range results {
+				wantFailed := 0
+				if r.Column == column {
+					wantFailed = 1
+				}
+				if r.Failed != wantFailed {
+					t.Errorf("%s failed = %d/%d (maxdiff %g), want %d", r.Column, r.Failed, r.Rows, r.MaxDiff, wantFailed)
+				}
+			}
+			if isPassed, err := run([]string{modelFixture(t, "2586", shiftValue(t, column, 10, 0.5))}); isPassed == true || err != nil {
+				t.Errorf("run passed = %v, err = %v, want FAIL", isPassed, err)
+			}
+		})
+	}
+}
+
+func TestVerifyTolerance(t *testing.T) {
+	// 約 150 の移動平均に 0.003 の差(相対誤差 2e-5)は既定の許容誤差 1e-4 では PASS、1e-5 では FAIL
+	modelFileName := modelFixture(t, "2586", shiftValue(t, "MovingAve30", 0, 0.003))
+	if isPassed, err := run([]string{modelFileName}); isPassed == false || err != nil {
+		t.Errorf("default tolerance: passed = %v, err = %v, want PASS", isPassed, err)
+	}
+	if isPassed, err := run([]string{"-tolerance", "1e-5", modelFileName}); isPassed == true || err != nil {
+		t.Errorf("-tolerance 1e-5: passed = %v, err = %v, want FAIL", isPassed, err)
+	}
+	// ModelData の小数第5位への丸めの差は 1e-5 でも PASS
+	if isPassed, err := run([]string{"-tolerance", "1e-5", modelFixture(t, "2586", nil)}); isPassed == false || err != nil {
+		t.Errorf("rounding with -tolerance 1e-5: passed = %v, err = %v, want PASS", isPassed, err)
+	}
+}
+
+func TestVerifyDateNotFound(t *testing.T) {
+	modelFileName := modelFixture(t, "2586", func(header []string, rows [][]string) {
+		rows[3][0] = "2024/12/29" // 日曜日(RawData にない日付)
+	})
+	isPassed, err := run([]string{modelFileName})
+	if isPassed == true || err == nil || strings.Contains(err.Error(), "date 2024/12/29 not found in raw data") == false {
+		t.Errorf("passed = %v, err = %v, want date not found error", isPassed, err)
+	}
+
+	if isPassed, err := run([]string{filepath.Join(t.TempDir(), "ModelData.csv")}); isPassed == true || err == nil {
+		t.Errorf("missing file: passed = %v, err = %v, want error", isPassed, err)
+	}
+}
+
+// 終了コードは PASS で 0、FAIL・エラーで 1 (テストのバイナリを VERIFICATION_MAIN_ARGS を指定して実行し main を呼ぶ)
+func TestMainExitCode(t *testing.T) {
+	if args := os.Getenv("VERIFICATION_MAIN_ARGS"); args != "" {
+		os.Args = append([]string{"verification"}, strings.Split(args, "\n")...)
+		main()
+		os.Exit(0)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"pass", []string{modelFixture(t, "2586", nil)}, 0},
+		{"fail", []string{modelFixture(t, "2586", shiftValue(t, "RSI14", 10, 0.5))}, 1},
+		{"date not found", []string{modelFixture(t, "2586", func(header []string, rows [][]string) { rows[0][0] = "2030/01/01" })}, 1},
+		{"no file", []string{"-top", "1"}, 1},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestMainExitCode$")
+		cmd.Env = append(os.Environ(), "VERIFICATION_MAIN_ARGS="+strings.Join(tt.args, "\n"))
+		err := cmd.Run()
+		code := 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			code = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.want {
+			t.Errorf("%s: exit code = %d, want %d", tt.name, code, tt.want)
+		}
+	}
+}